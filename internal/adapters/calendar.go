@@ -0,0 +1,59 @@
+package adapters
+
+import (
+	"birthdayapp/internal/config"
+	"birthdayapp/internal/core/domain"
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// NewWorkCalendar builds the working calendar, the holidays file has a date per line:
+// "YYYY-MM-DD" for a single date or "MM-DD" for every year, "#" starts a comment
+func NewWorkCalendar(cfg config.Calendar) (*domain.WorkCalendar, error) {
+	switch cfg.WeekendShift {
+	case "", domain.ShiftNone, domain.ShiftPrevious, domain.ShiftNext:
+	default:
+		return nil, fmt.Errorf("unknown weekend shift %q, expected none, previous or next", cfg.WeekendShift)
+	}
+
+	calendar := &domain.WorkCalendar{
+		Shift:    cfg.WeekendShift,
+		Holidays: make(map[string]bool),
+	}
+	if cfg.HolidaysPath == "" {
+		return calendar, nil
+	}
+
+	file, oErr := os.Open(cfg.HolidaysPath)
+	if oErr != nil {
+		return nil, fmt.Errorf("error open holidays file: %w", oErr)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for row := 1; scanner.Scan(); row++ {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if _, pErr := time.Parse(time.DateOnly, line); pErr == nil {
+			calendar.Holidays[line] = true
+			continue
+		}
+		//2000 is a leap year, so "02-29" is accepted
+		if _, pErr := time.Parse(time.DateOnly, "2000-"+line); pErr == nil {
+			calendar.Holidays[line] = true
+			continue
+		}
+		return nil, fmt.Errorf("holidays file %s row %d: %q must be YYYY-MM-DD or MM-DD: %w", cfg.HolidaysPath, row, line, domain.ErrValidation)
+	}
+	if sErr := scanner.Err(); sErr != nil {
+		return nil, fmt.Errorf("error read holidays file: %w", sErr)
+	}
+
+	return calendar, nil
+}
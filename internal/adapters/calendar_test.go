@@ -0,0 +1,34 @@
+package adapters
+
+import (
+	"birthdayapp/internal/config"
+	"birthdayapp/internal/core/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewWorkCalendar(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "holidays.txt")
+	content := "# new year\n01-01\n\n2024-05-09 # victory day\n02-29\n"
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	calendar, err := NewWorkCalendar(config.Calendar{WeekendShift: domain.ShiftNext, HolidaysPath: path})
+	require.NoError(t, err)
+	assert.Equal(t, domain.ShiftNext, calendar.Shift)
+	assert.Equal(t, map[string]bool{"01-01": true, "2024-05-09": true, "02-29": true}, calendar.Holidays)
+}
+
+func TestNewWorkCalendar_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "holidays.txt")
+	require.NoError(t, os.WriteFile(path, []byte("2024-05-09\n09.05.2024\n"), 0o600))
+
+	_, err := NewWorkCalendar(config.Calendar{HolidaysPath: path})
+	assert.ErrorIs(t, err, domain.ErrValidation)
+	assert.Contains(t, err.Error(), "row 2")
+
+	_, err = NewWorkCalendar(config.Calendar{WeekendShift: "friday"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,3 @@
+ALTER TABLE users DROP COLUMN birthday_source;
+ALTER TABLE users DROP COLUMN username_source;
+ALTER TABLE users DROP COLUMN telegram_id_source;
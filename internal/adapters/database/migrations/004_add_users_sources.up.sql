@@ -0,0 +1,3 @@
+ALTER TABLE users ADD COLUMN telegram_id_source TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN username_source TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN birthday_source TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS job_runs (
+    id INTEGER PRIMARY KEY,
+    job TEXT NOT NULL,
+    date TEXT NOT NULL,
+    timezone TEXT NOT NULL,
+    started_at DATETIME NOT NULL,
+    UNIQUE (job, date, timezone)
+);
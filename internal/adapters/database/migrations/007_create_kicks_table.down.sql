@@ -0,0 +1 @@
+DROP TABLE IF EXISTS kicks;
\ No newline at end of file
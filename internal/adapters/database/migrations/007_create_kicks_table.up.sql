@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS kicks (
+    id INTEGER PRIMARY KEY,
+    chat_id INTEGER NOT NULL,
+    telegram_id INTEGER NOT NULL,
+    due_at DATETIME NOT NULL,
+    UNIQUE (chat_id, telegram_id)
+);
+
+CREATE INDEX IF NOT EXISTS kicks_due_at ON kicks (due_at);
@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN reminder_days TEXT;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN digest_opt_out BOOLEAN NOT NULL DEFAULT FALSE;
\ No newline at end of file
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS celebrations;
\ No newline at end of file
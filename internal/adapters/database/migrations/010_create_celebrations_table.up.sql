@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS celebrations (
+    id INTEGER PRIMARY KEY,
+    telegram_id INTEGER NOT NULL,
+    date TEXT NOT NULL,
+    timezone TEXT NOT NULL,
+    plan_at DATETIME NOT NULL,
+    reveal_at DATETIME NOT NULL,
+    planned BOOLEAN NOT NULL DEFAULT FALSE,
+    UNIQUE (telegram_id, date)
+);
+
+CREATE INDEX IF NOT EXISTS celebrations_reveal_at ON celebrations (reveal_at);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS group_allocations;
\ No newline at end of file
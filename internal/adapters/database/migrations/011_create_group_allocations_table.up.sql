@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS group_allocations (
+    chat_id INTEGER PRIMARY KEY,
+    allocated_at DATETIME NOT NULL,
+    release_after DATETIME NOT NULL
+);
@@ -0,0 +1 @@
+ALTER TABLE celebrations DROP COLUMN chat_id;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE celebrations ADD COLUMN chat_id INTEGER NOT NULL DEFAULT 0;
\ No newline at end of file
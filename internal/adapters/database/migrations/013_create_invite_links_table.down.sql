@@ -0,0 +1 @@
+DROP TABLE IF EXISTS invite_links;
\ No newline at end of file
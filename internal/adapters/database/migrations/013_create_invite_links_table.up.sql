@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS invite_links (
+    id INTEGER PRIMARY KEY,
+    chat_id INTEGER NOT NULL,
+    telegram_id INTEGER NOT NULL,
+    link TEXT NOT NULL UNIQUE,
+    expires_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS invite_links_expires_at ON invite_links (expires_at);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS memberships;
\ No newline at end of file
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS memberships (
+    id INTEGER PRIMARY KEY,
+    chat_id INTEGER NOT NULL,
+    telegram_id INTEGER NOT NULL,
+    joined_at DATETIME NOT NULL,
+    left_at DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS memberships_chat_id_telegram_id ON memberships (chat_id, telegram_id);
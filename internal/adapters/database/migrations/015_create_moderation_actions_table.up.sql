@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS moderation_actions (
+    id INTEGER PRIMARY KEY,
+    action TEXT NOT NULL,
+    chat_id INTEGER NOT NULL,
+    telegram_id INTEGER NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at DATETIME NOT NULL,
+    last_error TEXT NOT NULL DEFAULT '',
+    dead BOOLEAN NOT NULL DEFAULT FALSE,
+    UNIQUE (chat_id, telegram_id)
+);
+
+CREATE INDEX IF NOT EXISTS moderation_actions_next_attempt_at ON moderation_actions (next_attempt_at);
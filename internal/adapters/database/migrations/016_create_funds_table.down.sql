@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS contributions;
+DROP TABLE IF EXISTS funds;
@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS funds (
+    id INTEGER PRIMARY KEY,
+    chat_id INTEGER NOT NULL,
+    celebrants TEXT NOT NULL,
+    organizer_id INTEGER NOT NULL DEFAULT 0,
+    pledges_until DATETIME NOT NULL,
+    ends_at DATETIME NOT NULL,
+    closed BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE TABLE IF NOT EXISTS contributions (
+    id INTEGER PRIMARY KEY,
+    fund_id INTEGER NOT NULL,
+    telegram_id INTEGER NOT NULL,
+    amount INTEGER NOT NULL DEFAULT 0,
+    paid BOOLEAN NOT NULL DEFAULT FALSE,
+    FOREIGN KEY (fund_id) REFERENCES funds(id),
+    UNIQUE (fund_id, telegram_id)
+);
+
+CREATE INDEX IF NOT EXISTS funds_ends_at ON funds (ends_at);
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS wishlist_items (
+    id INTEGER PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    title TEXT NOT NULL,
+    claimed_by INTEGER,
+    FOREIGN KEY (user_id) REFERENCES users(id),
+    FOREIGN KEY (claimed_by) REFERENCES users(id)
+);
+
+CREATE INDEX IF NOT EXISTS wishlist_items_user_id ON wishlist_items (user_id);
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS greetings (
+    id INTEGER PRIMARY KEY,
+    chat_id INTEGER NOT NULL,
+    celebrant_id INTEGER NOT NULL,
+    telegram_id INTEGER NOT NULL,
+    text TEXT NOT NULL DEFAULT '',
+    deliver_at DATETIME NOT NULL,
+    expires_at DATETIME NOT NULL,
+    delivered BOOLEAN NOT NULL DEFAULT FALSE,
+    UNIQUE (celebrant_id, deliver_at, telegram_id)
+);
+
+CREATE INDEX IF NOT EXISTS greetings_deliver_at ON greetings (deliver_at);
@@ -0,0 +1,29 @@
+CREATE TABLE IF NOT EXISTS polls (
+    id INTEGER PRIMARY KEY,
+    fund_id INTEGER NOT NULL UNIQUE,
+    chat_id INTEGER NOT NULL,
+    message_id INTEGER NOT NULL,
+    telegram_poll_id TEXT NOT NULL UNIQUE,
+    celebrants TEXT NOT NULL,
+    ends_at DATETIME NOT NULL,
+    closed BOOLEAN NOT NULL DEFAULT FALSE,
+    FOREIGN KEY (fund_id) REFERENCES funds(id)
+);
+
+CREATE TABLE IF NOT EXISTS poll_options (
+    poll_id INTEGER NOT NULL,
+    option_id INTEGER NOT NULL,
+    text TEXT NOT NULL,
+    FOREIGN KEY (poll_id) REFERENCES polls(id),
+    UNIQUE (poll_id, option_id)
+);
+
+CREATE TABLE IF NOT EXISTS poll_votes (
+    poll_id INTEGER NOT NULL,
+    telegram_id INTEGER NOT NULL,
+    option_id INTEGER NOT NULL,
+    FOREIGN KEY (poll_id) REFERENCES polls(id),
+    UNIQUE (poll_id, telegram_id)
+);
+
+CREATE INDEX IF NOT EXISTS polls_ends_at ON polls (ends_at);
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS funds_chat_id_ends_at;
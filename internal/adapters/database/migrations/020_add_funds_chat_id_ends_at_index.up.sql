@@ -0,0 +1 @@
+CREATE UNIQUE INDEX IF NOT EXISTS funds_chat_id_ends_at ON funds (chat_id, ends_at);
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS fund_celebrants (
+    id INTEGER PRIMARY KEY,
+    fund_id INTEGER NOT NULL,
+    telegram_id INTEGER NOT NULL,
+    FOREIGN KEY (fund_id) REFERENCES funds(id),
+    UNIQUE (fund_id, telegram_id)
+);
@@ -0,0 +1 @@
+ALTER TABLE funds DROP COLUMN timezone;
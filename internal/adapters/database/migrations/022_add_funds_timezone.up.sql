@@ -0,0 +1 @@
+ALTER TABLE funds ADD COLUMN timezone TEXT NOT NULL DEFAULT '';
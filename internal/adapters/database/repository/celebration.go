@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"birthdayapp/internal/adapters/database"
+	"birthdayapp/internal/core/domain"
+	"fmt"
+	"time"
+)
+
+type CelebrationRepository struct {
+	db *database.DB
+}
+
+func NewCelebrationRepository(db *database.DB) *CelebrationRepository {
+	return &CelebrationRepository{
+		db,
+	}
+}
+
+// ScheduleCelebration stores the celebration, a birthday already scheduled keeps its phases
+func (cr *CelebrationRepository) ScheduleCelebration(celebration *domain.Celebration) error {
+	query := `
+        INSERT INTO celebrations (telegram_id, date, timezone, plan_at, reveal_at)
+        VALUES (?, ?, ?, ?, ?)
+        ON CONFLICT (telegram_id, date) DO NOTHING
+    `
+
+	_, err := cr.db.Exec(query, celebration.TelegramID, celebration.Date.Format(time.DateOnly), celebration.Timezone,
+		celebration.PlanAt.UTC().Truncate(time.Second), celebration.RevealAt.UTC().Truncate(time.Second))
+	if err != nil {
+		return fmt.Errorf("error scheduling celebration of telegram_id %d: %w", celebration.TelegramID, err)
+	}
+	return nil
+}
+
+// GetDueCelebrations returns celebrations with a due planning or reveal, ordered by reveal time
+func (cr *CelebrationRepository) GetDueCelebrations(now time.Time) (*[]domain.Celebration, error) {
+	query := `
+        SELECT id, telegram_id, chat_id, date, timezone, plan_at, reveal_at, planned
+        FROM celebrations
+        WHERE (planned = FALSE AND plan_at <= ?) OR reveal_at <= ?
+        ORDER BY reveal_at, telegram_id
+    `
+
+	rows, err := cr.db.Query(query, now.UTC(), now.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("error querying due celebrations: %w", err)
+	}
+	defer rows.Close()
+
+	var celebrations []domain.Celebration
+	for rows.Next() {
+		var celebration domain.Celebration
+		var date string
+		if err := rows.Scan(&celebration.ID, &celebration.TelegramID, &celebration.ChatID, &date, &celebration.Timezone,
+			&celebration.PlanAt, &celebration.RevealAt, &celebration.Planned); err != nil {
+			return nil, fmt.Errorf("error scanning celebration: %w", err)
+		}
+		celebration.Date, err = time.Parse(time.DateOnly, date)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing celebration date %q: %w", date, err)
+		}
+		celebrations = append(celebrations, celebration)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating celebrations: %w", err)
+	}
+
+	return &celebrations, nil
+}
+
+// MarkCelebrationPlanned stores the end of planning together with the group the celebration takes place in
+func (cr *CelebrationRepository) MarkCelebrationPlanned(celebration *domain.Celebration) error {
+	query := `
+        UPDATE celebrations
+        SET planned = TRUE, chat_id = ?
+        WHERE id = ?
+    `
+
+	_, err := cr.db.Exec(query, celebration.ChatID, celebration.ID)
+	if err != nil {
+		return fmt.Errorf("error marking celebration of telegram_id %d planned: %w", celebration.TelegramID, err)
+	}
+	return nil
+}
+
+func (cr *CelebrationRepository) DeleteCelebration(celebration *domain.Celebration) error {
+	query := `
+        DELETE FROM celebrations
+        WHERE id = ?
+    `
+
+	_, err := cr.db.Exec(query, celebration.ID)
+	if err != nil {
+		return fmt.Errorf("error deleting celebration of telegram_id %d: %w", celebration.TelegramID, err)
+	}
+	return nil
+}
@@ -0,0 +1,279 @@
+package repository
+
+import (
+	"birthdayapp/internal/adapters/database"
+	"birthdayapp/internal/core/domain"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+type FundRepository struct {
+	db *database.DB
+}
+
+func NewFundRepository(db *database.DB) *FundRepository {
+	return &FundRepository{
+		db,
+	}
+}
+
+// CreateFund stores the fund together with an empty ledger entry of every member, a celebration has a single fund,
+// so the fund of the chat ending at the same time gets the new celebrants and members and false is returned
+func (fr *FundRepository) CreateFund(fund *domain.Fund, members []int64) (*domain.Fund, bool, error) {
+	tx, err := fr.db.Begin()
+	if err != nil {
+		return nil, false, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+        SELECT id
+        FROM funds
+        WHERE chat_id = ? AND ends_at = ?
+    `
+	created := false
+	err = tx.QueryRow(query, fund.ChatID, fund.EndsAt.UTC()).Scan(&fund.ID)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		query = `
+            INSERT INTO funds (chat_id, celebrants, pledges_until, ends_at, timezone)
+            VALUES (?, ?, ?, ?, ?)
+            RETURNING id
+        `
+		if err := tx.QueryRow(query, fund.ChatID, fund.Celebrants, fund.PledgesUntil.UTC(), fund.EndsAt.UTC(), fund.Timezone).Scan(&fund.ID); err != nil {
+			return nil, false, fmt.Errorf("error creating fund: %w", err)
+		}
+		created = true
+	case err != nil:
+		return nil, false, fmt.Errorf("error querying fund of chat %d: %w", fund.ChatID, err)
+	default:
+		query = `
+            UPDATE funds
+            SET celebrants = ?
+            WHERE id = ?
+        `
+		if _, err := tx.Exec(query, fund.Celebrants, fund.ID); err != nil {
+			return nil, false, fmt.Errorf("error updating fund %d: %w", fund.ID, err)
+		}
+	}
+
+	query = `
+        INSERT INTO fund_celebrants (fund_id, telegram_id)
+        VALUES (?, ?)
+        ON CONFLICT (fund_id, telegram_id) DO NOTHING
+    `
+	for _, telegramID := range fund.CelebrantIDs {
+		if _, err := tx.Exec(query, fund.ID, telegramID); err != nil {
+			return nil, false, fmt.Errorf("error adding celebrant telegram_id %d to fund: %w", telegramID, err)
+		}
+	}
+
+	query = `
+        INSERT INTO contributions (fund_id, telegram_id)
+        VALUES (?, ?)
+        ON CONFLICT (fund_id, telegram_id) DO NOTHING
+    `
+	for _, telegramID := range members {
+		if _, err := tx.Exec(query, fund.ID, telegramID); err != nil {
+			return nil, false, fmt.Errorf("error adding telegram_id %d to fund: %w", telegramID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, false, fmt.Errorf("error committing fund: %w", err)
+	}
+	return fund, created, nil
+}
+
+func (fr *FundRepository) GetFund(id int) (*domain.Fund, error) {
+	funds, err := fr.getFunds(`
+        WHERE id = ?
+    `, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(*funds) == 0 {
+		return nil, fmt.Errorf("fund %d: %w", id, domain.ErrNotFound)
+	}
+	return &(*funds)[0], nil
+}
+
+// GetFundCelebrants returns telegram ids of the celebrants of the fund
+func (fr *FundRepository) GetFundCelebrants(fundID int) ([]int64, error) {
+	query := `
+        SELECT telegram_id
+        FROM fund_celebrants
+        WHERE fund_id = ?
+        ORDER BY telegram_id
+    `
+
+	rows, err := fr.db.Query(query, fundID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying celebrants of fund %d: %w", fundID, err)
+	}
+	defer rows.Close()
+
+	var telegramIDs []int64
+	for rows.Next() {
+		var telegramID int64
+		if err := rows.Scan(&telegramID); err != nil {
+			return nil, fmt.Errorf("error scanning celebrant: %w", err)
+		}
+		telegramIDs = append(telegramIDs, telegramID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating celebrants: %w", err)
+	}
+
+	return telegramIDs, nil
+}
+
+// GetMemberFunds returns funds of the user that are still open
+func (fr *FundRepository) GetMemberFunds(telegramID int64, now time.Time) (*[]domain.Fund, error) {
+	return fr.getFunds(`
+        WHERE closed = FALSE AND ends_at > ? AND id IN (
+            SELECT fund_id
+            FROM contributions
+            WHERE telegram_id = ?
+        )
+        ORDER BY id
+    `, now.UTC(), telegramID)
+}
+
+// GetEndedFunds returns funds whose celebration has ended and which aren't closed yet
+func (fr *FundRepository) GetEndedFunds(now time.Time) (*[]domain.Fund, error) {
+	return fr.getFunds(`
+        WHERE closed = FALSE AND ends_at <= ?
+        ORDER BY ends_at, id
+    `, now.UTC())
+}
+
+func (fr *FundRepository) getFunds(condition string, args ...any) (*[]domain.Fund, error) {
+	query := `
+        SELECT id, chat_id, celebrants, organizer_id, pledges_until, ends_at, timezone, closed
+        FROM funds
+    ` + condition
+
+	rows, err := fr.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying funds: %w", err)
+	}
+	defer rows.Close()
+
+	var funds []domain.Fund
+	for rows.Next() {
+		var fund domain.Fund
+		if err := rows.Scan(&fund.ID, &fund.ChatID, &fund.Celebrants, &fund.OrganizerID, &fund.PledgesUntil,
+			&fund.EndsAt, &fund.Timezone, &fund.Closed); err != nil {
+			return nil, fmt.Errorf("error scanning fund: %w", err)
+		}
+		funds = append(funds, fund)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating funds: %w", err)
+	}
+
+	return &funds, nil
+}
+
+// GetContributions returns the ledger of the fund ordered by username
+func (fr *FundRepository) GetContributions(fundID int) (*[]domain.Contribution, error) {
+	query := `
+        SELECT c.id, c.fund_id, c.telegram_id, COALESCE(u.username, ''), c.amount, c.paid
+        FROM contributions c
+        LEFT JOIN users u ON u.telegram_id = c.telegram_id
+        WHERE c.fund_id = ?
+        ORDER BY u.username, c.telegram_id
+    `
+
+	rows, err := fr.db.Query(query, fundID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying contributions of fund %d: %w", fundID, err)
+	}
+	defer rows.Close()
+
+	var contributions []domain.Contribution
+	for rows.Next() {
+		var contribution domain.Contribution
+		if err := rows.Scan(&contribution.ID, &contribution.FundID, &contribution.TelegramID, &contribution.Username,
+			&contribution.Amount, &contribution.Paid); err != nil {
+			return nil, fmt.Errorf("error scanning contribution: %w", err)
+		}
+		contributions = append(contributions, contribution)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating contributions: %w", err)
+	}
+
+	return &contributions, nil
+}
+
+// SetOrganizer makes the user the organizer of the fund unless the fund already has one
+func (fr *FundRepository) SetOrganizer(fundID int, telegramID int64) error {
+	query := `
+        UPDATE funds
+        SET organizer_id = ?
+        WHERE id = ? AND organizer_id = 0
+    `
+
+	_, err := fr.db.Exec(query, telegramID, fundID)
+	if err != nil {
+		return fmt.Errorf("error setting organizer of fund %d: %w", fundID, err)
+	}
+	return nil
+}
+
+// Pledge changes the amount of an unpaid ledger entry
+func (fr *FundRepository) Pledge(fundID int, telegramID int64, amount int64) error {
+	query := `
+        UPDATE contributions
+        SET amount = ?
+        WHERE fund_id = ? AND telegram_id = ? AND paid = FALSE
+    `
+
+	return fr.updateContribution(query, amount, fundID, telegramID)
+}
+
+// MarkPaid marks a pledged ledger entry paid
+func (fr *FundRepository) MarkPaid(fundID int, telegramID int64) error {
+	query := `
+        UPDATE contributions
+        SET paid = TRUE
+        WHERE fund_id = ? AND telegram_id = ? AND amount > 0
+    `
+
+	return fr.updateContribution(query, fundID, telegramID)
+}
+
+func (fr *FundRepository) updateContribution(query string, args ...any) error {
+	result, err := fr.db.Exec(query, args...)
+	if err != nil {
+		return fmt.Errorf("error updating contribution: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error checking rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("contribution: %w", domain.ErrNotFound)
+	}
+	return nil
+}
+
+func (fr *FundRepository) CloseFund(fundID int) error {
+	query := `
+        UPDATE funds
+        SET closed = TRUE
+        WHERE id = ?
+    `
+
+	_, err := fr.db.Exec(query, fundID)
+	if err != nil {
+		return fmt.Errorf("error closing fund %d: %w", fundID, err)
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"birthdayapp/internal/adapters/database"
+	"birthdayapp/internal/core/domain"
+	"fmt"
+	"time"
+)
+
+type GreetingRepository struct {
+	db *database.DB
+}
+
+func NewGreetingRepository(db *database.DB) *GreetingRepository {
+	return &GreetingRepository{
+		db,
+	}
+}
+
+// SaveGreetingRequest stores the request of a greeting, a request already sent keeps its greeting and false is returned
+func (gr *GreetingRepository) SaveGreetingRequest(greeting *domain.Greeting) (bool, error) {
+	query := `
+        INSERT INTO greetings (chat_id, celebrant_id, telegram_id, deliver_at, expires_at)
+        VALUES (?, ?, ?, ?, ?)
+        ON CONFLICT (celebrant_id, deliver_at, telegram_id) DO NOTHING
+    `
+
+	res, err := gr.db.Exec(query, greeting.ChatID, greeting.CelebrantID, greeting.TelegramID,
+		greeting.DeliverAt.UTC().Truncate(time.Second), greeting.ExpiresAt.UTC().Truncate(time.Second))
+	if err != nil {
+		return false, fmt.Errorf("error saving greeting request of telegram_id %d: %w", greeting.TelegramID, err)
+	}
+	saved, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error getting rows affected: %w", err)
+	}
+	return saved > 0, nil
+}
+
+// GetOpenGreetings returns greetings the user may still write or change, greetings of delivered cards only until answered,
+// the card delivered first goes first
+func (gr *GreetingRepository) GetOpenGreetings(telegramID int64, now time.Time) (*[]domain.Greeting, error) {
+	return gr.getGreetings(`
+        WHERE g.telegram_id = ? AND g.expires_at > ? AND (g.delivered = FALSE OR g.text = '')
+        ORDER BY g.deliver_at, g.id
+    `, telegramID, now.UTC())
+}
+
+// GetDueGreetings returns greetings of undelivered cards whose time has come, grouped by card
+func (gr *GreetingRepository) GetDueGreetings(now time.Time) (*[]domain.Greeting, error) {
+	return gr.getGreetings(`
+        WHERE g.delivered = FALSE AND g.deliver_at <= ?
+        ORDER BY g.celebrant_id, g.deliver_at, g.id
+    `, now.UTC())
+}
+
+func (gr *GreetingRepository) getGreetings(condition string, args ...any) (*[]domain.Greeting, error) {
+	query := `
+        SELECT g.id, g.chat_id, g.celebrant_id, COALESCE(celebrant.username, ''), g.telegram_id, COALESCE(u.username, ''),
+               g.text, g.deliver_at, g.expires_at, g.delivered
+        FROM greetings g
+        LEFT JOIN users celebrant ON celebrant.telegram_id = g.celebrant_id
+        LEFT JOIN users u ON u.telegram_id = g.telegram_id
+    ` + condition
+
+	rows, err := gr.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying greetings: %w", err)
+	}
+	defer rows.Close()
+
+	var greetings []domain.Greeting
+	for rows.Next() {
+		var greeting domain.Greeting
+		if err := rows.Scan(&greeting.ID, &greeting.ChatID, &greeting.CelebrantID, &greeting.CelebrantUsername,
+			&greeting.TelegramID, &greeting.Username, &greeting.Text, &greeting.DeliverAt, &greeting.ExpiresAt,
+			&greeting.Delivered); err != nil {
+			return nil, fmt.Errorf("error scanning greeting: %w", err)
+		}
+		greetings = append(greetings, greeting)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating greetings: %w", err)
+	}
+
+	return &greetings, nil
+}
+
+func (gr *GreetingRepository) SaveGreetingText(id int, text string) error {
+	query := `
+        UPDATE greetings
+        SET text = ?
+        WHERE id = ?
+    `
+
+	_, err := gr.db.Exec(query, text, id)
+	if err != nil {
+		return fmt.Errorf("error saving greeting %d: %w", id, err)
+	}
+	return nil
+}
+
+func (gr *GreetingRepository) MarkGreetingsDelivered(celebrantID int64, deliverAt time.Time) error {
+	query := `
+        UPDATE greetings
+        SET delivered = TRUE
+        WHERE celebrant_id = ? AND deliver_at = ?
+    `
+
+	_, err := gr.db.Exec(query, celebrantID, deliverAt.UTC())
+	if err != nil {
+		return fmt.Errorf("error marking card of telegram_id %d delivered: %w", celebrantID, err)
+	}
+	return nil
+}
+
+// DeleteExpiredGreetings drops greetings of cards whose celebration is over
+func (gr *GreetingRepository) DeleteExpiredGreetings(now time.Time) error {
+	query := `
+        DELETE FROM greetings
+        WHERE expires_at <= ?
+    `
+
+	_, err := gr.db.Exec(query, now.UTC())
+	if err != nil {
+		return fmt.Errorf("error deleting expired greetings: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"birthdayapp/internal/adapters/database"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type GroupRepository struct {
+	db *database.DB
+}
+
+func NewGroupRepository(db *database.DB) *GroupRepository {
+	return &GroupRepository{
+		db,
+	}
+}
+
+// AllocateGroup takes the first free group of the pool until releaseAfter. When every group is taken
+// the one released first is shared, its allocation is extended to releaseAfter and shared is true
+func (gr *GroupRepository) AllocateGroup(pool []int64, now time.Time, releaseAfter time.Time) (int64, bool, error) {
+	if len(pool) == 0 {
+		return 0, false, errors.New("empty pool of birthday groups")
+	}
+
+	insertQuery := `
+        INSERT INTO group_allocations (chat_id, allocated_at, release_after)
+        VALUES (?, ?, ?)
+        ON CONFLICT (chat_id) DO NOTHING
+    `
+	for _, chatID := range pool {
+		result, err := gr.db.Exec(insertQuery, chatID, now.UTC().Truncate(time.Second), releaseAfter.UTC().Truncate(time.Second))
+		if err != nil {
+			return 0, false, fmt.Errorf("error allocating group %d: %w", chatID, err)
+		}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return 0, false, fmt.Errorf("error checking rows affected: %w", err)
+		}
+		if rowsAffected == 1 {
+			return chatID, false, nil
+		}
+	}
+
+	args := make([]any, len(pool))
+	for i, chatID := range pool {
+		args[i] = chatID
+	}
+	selectQuery := fmt.Sprintf(`
+        SELECT chat_id
+        FROM group_allocations
+        WHERE chat_id IN (%s)
+        ORDER BY release_after, chat_id
+        LIMIT 1
+    `, strings.TrimSuffix(strings.Repeat("?, ", len(pool)), ", "))
+
+	var chatID int64
+	if err := gr.db.QueryRow(selectQuery, args...).Scan(&chatID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			//released meanwhile, share the first group without tracking it
+			return pool[0], true, nil
+		}
+		return 0, false, fmt.Errorf("error selecting group to share: %w", err)
+	}
+
+	updateQuery := `
+        UPDATE group_allocations
+        SET release_after = MAX(release_after, ?)
+        WHERE chat_id = ?
+    `
+	if _, err := gr.db.Exec(updateQuery, releaseAfter.UTC().Truncate(time.Second), chatID); err != nil {
+		return 0, false, fmt.Errorf("error extending allocation of group %d: %w", chatID, err)
+	}
+	return chatID, true, nil
+}
+
+// ReleaseGroups frees groups whose celebrations ended and nobody is waiting for a kick
+func (gr *GroupRepository) ReleaseGroups(now time.Time) error {
+	query := `
+        DELETE FROM group_allocations
+        WHERE release_after <= ?
+            AND chat_id NOT IN (SELECT chat_id FROM kicks)
+    `
+
+	_, err := gr.db.Exec(query, now.UTC())
+	if err != nil {
+		return fmt.Errorf("error releasing groups: %w", err)
+	}
+	return nil
+}
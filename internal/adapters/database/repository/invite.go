@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"birthdayapp/internal/adapters/database"
+	"birthdayapp/internal/core/domain"
+	"fmt"
+	"time"
+)
+
+type InviteLinkRepository struct {
+	db *database.DB
+}
+
+func NewInviteLinkRepository(db *database.DB) *InviteLinkRepository {
+	return &InviteLinkRepository{
+		db,
+	}
+}
+
+func (ir *InviteLinkRepository) SaveInviteLink(link *domain.InviteLink) error {
+	query := `
+        INSERT INTO invite_links (chat_id, telegram_id, link, expires_at)
+        VALUES (?, ?, ?, ?)
+    `
+
+	_, err := ir.db.Exec(query, link.ChatID, link.TelegramID, link.Link, link.ExpiresAt.UTC().Truncate(time.Second))
+	if err != nil {
+		return fmt.Errorf("error saving invite link of telegram_id %d: %w", link.TelegramID, err)
+	}
+	return nil
+}
+
+func (ir *InviteLinkRepository) GetExpiredInviteLinks(now time.Time) (*[]domain.InviteLink, error) {
+	query := `
+        SELECT id, chat_id, telegram_id, link, expires_at
+        FROM invite_links
+        WHERE expires_at <= ?
+        ORDER BY expires_at
+    `
+
+	rows, err := ir.db.Query(query, now.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("error querying expired invite links: %w", err)
+	}
+	defer rows.Close()
+
+	var links []domain.InviteLink
+	for rows.Next() {
+		var link domain.InviteLink
+		if err := rows.Scan(&link.ID, &link.ChatID, &link.TelegramID, &link.Link, &link.ExpiresAt); err != nil {
+			return nil, fmt.Errorf("error scanning invite link: %w", err)
+		}
+		links = append(links, link)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating invite links: %w", err)
+	}
+
+	return &links, nil
+}
+
+// HasActiveInviteLink reports whether the user was invited to the chat by a link that hasn't expired
+func (ir *InviteLinkRepository) HasActiveInviteLink(chatID int64, telegramID int64, now time.Time) (bool, error) {
+	query := `
+        SELECT EXISTS (
+            SELECT 1
+            FROM invite_links
+            WHERE chat_id = ? AND telegram_id = ? AND expires_at > ?
+        )
+    `
+
+	var exists bool
+	if err := ir.db.QueryRow(query, chatID, telegramID, now.UTC()).Scan(&exists); err != nil {
+		return false, fmt.Errorf("error checking invite link of telegram_id %d: %w", telegramID, err)
+	}
+	return exists, nil
+}
+
+func (ir *InviteLinkRepository) DeleteInviteLink(link *domain.InviteLink) error {
+	query := `
+        DELETE FROM invite_links
+        WHERE id = ?
+    `
+
+	_, err := ir.db.Exec(query, link.ID)
+	if err != nil {
+		return fmt.Errorf("error deleting invite link of telegram_id %d: %w", link.TelegramID, err)
+	}
+	return nil
+}
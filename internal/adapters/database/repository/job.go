@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"birthdayapp/internal/adapters/database"
+	"birthdayapp/internal/core/domain"
+	"fmt"
+	"time"
+)
+
+type JobRunRepository struct {
+	db *database.DB
+}
+
+func NewJobRunRepository(db *database.DB) *JobRunRepository {
+	return &JobRunRepository{
+		db,
+	}
+}
+
+// ClaimJobRun records the run and returns false if the date was already claimed
+func (jr *JobRunRepository) ClaimJobRun(run *domain.JobRun) (bool, error) {
+	query := `
+        INSERT INTO job_runs (job, date, timezone, started_at)
+        VALUES (?, ?, ?, ?)
+        ON CONFLICT (job, date, timezone) DO NOTHING
+    `
+
+	result, err := jr.db.Exec(query, run.Job, run.Date.Format(time.DateOnly), run.Timezone, run.StartedAt.UTC())
+	if err != nil {
+		return false, fmt.Errorf("error claiming job %s run on %s: %w", run.Job, run.Date.Format(time.DateOnly), err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error checking rows affected: %w", err)
+	}
+
+	return rowsAffected == 1, nil
+}
+
+// ReleaseJobRun removes the claim so the date is processed again
+func (jr *JobRunRepository) ReleaseJobRun(run *domain.JobRun) error {
+	query := `
+        DELETE FROM job_runs
+        WHERE job = ? AND date = ? AND timezone = ?
+    `
+
+	_, err := jr.db.Exec(query, run.Job, run.Date.Format(time.DateOnly), run.Timezone)
+	if err != nil {
+		return fmt.Errorf("error releasing job %s run on %s: %w", run.Job, run.Date.Format(time.DateOnly), err)
+	}
+	return nil
+}
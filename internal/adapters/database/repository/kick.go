@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"birthdayapp/internal/adapters/database"
+	"birthdayapp/internal/core/domain"
+	"fmt"
+	"time"
+)
+
+type KickRepository struct {
+	db *database.DB
+}
+
+func NewKickRepository(db *database.DB) *KickRepository {
+	return &KickRepository{
+		db,
+	}
+}
+
+// ScheduleKick stores the kick, a user already waiting for a kick in the chat stays until the later due time
+func (kr *KickRepository) ScheduleKick(kick *domain.Kick) error {
+	query := `
+        INSERT INTO kicks (chat_id, telegram_id, due_at)
+        VALUES (?, ?, ?)
+        ON CONFLICT (chat_id, telegram_id) DO UPDATE SET due_at = MAX(due_at, excluded.due_at)
+    `
+
+	_, err := kr.db.Exec(query, kick.ChatID, kick.TelegramID, kick.DueAt.UTC().Truncate(time.Second))
+	if err != nil {
+		return fmt.Errorf("error scheduling kick of telegram_id %d: %w", kick.TelegramID, err)
+	}
+	return nil
+}
+
+func (kr *KickRepository) GetDueKicks(now time.Time) (*[]domain.Kick, error) {
+	query := `
+        SELECT id, chat_id, telegram_id, due_at
+        FROM kicks
+        WHERE due_at <= ?
+        ORDER BY due_at
+    `
+
+	rows, err := kr.db.Query(query, now.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("error querying due kicks: %w", err)
+	}
+	defer rows.Close()
+
+	var kicks []domain.Kick
+	for rows.Next() {
+		var kick domain.Kick
+		if err := rows.Scan(&kick.ID, &kick.ChatID, &kick.TelegramID, &kick.DueAt); err != nil {
+			return nil, fmt.Errorf("error scanning kick: %w", err)
+		}
+		kicks = append(kicks, kick)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating kicks: %w", err)
+	}
+
+	return &kicks, nil
+}
+
+// DeleteKick removes the kick only if it wasn't rescheduled after it was read
+func (kr *KickRepository) DeleteKick(kick *domain.Kick) error {
+	query := `
+        DELETE FROM kicks
+        WHERE id = ? AND due_at = ?
+    `
+
+	_, err := kr.db.Exec(query, kick.ID, kick.DueAt.UTC())
+	if err != nil {
+		return fmt.Errorf("error deleting kick of telegram_id %d: %w", kick.TelegramID, err)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"birthdayapp/internal/adapters/database"
+	"birthdayapp/internal/core/domain"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+type MembershipRepository struct {
+	db *database.DB
+}
+
+func NewMembershipRepository(db *database.DB) *MembershipRepository {
+	return &MembershipRepository{
+		db,
+	}
+}
+
+// RecordJoin opens a stay of the user in the chat, a user already in the chat keeps the open stay
+func (mr *MembershipRepository) RecordJoin(chatID int64, telegramID int64, at time.Time) error {
+	query := `
+        INSERT INTO memberships (chat_id, telegram_id, joined_at)
+        SELECT ?, ?, ?
+        WHERE NOT EXISTS (
+            SELECT 1
+            FROM memberships
+            WHERE chat_id = ? AND telegram_id = ? AND left_at IS NULL
+        )
+    `
+
+	_, err := mr.db.Exec(query, chatID, telegramID, at.UTC(), chatID, telegramID)
+	if err != nil {
+		return fmt.Errorf("error recording join of telegram_id %d: %w", telegramID, err)
+	}
+	return nil
+}
+
+// RecordLeave closes the open stay of the user in the chat
+func (mr *MembershipRepository) RecordLeave(chatID int64, telegramID int64, at time.Time) error {
+	query := `
+        UPDATE memberships
+        SET left_at = ?
+        WHERE chat_id = ? AND telegram_id = ? AND left_at IS NULL
+    `
+
+	_, err := mr.db.Exec(query, at.UTC(), chatID, telegramID)
+	if err != nil {
+		return fmt.Errorf("error recording leave of telegram_id %d: %w", telegramID, err)
+	}
+	return nil
+}
+
+// IsMember reports whether the user has an open stay in the chat and whether any stay of the user is recorded,
+// users who joined before the tracking have no stays
+func (mr *MembershipRepository) IsMember(chatID int64, telegramID int64) (bool, bool, error) {
+	query := `
+        SELECT COUNT(*) > COUNT(left_at), COUNT(*) > 0
+        FROM memberships
+        WHERE chat_id = ? AND telegram_id = ?
+    `
+
+	var member, tracked bool
+	if err := mr.db.QueryRow(query, chatID, telegramID).Scan(&member, &tracked); err != nil {
+		return false, false, fmt.Errorf("error checking membership of telegram_id %d: %w", telegramID, err)
+	}
+	return member, tracked, nil
+}
+
+// GetAttendance returns stays in the chat overlapping the period, ordered by join time
+func (mr *MembershipRepository) GetAttendance(chatID int64, from time.Time, to time.Time) (*[]domain.Membership, error) {
+	query := `
+        SELECT id, chat_id, telegram_id, joined_at, left_at
+        FROM memberships
+        WHERE chat_id = ? AND joined_at < ? AND (left_at IS NULL OR left_at >= ?)
+        ORDER BY joined_at, telegram_id
+    `
+
+	rows, err := mr.db.Query(query, chatID, to.UTC(), from.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("error querying attendance of chat %d: %w", chatID, err)
+	}
+	defer rows.Close()
+
+	var memberships []domain.Membership
+	for rows.Next() {
+		var membership domain.Membership
+		var leftAt sql.NullTime
+		if err := rows.Scan(&membership.ID, &membership.ChatID, &membership.TelegramID, &membership.JoinedAt, &leftAt); err != nil {
+			return nil, fmt.Errorf("error scanning membership: %w", err)
+		}
+		membership.LeftAt = leftAt.Time
+		memberships = append(memberships, membership)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating memberships: %w", err)
+	}
+
+	return &memberships, nil
+}
@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"birthdayapp/internal/adapters/database"
+	"birthdayapp/internal/core/domain"
+	"fmt"
+	"time"
+)
+
+type ModerationRepository struct {
+	db *database.DB
+}
+
+func NewModerationRepository(db *database.DB) *ModerationRepository {
+	return &ModerationRepository{
+		db,
+	}
+}
+
+// SaveAction stores the action, it replaces any action queued earlier for the user in the chat
+func (mr *ModerationRepository) SaveAction(action *domain.ModerationAction) error {
+	query := `
+        INSERT INTO moderation_actions (action, chat_id, telegram_id, attempts, next_attempt_at, last_error, dead)
+        VALUES (?, ?, ?, ?, ?, ?, ?)
+        ON CONFLICT (chat_id, telegram_id) DO UPDATE SET
+            action = excluded.action,
+            attempts = excluded.attempts,
+            next_attempt_at = excluded.next_attempt_at,
+            last_error = excluded.last_error,
+            dead = excluded.dead
+    `
+
+	_, err := mr.db.Exec(query, action.Action, action.ChatID, action.TelegramID, action.Attempts,
+		action.NextAttemptAt.UTC().Truncate(time.Second), action.LastError, action.Dead)
+	if err != nil {
+		return fmt.Errorf("error saving %s of telegram_id %d: %w", action.Action, action.TelegramID, err)
+	}
+	return nil
+}
+
+func (mr *ModerationRepository) GetDueActions(now time.Time) (*[]domain.ModerationAction, error) {
+	return mr.getActions(`
+        WHERE dead = FALSE AND next_attempt_at <= ?
+        ORDER BY next_attempt_at, id
+    `, now.UTC())
+}
+
+func (mr *ModerationRepository) GetDeadActions() (*[]domain.ModerationAction, error) {
+	return mr.getActions(`
+        WHERE dead = TRUE
+        ORDER BY id
+    `)
+}
+
+func (mr *ModerationRepository) getActions(condition string, args ...any) (*[]domain.ModerationAction, error) {
+	query := `
+        SELECT id, action, chat_id, telegram_id, attempts, next_attempt_at, last_error, dead
+        FROM moderation_actions
+    ` + condition
+
+	rows, err := mr.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying moderation actions: %w", err)
+	}
+	defer rows.Close()
+
+	var actions []domain.ModerationAction
+	for rows.Next() {
+		var action domain.ModerationAction
+		if err := rows.Scan(&action.ID, &action.Action, &action.ChatID, &action.TelegramID, &action.Attempts,
+			&action.NextAttemptAt, &action.LastError, &action.Dead); err != nil {
+			return nil, fmt.Errorf("error scanning moderation action: %w", err)
+		}
+		actions = append(actions, action)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating moderation actions: %w", err)
+	}
+
+	return &actions, nil
+}
+
+// DeleteActions drops queued and dead actions of the user in the chat
+func (mr *ModerationRepository) DeleteActions(chatID int64, telegramID int64) error {
+	query := `
+        DELETE FROM moderation_actions
+        WHERE chat_id = ? AND telegram_id = ?
+    `
+
+	_, err := mr.db.Exec(query, chatID, telegramID)
+	if err != nil {
+		return fmt.Errorf("error deleting moderation actions of telegram_id %d: %w", telegramID, err)
+	}
+	return nil
+}
+
+// RetryDeadActions queues the dead action with the id again, every dead action when id is 0
+func (mr *ModerationRepository) RetryDeadActions(id int, now time.Time) (int64, error) {
+	query := `
+        UPDATE moderation_actions
+        SET dead = FALSE, attempts = 0, next_attempt_at = ?
+        WHERE dead = TRUE AND (? = 0 OR id = ?)
+    `
+
+	result, err := mr.db.Exec(query, now.UTC().Truncate(time.Second), id, id)
+	if err != nil {
+		return 0, fmt.Errorf("error retrying dead moderation actions: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("error checking rows affected: %w", err)
+	}
+	return rowsAffected, nil
+}
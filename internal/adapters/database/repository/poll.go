@@ -0,0 +1,187 @@
+package repository
+
+import (
+	"birthdayapp/internal/adapters/database"
+	"birthdayapp/internal/core/domain"
+	"fmt"
+	"time"
+)
+
+type PollRepository struct {
+	db *database.DB
+}
+
+func NewPollRepository(db *database.DB) *PollRepository {
+	return &PollRepository{
+		db,
+	}
+}
+
+// CreatePoll stores the poll together with its options, a fund has a single poll
+func (pr *PollRepository) CreatePoll(poll *domain.Poll) (*domain.Poll, error) {
+	tx, err := pr.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+        INSERT INTO polls (fund_id, chat_id, message_id, telegram_poll_id, celebrants, ends_at)
+        VALUES (?, ?, ?, ?, ?, ?)
+        RETURNING id
+    `
+	if err := tx.QueryRow(query, poll.FundID, poll.ChatID, poll.MessageID, poll.PollID, poll.Celebrants,
+		poll.EndsAt.UTC()).Scan(&poll.ID); err != nil {
+		return nil, fmt.Errorf("error creating poll of fund %d: %w", poll.FundID, err)
+	}
+
+	query = `
+        INSERT INTO poll_options (poll_id, option_id, text)
+        VALUES (?, ?, ?)
+    `
+	for _, option := range poll.Options {
+		if _, err := tx.Exec(query, poll.ID, option.ID, option.Text); err != nil {
+			return nil, fmt.Errorf("error adding option %d to poll: %w", option.ID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing poll: %w", err)
+	}
+	return poll, nil
+}
+
+// GetPoll returns the poll by the telegram poll identifier, options aren't loaded
+func (pr *PollRepository) GetPoll(pollID string) (*domain.Poll, error) {
+	return pr.getPoll(`
+        WHERE telegram_poll_id = ?
+    `, pollID)
+}
+
+// GetFundPoll returns the poll of the fund, options aren't loaded
+func (pr *PollRepository) GetFundPoll(fundID int) (*domain.Poll, error) {
+	return pr.getPoll(`
+        WHERE fund_id = ?
+    `, fundID)
+}
+
+func (pr *PollRepository) getPoll(condition string, arg any) (*domain.Poll, error) {
+	polls, err := pr.getPolls(condition, arg)
+	if err != nil {
+		return nil, err
+	}
+	if len(*polls) == 0 {
+		return nil, fmt.Errorf("poll %v: %w", arg, domain.ErrNotFound)
+	}
+	return &(*polls)[0], nil
+}
+
+// GetEndedPolls returns polls whose deadline has passed and which aren't closed yet
+func (pr *PollRepository) GetEndedPolls(now time.Time) (*[]domain.Poll, error) {
+	return pr.getPolls(`
+        WHERE closed = FALSE AND ends_at <= ?
+        ORDER BY ends_at, id
+    `, now.UTC())
+}
+
+func (pr *PollRepository) getPolls(condition string, args ...any) (*[]domain.Poll, error) {
+	query := `
+        SELECT id, fund_id, chat_id, message_id, telegram_poll_id, celebrants, ends_at, closed
+        FROM polls
+    ` + condition
+
+	rows, err := pr.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying polls: %w", err)
+	}
+	defer rows.Close()
+
+	var polls []domain.Poll
+	for rows.Next() {
+		var poll domain.Poll
+		if err := rows.Scan(&poll.ID, &poll.FundID, &poll.ChatID, &poll.MessageID, &poll.PollID, &poll.Celebrants,
+			&poll.EndsAt, &poll.Closed); err != nil {
+			return nil, fmt.Errorf("error scanning poll: %w", err)
+		}
+		polls = append(polls, poll)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating polls: %w", err)
+	}
+
+	return &polls, nil
+}
+
+// GetPollResults returns options of the poll in their order with the number of votes
+func (pr *PollRepository) GetPollResults(id int) (*[]domain.PollOption, error) {
+	query := `
+        SELECT o.option_id, o.text, COUNT(v.telegram_id)
+        FROM poll_options o
+        LEFT JOIN poll_votes v ON v.poll_id = o.poll_id AND v.option_id = o.option_id
+        WHERE o.poll_id = ?
+        GROUP BY o.option_id, o.text
+        ORDER BY o.option_id
+    `
+
+	rows, err := pr.db.Query(query, id)
+	if err != nil {
+		return nil, fmt.Errorf("error querying results of poll %d: %w", id, err)
+	}
+	defer rows.Close()
+
+	var options []domain.PollOption
+	for rows.Next() {
+		var option domain.PollOption
+		if err := rows.Scan(&option.ID, &option.Text, &option.Votes); err != nil {
+			return nil, fmt.Errorf("error scanning poll option: %w", err)
+		}
+		options = append(options, option)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating poll options: %w", err)
+	}
+
+	return &options, nil
+}
+
+// SaveVote stores the answer of the user, a changed answer replaces the previous one
+func (pr *PollRepository) SaveVote(id int, telegramID int64, optionID int) error {
+	query := `
+        INSERT INTO poll_votes (poll_id, telegram_id, option_id)
+        VALUES (?, ?, ?)
+        ON CONFLICT (poll_id, telegram_id) DO UPDATE SET option_id = excluded.option_id
+    `
+
+	_, err := pr.db.Exec(query, id, telegramID, optionID)
+	if err != nil {
+		return fmt.Errorf("error saving vote of telegram_id %d: %w", telegramID, err)
+	}
+	return nil
+}
+
+func (pr *PollRepository) DeleteVote(id int, telegramID int64) error {
+	query := `
+        DELETE FROM poll_votes
+        WHERE poll_id = ? AND telegram_id = ?
+    `
+
+	_, err := pr.db.Exec(query, id, telegramID)
+	if err != nil {
+		return fmt.Errorf("error deleting vote of telegram_id %d: %w", telegramID, err)
+	}
+	return nil
+}
+
+func (pr *PollRepository) ClosePoll(id int) error {
+	query := `
+        UPDATE polls
+        SET closed = TRUE
+        WHERE id = ?
+    `
+
+	_, err := pr.db.Exec(query, id)
+	if err != nil {
+		return fmt.Errorf("error closing poll %d: %w", id, err)
+	}
+	return nil
+}
@@ -28,7 +28,7 @@ func (sr *SubscriptionsRepository) InsertSubscriptionByTelegramID(subscription *
 	subscribe_to_ids AS (
 		SELECT id AS subscribe_to_id
 		FROM users
-		WHERE telegram_id = $2
+		WHERE telegram_id = $2 AND active = TRUE
 	)
 	INSERT INTO subscriptions (subscriber, subscribe_to)
 	SELECT subscriber_id, subscribe_to_id
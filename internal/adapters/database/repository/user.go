@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"github.com/mattn/go-sqlite3"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -21,14 +22,74 @@ func NewUserRepository(db *database.DB) *UserRepository {
 	}
 }
 
+// userColumns returns users columns in the order read by scanUser
+func userColumns(alias string) string {
+	columns := []string{"id", "username", "telegram_id", "birthday", "notify_birthday", "active",
+		"telegram_id_source", "username_source", "birthday_source", "timezone", "reminder_days", "digest_opt_out"}
+	for i, column := range columns {
+		columns[i] = alias + column
+	}
+	return strings.Join(columns, ", ")
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanUser(row rowScanner) (*domain.User, error) {
+	var user domain.User
+	var reminderDays sql.NullString
+	err := row.Scan(&user.ID, &user.Username, &user.TelegramID, &user.Birthday, &user.NotifyBirthday, &user.Active,
+		&user.Sources.TelegramID, &user.Sources.Username, &user.Sources.Birthday, &user.Timezone, &reminderDays, &user.DigestOptOut)
+	if err != nil {
+		return nil, err
+	}
+	user.ReminderDays, err = parseReminderDays(reminderDays)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// reminder days are stored as "7,1", NULL keeps the configured default and "" turns reminders off
+func formatReminderDays(days []int) sql.NullString {
+	if days == nil {
+		return sql.NullString{}
+	}
+	values := make([]string, len(days))
+	for i, day := range days {
+		values[i] = strconv.Itoa(day)
+	}
+	return sql.NullString{String: strings.Join(values, ","), Valid: true}
+}
+
+func parseReminderDays(value sql.NullString) ([]int, error) {
+	if !value.Valid {
+		return nil, nil
+	}
+	days := []int{}
+	if value.String == "" {
+		return days, nil
+	}
+	for _, dayStr := range strings.Split(value.String, ",") {
+		day, aErr := strconv.Atoi(dayStr)
+		if aErr != nil {
+			return nil, fmt.Errorf("error parse reminder days %q: %w", value.String, aErr)
+		}
+		days = append(days, day)
+	}
+	return days, nil
+}
+
 func (u *UserRepository) InsertUser(user *domain.User) (*domain.User, error) {
 	query := `
-        INSERT INTO users (username, telegram_id, birthday, notify_birthday) 
-        VALUES ( $1, $2, $3, $4)
+        INSERT INTO users (username, telegram_id, birthday, notify_birthday, active, telegram_id_source, username_source, birthday_source) 
+        VALUES ( $1, $2, $3, $4, $5, $6, $7, $8)
         ON CONFLICT DO NOTHING
         RETURNING id
     `
-	err := u.db.QueryRow(query, user.Username, user.TelegramID, user.Birthday, user.NotifyBirthday).Scan(&user.ID)
+	err := u.db.QueryRow(query, user.Username, user.TelegramID, user.Birthday, user.NotifyBirthday, user.Active,
+		user.Sources.TelegramID, user.Sources.Username, user.Sources.Birthday).Scan(&user.ID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, fmt.Errorf("user %s: %w", user.Username, domain.ErrAlreadyExist)
@@ -38,46 +99,6 @@ func (u *UserRepository) InsertUser(user *domain.User) (*domain.User, error) {
 	return user, nil
 }
 
-func (u *UserRepository) InsertUsers(users *[]domain.User) error {
-
-	tx, txErr := u.db.Begin()
-	if txErr != nil {
-		return fmt.Errorf("error begin transaction: %v", txErr)
-	}
-
-	defer func() {
-		if txErr != nil {
-			tx.Rollback()
-		}
-		tx.Commit()
-	}()
-
-	stmt, pErr := tx.Prepare(`
-	INSERT INTO users (username, telegram_id, birthday, notify_birthday) 
-	VALUES (?,?,?,?)
-	`)
-	defer stmt.Close()
-	if pErr != nil {
-		return fmt.Errorf("error prepare statement: %v", pErr)
-	}
-
-	for _, user := range *users {
-		_, eErr := stmt.Exec(user.Username, user.TelegramID, user.Birthday, user.NotifyBirthday)
-		if eErr != nil {
-			switch {
-			case errors.Is(eErr, sql.ErrNoRows):
-				return domain.ErrAlreadyExist
-			case isUniqueConstraintError(eErr):
-				return domain.ErrAlreadyExist
-			default:
-				return fmt.Errorf("error execute statement for user %v: %v", user.Username, eErr)
-			}
-		}
-	}
-
-	return nil
-}
-
 func isUniqueConstraintError(err error) bool {
 	var sqliteErr sqlite3.Error
 	if errors.As(err, &sqliteErr) {
@@ -111,18 +132,199 @@ func (u *UserRepository) ChangeNotifyBirthdayByTelegramID(user *domain.User) (*d
 	return user, nil
 }
 
-func (u *UserRepository) GetUserByTelegramID(user *domain.User) (*domain.User, error) {
+func (u *UserRepository) ChangeTimezoneByTelegramID(user *domain.User) (*domain.User, error) {
 
 	query := `
-        SELECT id, username, telegram_id, birthday, notify_birthday
-        FROM users
+        UPDATE users
+        SET timezone = ?
+        WHERE telegram_id = ?
+    `
+
+	result, err := u.db.Exec(query, user.Timezone, user.TelegramID)
+	if err != nil {
+		return nil, fmt.Errorf("error updating timezone: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("error checking rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return nil, fmt.Errorf("telegram_id %d: %w", user.TelegramID, domain.ErrNotFound)
+	}
+
+	return user, nil
+}
+
+func (u *UserRepository) ChangeReminderDaysByTelegramID(user *domain.User) (*domain.User, error) {
+
+	query := `
+        UPDATE users
+        SET reminder_days = ?
+        WHERE telegram_id = ?
+    `
+
+	result, err := u.db.Exec(query, formatReminderDays(user.ReminderDays), user.TelegramID)
+	if err != nil {
+		return nil, fmt.Errorf("error updating reminder days: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("error checking rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return nil, fmt.Errorf("telegram_id %d: %w", user.TelegramID, domain.ErrNotFound)
+	}
+
+	return user, nil
+}
+
+func (u *UserRepository) ChangeDigestOptOutByTelegramID(user *domain.User) (*domain.User, error) {
+
+	query := `
+        UPDATE users
+        SET digest_opt_out = ?
+        WHERE telegram_id = ?
+    `
+
+	result, err := u.db.Exec(query, user.DigestOptOut, user.TelegramID)
+	if err != nil {
+		return nil, fmt.Errorf("error updating digest opt-out: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("error checking rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return nil, fmt.Errorf("telegram_id %d: %w", user.TelegramID, domain.ErrNotFound)
+	}
+
+	return user, nil
+}
+
+func (u *UserRepository) UpdateUserByTelegramID(user *domain.User) (*domain.User, error) {
+
+	query := `
+        UPDATE users
+        SET username = ?, birthday = ?, active = ?, telegram_id_source = ?, username_source = ?, birthday_source = ?
+        WHERE telegram_id = ?
+    `
+
+	result, err := u.db.Exec(query, user.Username, user.Birthday, user.Active,
+		user.Sources.TelegramID, user.Sources.Username, user.Sources.Birthday, user.TelegramID)
+	if err != nil {
+		if isUniqueConstraintError(err) {
+			return nil, fmt.Errorf("username %s: %w", user.Username, domain.ErrAlreadyExist)
+		}
+		return nil, fmt.Errorf("error updating user: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("error checking rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return nil, fmt.Errorf("telegram_id %d: %w", user.TelegramID, domain.ErrNotFound)
+	}
+
+	return user, nil
+}
+
+func (u *UserRepository) DeactivateUserByTelegramID(user *domain.User) error {
+
+	query := `
+        UPDATE users
+        SET active = FALSE
         WHERE telegram_id = ?
     `
 
+	result, err := u.db.Exec(query, user.TelegramID)
+	if err != nil {
+		return fmt.Errorf("error deactivating user: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error checking rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("telegram_id %d: %w", user.TelegramID, domain.ErrNotFound)
+	}
+
+	return nil
+}
+
+func (u *UserRepository) DeleteUserByTelegramID(user *domain.User) error {
+
+	query := `
+        DELETE FROM users
+        WHERE telegram_id = ?
+    `
+
+	result, err := u.db.Exec(query, user.TelegramID)
+	if err != nil {
+		return fmt.Errorf("error deleting user: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error checking rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("telegram_id %d: %w", user.TelegramID, domain.ErrNotFound)
+	}
+
+	return nil
+}
+
+func (u *UserRepository) GetAllUsers() (*[]domain.User, error) {
+
+	query := fmt.Sprintf(`
+        SELECT %s
+        FROM users
+    `, userColumns(""))
+
+	rows, qErr := u.db.Query(query)
+	if qErr != nil {
+		return nil, fmt.Errorf("error query: %w", qErr)
+	}
+	defer rows.Close()
+
+	var users []domain.User
+	for rows.Next() {
+		user, sErr := scanUser(rows)
+		if sErr != nil {
+			return nil, fmt.Errorf("error scan user : %w", sErr)
+		}
+		users = append(users, *user)
+	}
+
+	if rErr := rows.Err(); rErr != nil {
+		return nil, fmt.Errorf("error rows : %w", rErr)
+	}
+
+	return &users, nil
+}
+
+func (u *UserRepository) GetUserByTelegramID(user *domain.User) (*domain.User, error) {
+
+	query := fmt.Sprintf(`
+        SELECT %s
+        FROM users
+        WHERE telegram_id = ?
+    `, userColumns(""))
+
 	row := u.db.QueryRow(query, user.TelegramID)
 
-	var uUser domain.User
-	err := row.Scan(&uUser.ID, &uUser.Username, &uUser.TelegramID, &uUser.Birthday, &uUser.NotifyBirthday)
+	uUser, err := scanUser(row)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, fmt.Errorf("telegram_id %d: %w", user.TelegramID, domain.ErrNotFound)
@@ -130,21 +332,20 @@ func (u *UserRepository) GetUserByTelegramID(user *domain.User) (*domain.User, e
 		return nil, fmt.Errorf("error get user by telegram_id: %w", err)
 	}
 
-	return &uUser, nil
+	return uUser, nil
 }
 
 func (u *UserRepository) GetUserByUsername(user *domain.User) (*domain.User, error) {
 
-	query := `
-        SELECT id, username, telegram_id, birthday, notify_birthday
+	query := fmt.Sprintf(`
+        SELECT %s
         FROM users
         WHERE username = ?
-    `
+    `, userColumns(""))
 
 	row := u.db.QueryRow(query, user.Username)
 
-	var uUser domain.User
-	err := row.Scan(&uUser.ID, &uUser.Username, &uUser.TelegramID, &uUser.Birthday, &uUser.NotifyBirthday)
+	uUser, err := scanUser(row)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, fmt.Errorf("username %s: %w", user.Username, domain.ErrNotFound)
@@ -152,33 +353,32 @@ func (u *UserRepository) GetUserByUsername(user *domain.User) (*domain.User, err
 		return nil, fmt.Errorf("error get user by username: %w", err)
 	}
 
-	return &uUser, nil
+	return uUser, nil
 }
 
 func (u *UserRepository) GetUsersToSubscribeByTelegramID(user *domain.User) (*[]domain.User, error) {
 
-	query := `
-        SELECT id, username, telegram_id, birthday, notify_birthday
+	query := fmt.Sprintf(`
+        SELECT %s
 		FROM users
-		WHERE telegram_id != ?
+		WHERE telegram_id != ? AND active = TRUE
 		ORDER BY birthday
 		LIMIT ?
-    `
+    `, userColumns(""))
 
 	rows, err := u.db.Query(query, user.TelegramID, 10)
-	defer rows.Close()
 	if err != nil {
 		return nil, fmt.Errorf("error querying users by excluding telegram_id: %w", err)
 	}
+	defer rows.Close()
 
 	var users []domain.User
 	for rows.Next() {
-		var uUser domain.User
-		err := rows.Scan(&uUser.ID, &uUser.Username, &uUser.TelegramID, &uUser.Birthday, &uUser.NotifyBirthday)
+		uUser, err := scanUser(rows)
 		if err != nil {
 			return nil, fmt.Errorf("error scanning user row: %w", err)
 		}
-		users = append(users, uUser)
+		users = append(users, *uUser)
 	}
 
 	// Check for any errors encountered during iteration
@@ -189,19 +389,56 @@ func (u *UserRepository) GetUsersToSubscribeByTelegramID(user *domain.User) (*[]
 	return &users, nil
 }
 
-func (u *UserRepository) GetUsersWithBirthdayToday() (*[]domain.User, error) {
-	now := time.Now()
-	const stdNumMonth = "01"
-	const stdNumDay = "02"
-	today := now.Format(fmt.Sprintf("%s-%s", stdNumMonth, stdNumDay))
+// GetTimezones returns zones of active users, users without zone are in the default zone
+func (u *UserRepository) GetTimezones() ([]string, error) {
 
 	query := `
-        SELECT id, username, telegram_id, birthday, notify_birthday
+        SELECT DISTINCT CASE WHEN timezone = '' THEN ? ELSE timezone END
         FROM users
-		WHERE strftime('%m-%d', birthday) = ?
+        WHERE active = TRUE
     `
 
-	rows, qErr := u.db.Query(query, today)
+	rows, qErr := u.db.Query(query, u.db.Cfg.DefaultTimezone)
+	if qErr != nil {
+		return nil, fmt.Errorf("error query: %w", qErr)
+	}
+	defer rows.Close()
+
+	var timezones []string
+	for rows.Next() {
+		var timezone string
+		if sErr := rows.Scan(&timezone); sErr != nil {
+			return nil, fmt.Errorf("error scan timezone : %w", sErr)
+		}
+		timezones = append(timezones, timezone)
+	}
+
+	if rErr := rows.Err(); rErr != nil {
+		return nil, fmt.Errorf("error rows : %w", rErr)
+	}
+
+	return timezones, nil
+}
+
+// GetUsersWithBirthdayOn returns users of the zone celebrating on the date, Feb 29 birthdays follow the leap day policy
+func (u *UserRepository) GetUsersWithBirthdayOn(date time.Time, timezone string) (*[]domain.User, error) {
+	monthDays := domain.BirthdayMonthDays(date, u.db.Cfg.LeapDayPolicy)
+
+	placeholders := make([]string, len(monthDays))
+	args := []interface{}{u.db.Cfg.DefaultTimezone, timezone}
+	for i, monthDay := range monthDays {
+		placeholders[i] = "?"
+		args = append(args, monthDay)
+	}
+
+	query := fmt.Sprintf(`
+        SELECT %s
+        FROM users
+		WHERE (CASE WHEN timezone = '' THEN ? ELSE timezone END) = ?
+			AND strftime('%%m-%%d', birthday) IN (%s) AND active = TRUE
+    `, userColumns(""), strings.Join(placeholders, ","))
+
+	rows, qErr := u.db.Query(query, args...)
 	if qErr != nil {
 		return nil, fmt.Errorf("error query: %w", qErr)
 	}
@@ -209,11 +446,11 @@ func (u *UserRepository) GetUsersWithBirthdayToday() (*[]domain.User, error) {
 
 	var users []domain.User
 	for rows.Next() {
-		var user domain.User
-		if sErr := rows.Scan(&user.ID, &user.Username, &user.TelegramID, &user.Birthday, &user.NotifyBirthday); sErr != nil {
+		user, sErr := scanUser(rows)
+		if sErr != nil {
 			return nil, fmt.Errorf("error scan user : %w", sErr)
 		}
-		users = append(users, user)
+		users = append(users, *user)
 	}
 
 	if rErr := rows.Err(); rErr != nil {
@@ -223,6 +460,44 @@ func (u *UserRepository) GetUsersWithBirthdayToday() (*[]domain.User, error) {
 	return &users, nil
 }
 
+// GetUsersWithBirthdayIn returns users of the zone celebrating the given number of days after the date
+func (u *UserRepository) GetUsersWithBirthdayIn(date time.Time, days int, timezone string) (*[]domain.User, error) {
+	return u.GetUsersWithBirthdayOn(date.AddDate(0, 0, days), timezone)
+}
+
+// GetSubscriptionsByTelegramID returns active users the user is subscribed to
+func (u *UserRepository) GetSubscriptionsByTelegramID(user *domain.User) (*[]domain.User, error) {
+
+	query := fmt.Sprintf(`
+        SELECT %s
+        FROM users u
+        INNER JOIN subscriptions s ON u.id = s.subscribe_to
+        INNER JOIN users subscriber ON subscriber.id = s.subscriber
+        WHERE subscriber.telegram_id = ? AND u.active = TRUE
+    `, userColumns("u."))
+
+	rows, qErr := u.db.Query(query, user.TelegramID)
+	if qErr != nil {
+		return nil, fmt.Errorf("error executing query: %w", qErr)
+	}
+	defer rows.Close()
+
+	var users []domain.User
+	for rows.Next() {
+		sUser, sErr := scanUser(rows)
+		if sErr != nil {
+			return nil, fmt.Errorf("error scanning user: %w", sErr)
+		}
+		users = append(users, *sUser)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error rows: %w", err)
+	}
+
+	return &users, nil
+}
+
 func (u *UserRepository) GetUsersSubscribedToUsers(birthdayUsers *[]domain.User) (*[]domain.User, error) {
 	var placeholders []string
 	for range *birthdayUsers {
@@ -231,11 +506,11 @@ func (u *UserRepository) GetUsersSubscribedToUsers(birthdayUsers *[]domain.User)
 	placeholderStr := strings.Join(placeholders, ",")
 
 	query := fmt.Sprintf(`
-        SELECT DISTINCT u.id, u.username, u.telegram_id, u.birthday, u.notify_birthday
+        SELECT DISTINCT %s
         FROM users u
         INNER JOIN subscriptions s ON u.id = s.subscriber
-        WHERE s.subscribe_to IN (%s)
-    `, placeholderStr)
+        WHERE s.subscribe_to IN (%s) AND u.active = TRUE
+    `, userColumns("u."), placeholderStr)
 
 	args := make([]interface{}, len(*birthdayUsers))
 	for i, user := range *birthdayUsers {
@@ -250,11 +525,11 @@ func (u *UserRepository) GetUsersSubscribedToUsers(birthdayUsers *[]domain.User)
 
 	var users []domain.User
 	for rows.Next() {
-		var user domain.User
-		if sErr := rows.Scan(&user.ID, &user.Username, &user.TelegramID, &user.Birthday, &user.NotifyBirthday); sErr != nil {
+		user, sErr := scanUser(rows)
+		if sErr != nil {
 			return nil, fmt.Errorf("error scanning user: %w", sErr)
 		}
-		users = append(users, user)
+		users = append(users, *user)
 	}
 
 	if err := rows.Err(); err != nil {
@@ -0,0 +1,190 @@
+package repository
+
+import (
+	"birthdayapp/internal/adapters/database"
+	"birthdayapp/internal/core/domain"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type WishlistRepository struct {
+	db *database.DB
+}
+
+func NewWishlistRepository(db *database.DB) *WishlistRepository {
+	return &WishlistRepository{
+		db,
+	}
+}
+
+func (wr *WishlistRepository) AddItem(item *domain.WishlistItem) (*domain.WishlistItem, error) {
+	query := `
+        INSERT INTO wishlist_items (user_id, title)
+        SELECT id, ?
+        FROM users
+        WHERE telegram_id = ?
+        RETURNING id
+    `
+
+	err := wr.db.QueryRow(query, item.Title, item.TelegramID).Scan(&item.ID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("telegram_id %d: %w", item.TelegramID, domain.ErrNotFound)
+		}
+		return nil, fmt.Errorf("error adding wishlist item: %w", err)
+	}
+	return item, nil
+}
+
+// DeleteItem removes the item from the wishlist of the user
+func (wr *WishlistRepository) DeleteItem(id int, telegramID int64) error {
+	query := `
+        DELETE FROM wishlist_items
+        WHERE id = ? AND user_id = (SELECT id FROM users WHERE telegram_id = ?)
+    `
+
+	return wr.updateItem(query, id, id, telegramID)
+}
+
+func (wr *WishlistRepository) GetItem(id int) (*domain.WishlistItem, error) {
+	items, err := wr.getItems(`
+        WHERE w.id = ?
+    `, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(*items) == 0 {
+		return nil, fmt.Errorf("wishlist item %d: %w", id, domain.ErrNotFound)
+	}
+	return &(*items)[0], nil
+}
+
+// GetItems returns wishlists of the users ordered by owner and the time items were added
+func (wr *WishlistRepository) GetItems(telegramIDs []int64) (*[]domain.WishlistItem, error) {
+	if len(telegramIDs) == 0 {
+		return &[]domain.WishlistItem{}, nil
+	}
+
+	placeholders := make([]string, len(telegramIDs))
+	args := make([]interface{}, len(telegramIDs))
+	for i, telegramID := range telegramIDs {
+		placeholders[i] = "?"
+		args[i] = telegramID
+	}
+
+	return wr.getItems(fmt.Sprintf(`
+        WHERE owner.telegram_id IN (%s)
+        ORDER BY owner.telegram_id, w.id
+    `, strings.Join(placeholders, ",")), args...)
+}
+
+func (wr *WishlistRepository) getItems(condition string, args ...any) (*[]domain.WishlistItem, error) {
+	query := `
+        SELECT w.id, owner.telegram_id, w.title, COALESCE(claimer.telegram_id, 0)
+        FROM wishlist_items w
+        INNER JOIN users owner ON owner.id = w.user_id
+        LEFT JOIN users claimer ON claimer.id = w.claimed_by
+    ` + condition
+
+	rows, err := wr.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying wishlist items: %w", err)
+	}
+	defer rows.Close()
+
+	var items []domain.WishlistItem
+	for rows.Next() {
+		var item domain.WishlistItem
+		if err := rows.Scan(&item.ID, &item.TelegramID, &item.Title, &item.ClaimedBy); err != nil {
+			return nil, fmt.Errorf("error scanning wishlist item: %w", err)
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating wishlist items: %w", err)
+	}
+
+	return &items, nil
+}
+
+// ClaimItem marks the item bought by the user unless someone has claimed it already
+func (wr *WishlistRepository) ClaimItem(id int, telegramID int64) error {
+	query := `
+        UPDATE wishlist_items
+        SET claimed_by = (SELECT id FROM users WHERE telegram_id = ?)
+        WHERE id = ? AND claimed_by IS NULL
+    `
+
+	result, err := wr.db.Exec(query, telegramID, id)
+	if err != nil {
+		return fmt.Errorf("error claiming wishlist item %d: %w", id, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error checking rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("claim of wishlist item %d: %w", id, domain.ErrAlreadyExist)
+	}
+	return nil
+}
+
+// UnclaimItem releases the claim of the user on the item
+func (wr *WishlistRepository) UnclaimItem(id int, telegramID int64) error {
+	query := `
+        UPDATE wishlist_items
+        SET claimed_by = NULL
+        WHERE id = ? AND claimed_by = (SELECT id FROM users WHERE telegram_id = ?)
+    `
+
+	return wr.updateItem(query, id, id, telegramID)
+}
+
+// DeleteClaimedItems removes items of the users someone has claimed
+func (wr *WishlistRepository) DeleteClaimedItems(telegramIDs []int64) error {
+	if len(telegramIDs) == 0 {
+		return nil
+	}
+
+	placeholders := make([]string, len(telegramIDs))
+	args := make([]interface{}, len(telegramIDs))
+	for i, telegramID := range telegramIDs {
+		placeholders[i] = "?"
+		args[i] = telegramID
+	}
+
+	query := fmt.Sprintf(`
+        DELETE FROM wishlist_items
+        WHERE claimed_by IS NOT NULL AND user_id IN (
+            SELECT id
+            FROM users
+            WHERE telegram_id IN (%s)
+        )
+    `, strings.Join(placeholders, ","))
+
+	if _, err := wr.db.Exec(query, args...); err != nil {
+		return fmt.Errorf("error deleting claimed wishlist items: %w", err)
+	}
+	return nil
+}
+
+func (wr *WishlistRepository) updateItem(query string, id int, args ...any) error {
+	result, err := wr.db.Exec(query, args...)
+	if err != nil {
+		return fmt.Errorf("error updating wishlist item %d: %w", id, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error checking rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("wishlist item %d: %w", id, domain.ErrNotFound)
+	}
+	return nil
+}
@@ -0,0 +1,223 @@
+package adapters
+
+import (
+	"birthdayapp/internal/config"
+	"birthdayapp/internal/core/domain"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FileExternalAPI loads users from a CSV, YAML or JSON file
+type FileExternalAPI struct {
+	cfg config.FileAPI
+}
+
+// fileRecord is a raw user record with the row number used in validation errors
+type fileRecord struct {
+	row        int
+	username   string
+	telegramID string
+	birthday   string
+}
+
+func NewFileExternalAPI(cfg config.FileAPI) (*FileExternalAPI, error) {
+	switch strings.ToLower(filepath.Ext(cfg.Path)) {
+	case ".csv", ".yaml", ".yml", ".json":
+	default:
+		return nil, fmt.Errorf("unsupported users file %q, expected .csv, .yaml, .yml or .json", cfg.Path)
+	}
+	if len(cfg.DateFormats) == 0 {
+		cfg.DateFormats = []string{birthdayLayout, "02.01.2006", "02/01/2006", "2006/01/02"}
+	}
+	return &FileExternalAPI{
+		cfg: cfg,
+	}, nil
+}
+
+// GetUsers returns valid users and domain.RowErrors for rejected rows
+func (extAPI *FileExternalAPI) GetUsers() (*[]domain.User, error) {
+
+	content, rfErr := os.ReadFile(extAPI.cfg.Path)
+	if rfErr != nil {
+		return nil, fmt.Errorf("error read users file: %w", rfErr)
+	}
+
+	var records []fileRecord
+	var prErr error
+	switch strings.ToLower(filepath.Ext(extAPI.cfg.Path)) {
+	case ".csv":
+		records, prErr = parseCSVRecords(content)
+	case ".yaml", ".yml":
+		records, prErr = parseYAMLRecords(content)
+	case ".json":
+		records, prErr = parseJSONRecords(content)
+	}
+	if prErr != nil {
+		return nil, fmt.Errorf("error parse users file %s: %w", extAPI.cfg.Path, prErr)
+	}
+
+	users := make([]domain.User, 0, len(records))
+	var rowErrs domain.RowErrors
+	telegramIDs := make(map[int64]int)
+	usernames := make(map[string]int)
+
+	for _, record := range records {
+		user, vErr := extAPI.toDomain(record)
+		if vErr != nil {
+			rowErrs = append(rowErrs, domain.RowError{Row: record.row, Err: vErr})
+			continue
+		}
+		if firstRow, ok := telegramIDs[user.TelegramID]; ok {
+			rowErrs = append(rowErrs, domain.RowError{Row: record.row, Err: fmt.Errorf("duplicate telegram_id %d, first seen in row %d: %w", user.TelegramID, firstRow, domain.ErrValidation)})
+			continue
+		}
+		if firstRow, ok := usernames[strings.ToLower(user.Username)]; ok {
+			rowErrs = append(rowErrs, domain.RowError{Row: record.row, Err: fmt.Errorf("duplicate username %s, first seen in row %d: %w", user.Username, firstRow, domain.ErrValidation)})
+			continue
+		}
+		telegramIDs[user.TelegramID] = record.row
+		usernames[strings.ToLower(user.Username)] = record.row
+		users = append(users, *user)
+	}
+
+	if len(rowErrs) != 0 {
+		return &users, rowErrs
+	}
+	return &users, nil
+}
+
+func (extAPI *FileExternalAPI) toDomain(record fileRecord) (*domain.User, error) {
+	username := strings.TrimPrefix(strings.TrimSpace(record.username), "@")
+	if username == "" {
+		return nil, fmt.Errorf("username is empty: %w", domain.ErrValidation)
+	}
+	telegramID, pErr := strconv.ParseInt(strings.TrimSpace(record.telegramID), 10, 64)
+	if pErr != nil || telegramID <= 0 {
+		return nil, fmt.Errorf("user %s: telegram_id %q must be a positive number: %w", username, record.telegramID, domain.ErrValidation)
+	}
+	birthday, pdErr := extAPI.parseDate(strings.TrimSpace(record.birthday))
+	if pdErr != nil {
+		return nil, fmt.Errorf("user %s: %w", username, pdErr)
+	}
+	return &domain.User{
+		Username:   username,
+		TelegramID: telegramID,
+		Birthday:   birthday,
+	}, nil
+}
+
+func (extAPI *FileExternalAPI) parseDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, fmt.Errorf("birthday is empty: %w", domain.ErrValidation)
+	}
+	for _, layout := range extAPI.cfg.DateFormats {
+		date, pErr := time.Parse(layout, value)
+		if pErr != nil {
+			continue
+		}
+		if date.After(time.Now()) {
+			return time.Time{}, fmt.Errorf("birthday %q is in the future: %w", value, domain.ErrValidation)
+		}
+		return date, nil
+	}
+	return time.Time{}, fmt.Errorf("birthday %q doesn't match formats %s: %w", value, strings.Join(extAPI.cfg.DateFormats, ", "), domain.ErrValidation)
+}
+
+// parseCSVRecords expects a header row with username, telegram_id and birthday columns in any order
+func parseCSVRecords(content []byte) ([]fileRecord, error) {
+	reader := csv.NewReader(bytes.NewReader(content))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, hErr := reader.Read()
+	if hErr != nil {
+		return nil, fmt.Errorf("error read header: %w", hErr)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	for _, name := range []string{"username", "telegram_id", "birthday"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("header doesn't contain column %s", name)
+		}
+	}
+
+	column := func(line []string, name string) string {
+		if i := columns[name]; i < len(line) {
+			return line[i]
+		}
+		return ""
+	}
+
+	var records []fileRecord
+	for row := 2; ; row++ {
+		line, rErr := reader.Read()
+		if errors.Is(rErr, io.EOF) {
+			return records, nil
+		}
+		if rErr != nil {
+			return nil, rErr
+		}
+		records = append(records, fileRecord{
+			row:        row,
+			username:   column(line, "username"),
+			telegramID: column(line, "telegram_id"),
+			birthday:   column(line, "birthday"),
+		})
+	}
+}
+
+func parseYAMLRecords(content []byte) ([]fileRecord, error) {
+	var raw []map[string]interface{}
+	if uErr := yaml.Unmarshal(content, &raw); uErr != nil {
+		return nil, uErr
+	}
+	return mapRecords(raw), nil
+}
+
+func parseJSONRecords(content []byte) ([]fileRecord, error) {
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+
+	var raw []map[string]interface{}
+	if dErr := decoder.Decode(&raw); dErr != nil {
+		return nil, dErr
+	}
+	return mapRecords(raw), nil
+}
+
+func mapRecords(raw []map[string]interface{}) []fileRecord {
+	records := make([]fileRecord, len(raw))
+	for i, item := range raw {
+		records[i] = fileRecord{
+			row:        i + 1,
+			username:   stringValue(item["username"]),
+			telegramID: stringValue(item["telegram_id"]),
+			birthday:   stringValue(item["birthday"]),
+		}
+	}
+	return records
+}
+
+func stringValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case time.Time:
+		//yaml decodes unquoted dates as timestamps
+		return v.Format(birthdayLayout)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,101 @@
+package adapters
+
+import (
+	"birthdayapp/internal/config"
+	"birthdayapp/internal/core/domain"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestFileAPI(t *testing.T, name string, content string) *FileExternalAPI {
+	path := filepath.Join(t.TempDir(), name)
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	extAPI, err := NewFileExternalAPI(config.FileAPI{
+		Path:        path,
+		DateFormats: []string{"2006-01-02", "02.01.2006", "02/01/2006"},
+	})
+	assert.NoError(t, err)
+	return extAPI
+}
+
+func TestFileExternalAPI_GetUsers_CSV(t *testing.T) {
+	extAPI := newTestFileAPI(t, "users.csv", `birthday,username,telegram_id
+2000-06-21,user1,111
+21.07.2001,@user2,222
+01/02/1999,user3,333
+`)
+
+	users, err := extAPI.GetUsers()
+	assert.NoError(t, err)
+	assert.Len(t, *users, 3)
+	assert.Equal(t, "user2", (*users)[1].Username)
+	assert.Equal(t, time.Date(2001, 7, 21, 0, 0, 0, 0, time.UTC), (*users)[1].Birthday)
+	assert.Equal(t, time.Date(1999, 2, 1, 0, 0, 0, 0, time.UTC), (*users)[2].Birthday)
+}
+
+func TestFileExternalAPI_GetUsers_RowErrors(t *testing.T) {
+	extAPI := newTestFileAPI(t, "users.csv", `username,telegram_id,birthday
+user1,111,2000-06-21
+user2,222,2001-13-40
+,333,2000-01-01
+user4,111,2000-01-01
+user5,555,2000-01-01
+`)
+
+	users, err := extAPI.GetUsers()
+	assert.Len(t, *users, 2)
+	assert.ErrorIs(t, err, domain.ErrValidation)
+
+	var rowErrs domain.RowErrors
+	assert.True(t, errors.As(err, &rowErrs))
+	assert.Len(t, rowErrs, 3)
+	assert.Equal(t, 3, rowErrs[0].Row)
+	assert.Contains(t, rowErrs[0].Error(), "doesn't match formats")
+	assert.Equal(t, 4, rowErrs[1].Row)
+	assert.Contains(t, rowErrs[1].Error(), "username is empty")
+	assert.Equal(t, 5, rowErrs[2].Row)
+	assert.Contains(t, rowErrs[2].Error(), "duplicate telegram_id 111, first seen in row 2")
+}
+
+func TestFileExternalAPI_GetUsers_YAML(t *testing.T) {
+	extAPI := newTestFileAPI(t, "users.yaml", `
+- username: user1
+  telegram_id: 111
+  birthday: 2000-06-21
+- username: user2
+  telegram_id: 222
+  birthday: "21.07.2001"
+`)
+
+	users, err := extAPI.GetUsers()
+	assert.NoError(t, err)
+	assert.Len(t, *users, 2)
+	assert.Equal(t, time.Date(2000, 6, 21, 0, 0, 0, 0, time.UTC), (*users)[0].Birthday)
+	assert.Equal(t, int64(222), (*users)[1].TelegramID)
+}
+
+func TestFileExternalAPI_GetUsers_JSON(t *testing.T) {
+	extAPI := newTestFileAPI(t, "users.json", `[
+		{"username": "user1", "telegram_id": 111, "birthday": "2000-06-21"},
+		{"username": "user2", "telegram_id": "222", "birthday": "2001-07-21"},
+		{"username": "user3", "birthday": "2001-07-21"}
+	]`)
+
+	users, err := extAPI.GetUsers()
+	assert.Len(t, *users, 2)
+
+	var rowErrs domain.RowErrors
+	assert.True(t, errors.As(err, &rowErrs))
+	assert.Len(t, rowErrs, 1)
+	assert.Equal(t, 3, rowErrs[0].Row)
+}
+
+func TestNewFileExternalAPI_UnsupportedFormat(t *testing.T) {
+	_, err := NewFileExternalAPI(config.FileAPI{Path: "users.xlsx", DateFormats: []string{"2006-01-02"}})
+	assert.Error(t, err)
+}
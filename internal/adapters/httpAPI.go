@@ -0,0 +1,160 @@
+package adapters
+
+import (
+	"birthdayapp/internal/config"
+	"birthdayapp/internal/core/domain"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const birthdayLayout = "2006-01-02"
+
+// HTTPExternalAPI loads users from the HR employee-directory endpoint
+type HTTPExternalAPI struct {
+	cfg    config.HTTPAPI
+	token  string
+	client *http.Client
+}
+
+type directoryPage struct {
+	Users    []directoryUser `json:"users"`
+	NextPage *int            `json:"next_page"`
+}
+
+type directoryUser struct {
+	Username   string `json:"username"`
+	TelegramID int64  `json:"telegram_id"`
+	Birthday   string `json:"birthday"`
+}
+
+func NewHTTPExternalAPI(cfg config.HTTPAPI, token string) (*HTTPExternalAPI, error) {
+	if _, pErr := url.ParseRequestURI(cfg.URL); pErr != nil {
+		return nil, fmt.Errorf("error parse external api url: %w", pErr)
+	}
+
+	//defaults are not applied to sources listed in external_apis
+	if cfg.AuthHeader == "" {
+		cfg.AuthHeader = "Authorization"
+		if cfg.AuthScheme == "" {
+			cfg.AuthScheme = "Bearer"
+		}
+	}
+	if cfg.PageSize <= 0 {
+		cfg.PageSize = 100
+	}
+	if cfg.MaxPages <= 0 {
+		cfg.MaxPages = 1000
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	return &HTTPExternalAPI{
+		cfg:    cfg,
+		token:  token,
+		client: &http.Client{Timeout: cfg.Timeout},
+	}, nil
+}
+
+func (extAPI *HTTPExternalAPI) GetUsers() (*[]domain.User, error) {
+
+	var users []domain.User
+	page := 1
+
+	for i := 0; i < extAPI.cfg.MaxPages; i++ {
+		dPage, gpErr := extAPI.getPage(page)
+		if gpErr != nil {
+			return nil, gpErr
+		}
+
+		var vErrs []error
+		for j, dUser := range dPage.Users {
+			user, vErr := dUser.toDomain()
+			if vErr != nil {
+				vErrs = append(vErrs, fmt.Errorf("page %d record %d: %w", page, j, vErr))
+				continue
+			}
+			users = append(users, *user)
+		}
+		if len(vErrs) != 0 {
+			return nil, errors.Join(vErrs...)
+		}
+
+		if dPage.NextPage == nil || *dPage.NextPage == 0 || len(dPage.Users) == 0 {
+			return &users, nil
+		}
+		if *dPage.NextPage <= page {
+			return nil, fmt.Errorf("page %d points to next page %d: %w", page, *dPage.NextPage, domain.ErrValidation)
+		}
+		page = *dPage.NextPage
+	}
+
+	return nil, fmt.Errorf("external api returned more than %d pages", extAPI.cfg.MaxPages)
+}
+
+func (extAPI *HTTPExternalAPI) getPage(page int) (*directoryPage, error) {
+
+	reqURL, pErr := url.Parse(extAPI.cfg.URL)
+	if pErr != nil {
+		return nil, fmt.Errorf("error parse external api url: %w", pErr)
+	}
+	query := reqURL.Query()
+	query.Set("page", strconv.Itoa(page))
+	query.Set("page_size", strconv.Itoa(extAPI.cfg.PageSize))
+	reqURL.RawQuery = query.Encode()
+
+	req, nrErr := http.NewRequest(http.MethodGet, reqURL.String(), nil)
+	if nrErr != nil {
+		return nil, fmt.Errorf("error create request: %w", nrErr)
+	}
+	req.Header.Set("Accept", "application/json")
+	if extAPI.token != "" {
+		req.Header.Set(extAPI.cfg.AuthHeader, strings.TrimSpace(fmt.Sprintf("%s %s", extAPI.cfg.AuthScheme, extAPI.token)))
+	}
+
+	resp, dErr := extAPI.client.Do(req)
+	if dErr != nil {
+		return nil, fmt.Errorf("error request page %d: %w", page, dErr)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("error request page %d: status %d: %s", page, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var dPage directoryPage
+	decoder := json.NewDecoder(resp.Body)
+	decoder.DisallowUnknownFields()
+	if jErr := decoder.Decode(&dPage); jErr != nil {
+		return nil, fmt.Errorf("error decode page %d: %w: %v", page, domain.ErrValidation, jErr)
+	}
+	return &dPage, nil
+}
+
+func (du directoryUser) toDomain() (*domain.User, error) {
+	if strings.TrimSpace(du.Username) == "" {
+		return nil, fmt.Errorf("username is empty: %w", domain.ErrValidation)
+	}
+	if du.TelegramID <= 0 {
+		return nil, fmt.Errorf("user %s: telegram_id must be positive: %w", du.Username, domain.ErrValidation)
+	}
+	birthday, pErr := time.Parse(birthdayLayout, du.Birthday)
+	if pErr != nil {
+		return nil, fmt.Errorf("user %s: birthday %q must be YYYY-MM-DD: %w", du.Username, du.Birthday, domain.ErrValidation)
+	}
+	if birthday.After(time.Now()) {
+		return nil, fmt.Errorf("user %s: birthday %q is in the future: %w", du.Username, du.Birthday, domain.ErrValidation)
+	}
+	return &domain.User{
+		Username:   strings.TrimPrefix(strings.TrimSpace(du.Username), "@"),
+		TelegramID: du.TelegramID,
+		Birthday:   birthday,
+	}, nil
+}
@@ -0,0 +1,97 @@
+package adapters
+
+import (
+	"birthdayapp/internal/config"
+	"birthdayapp/internal/core/domain"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newTestHTTPAPI(t *testing.T, handler http.HandlerFunc) *HTTPExternalAPI {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	extAPI, err := NewHTTPExternalAPI(config.HTTPAPI{
+		URL:        server.URL + "/employees",
+		AuthHeader: "Authorization",
+		AuthScheme: "Bearer",
+		PageSize:   2,
+		MaxPages:   10,
+		Timeout:    time.Second,
+	}, "secret")
+	assert.NoError(t, err)
+	return extAPI
+}
+
+func TestHTTPExternalAPI_GetUsers_Pagination(t *testing.T) {
+	extAPI := newTestHTTPAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		assert.Equal(t, "2", r.URL.Query().Get("page_size"))
+
+		switch r.URL.Query().Get("page") {
+		case "1":
+			fmt.Fprint(w, `{"users":[
+				{"username":"user1","telegram_id":111,"birthday":"2000-06-21"},
+				{"username":"@user2","telegram_id":222,"birthday":"2001-07-21"}],
+				"next_page":2}`)
+		case "2":
+			fmt.Fprint(w, `{"users":[{"username":"user3","telegram_id":333,"birthday":"1999-02-28"}],"next_page":null}`)
+		default:
+			t.Fatalf("unexpected page %s", r.URL.Query().Get("page"))
+		}
+	})
+
+	users, err := extAPI.GetUsers()
+	assert.NoError(t, err)
+	assert.Len(t, *users, 3)
+	assert.Equal(t, "user2", (*users)[1].Username)
+	assert.Equal(t, int64(333), (*users)[2].TelegramID)
+	assert.Equal(t, time.Date(1999, 2, 28, 0, 0, 0, 0, time.UTC), (*users)[2].Birthday)
+}
+
+func TestHTTPExternalAPI_GetUsers_InvalidSchema(t *testing.T) {
+	extAPI := newTestHTTPAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"users":[
+			{"username":"","telegram_id":111,"birthday":"2000-06-21"},
+			{"username":"user2","telegram_id":222,"birthday":"21.07.2001"}]}`)
+	})
+
+	users, err := extAPI.GetUsers()
+	assert.Nil(t, users)
+	assert.ErrorIs(t, err, domain.ErrValidation)
+	assert.Contains(t, err.Error(), "username is empty")
+	assert.Contains(t, err.Error(), "must be YYYY-MM-DD")
+}
+
+func TestHTTPExternalAPI_GetUsers_UnknownField(t *testing.T) {
+	extAPI := newTestHTTPAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"employees":[]}`)
+	})
+
+	_, err := extAPI.GetUsers()
+	assert.ErrorIs(t, err, domain.ErrValidation)
+}
+
+func TestHTTPExternalAPI_GetUsers_StatusError(t *testing.T) {
+	extAPI := newTestHTTPAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	})
+
+	_, err := extAPI.GetUsers()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "status 401")
+}
+
+func TestHTTPExternalAPI_GetUsers_Timeout(t *testing.T) {
+	extAPI := newTestHTTPAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	})
+	extAPI.client.Timeout = 50 * time.Millisecond
+
+	_, err := extAPI.GetUsers()
+	assert.Error(t, err)
+}
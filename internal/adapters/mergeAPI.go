@@ -0,0 +1,190 @@
+package adapters
+
+import (
+	"birthdayapp/internal/core/domain"
+	"birthdayapp/internal/core/port"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+)
+
+// NamedExternalAPI is a source of users with the name stored in domain.UserSources
+type NamedExternalAPI struct {
+	Name string
+	API  port.ExternalAPI
+}
+
+// MergedExternalAPI merges users from several sources by telegram_id or username,
+// every field is taken from the most trusted source that has it
+type MergedExternalAPI struct {
+	log     *slog.Logger
+	sources []NamedExternalAPI
+}
+
+func NewMergedExternalAPI(log *slog.Logger, sources []NamedExternalAPI, precedence []string) (*MergedExternalAPI, error) {
+	if len(sources) == 0 {
+		return nil, errors.New("no external api sources configured")
+	}
+
+	rank := make(map[string]int, len(sources))
+	for _, source := range sources {
+		if source.Name == "" {
+			return nil, errors.New("external api source name is empty")
+		}
+		if _, ok := rank[source.Name]; ok {
+			return nil, fmt.Errorf("duplicate external api source name %q", source.Name)
+		}
+		rank[source.Name] = len(precedence) + len(rank)
+	}
+	for i, name := range precedence {
+		if _, ok := rank[name]; ok {
+			rank[name] = i
+		}
+	}
+
+	ordered := append([]NamedExternalAPI(nil), sources...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return rank[ordered[i].Name] < rank[ordered[j].Name]
+	})
+
+	return &MergedExternalAPI{
+		log:     log,
+		sources: ordered,
+	}, nil
+}
+
+// GetUsers fails if any source fails, rejected rows of all sources are returned as domain.RowErrors
+func (extAPI *MergedExternalAPI) GetUsers() (*[]domain.User, error) {
+	op := "MergedExternalAPI.GetUsers"
+	log := extAPI.log.With(slog.String("op", op))
+
+	var records []domain.User
+	var rowErrs domain.RowErrors
+
+	for _, source := range extAPI.sources {
+		users, guErr := source.API.GetUsers()
+		var sourceRowErrs domain.RowErrors
+		if guErr != nil && !errors.As(guErr, &sourceRowErrs) {
+			return nil, fmt.Errorf("source %s: %w", source.Name, guErr)
+		}
+		for _, rowErr := range sourceRowErrs {
+			rowErrs = append(rowErrs, domain.RowError{Row: rowErr.Row, Err: fmt.Errorf("source %s: %w", source.Name, rowErr.Err)})
+		}
+		for _, user := range *users {
+			user.Sources = domain.UserSources{TelegramID: source.Name, Username: source.Name, Birthday: source.Name}
+			records = append(records, user)
+		}
+	}
+
+	users := make([]domain.User, 0, len(records))
+	for _, group := range groupRecords(records) {
+		user, conflicts := mergeRecords(group)
+		for _, conflict := range conflicts {
+			log.Warn("user sources conflict", "telegram_id", user.TelegramID, "username", user.Username, "conflict", conflict)
+		}
+		users = append(users, user)
+	}
+
+	if len(rowErrs) != 0 {
+		return &users, rowErrs
+	}
+	return &users, nil
+}
+
+// groupRecords links records sharing telegram_id or username, records keep the sources precedence order
+func groupRecords(records []domain.User) [][]domain.User {
+	parent := make([]int, len(records))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	union := func(i, j int) {
+		ri, rj := find(i), find(j)
+		if ri < rj {
+			parent[rj] = ri
+		} else if rj < ri {
+			parent[ri] = rj
+		}
+	}
+
+	byTelegramID := make(map[int64]int)
+	byUsername := make(map[string]int)
+	for i, record := range records {
+		if record.TelegramID != 0 {
+			if j, ok := byTelegramID[record.TelegramID]; ok {
+				union(i, j)
+			} else {
+				byTelegramID[record.TelegramID] = i
+			}
+		}
+		if username := strings.ToLower(record.Username); username != "" {
+			if j, ok := byUsername[username]; ok {
+				union(i, j)
+			} else {
+				byUsername[username] = i
+			}
+		}
+	}
+
+	var groups [][]domain.User
+	index := make(map[int]int)
+	for i, record := range records {
+		root := find(i)
+		g, ok := index[root]
+		if !ok {
+			g = len(groups)
+			index[root] = g
+			groups = append(groups, nil)
+		}
+		groups[g] = append(groups[g], record)
+	}
+	return groups
+}
+
+// mergeRecords takes every field from the first record that has it and reports differing values
+func mergeRecords(group []domain.User) (domain.User, []string) {
+	var merged domain.User
+	var conflicts []string
+
+	for _, record := range group {
+		switch {
+		case record.TelegramID == 0:
+		case merged.TelegramID == 0:
+			merged.TelegramID = record.TelegramID
+			merged.Sources.TelegramID = record.Sources.TelegramID
+		case merged.TelegramID != record.TelegramID:
+			conflicts = append(conflicts, fmt.Sprintf("telegram_id %d from %s ignored, %d from %s used",
+				record.TelegramID, record.Sources.TelegramID, merged.TelegramID, merged.Sources.TelegramID))
+		}
+
+		switch {
+		case record.Username == "":
+		case merged.Username == "":
+			merged.Username = record.Username
+			merged.Sources.Username = record.Sources.Username
+		case merged.Username != record.Username:
+			conflicts = append(conflicts, fmt.Sprintf("username %s from %s ignored, %s from %s used",
+				record.Username, record.Sources.Username, merged.Username, merged.Sources.Username))
+		}
+
+		switch {
+		case record.Birthday.IsZero():
+		case merged.Birthday.IsZero():
+			merged.Birthday = record.Birthday
+			merged.Sources.Birthday = record.Sources.Birthday
+		case !merged.Birthday.Equal(record.Birthday):
+			conflicts = append(conflicts, fmt.Sprintf("birthday %s from %s ignored, %s from %s used",
+				record.Birthday.Format(birthdayLayout), record.Sources.Birthday, merged.Birthday.Format(birthdayLayout), merged.Sources.Birthday))
+		}
+	}
+
+	return merged, conflicts
+}
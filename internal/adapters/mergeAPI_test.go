@@ -0,0 +1,92 @@
+package adapters
+
+import (
+	"birthdayapp/internal/core/domain"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+type stubExternalAPI struct {
+	users []domain.User
+	err   error
+}
+
+func (s *stubExternalAPI) GetUsers() (*[]domain.User, error) {
+	return &s.users, s.err
+}
+
+func TestMergedExternalAPI_GetUsers_Precedence(t *testing.T) {
+	hrBirthday := time.Date(2000, 6, 21, 0, 0, 0, 0, time.UTC)
+	csvBirthday := time.Date(2000, 6, 22, 0, 0, 0, 0, time.UTC)
+
+	hr := &stubExternalAPI{users: []domain.User{
+		{Username: "user1", TelegramID: 111, Birthday: hrBirthday},
+		{Username: "user2", TelegramID: 222, Birthday: hrBirthday},
+	}}
+	csv := &stubExternalAPI{users: []domain.User{
+		{Username: "user1_csv", TelegramID: 111, Birthday: csvBirthday},
+		{Username: "user2", TelegramID: 999, Birthday: hrBirthday},
+		{Username: "contractor", TelegramID: 333, Birthday: csvBirthday},
+	}}
+
+	extAPI, err := NewMergedExternalAPI(slog.New(slog.NewTextHandler(io.Discard, nil)),
+		[]NamedExternalAPI{{Name: "csv", API: csv}, {Name: "hr", API: hr}}, []string{"hr", "csv"})
+	assert.NoError(t, err)
+
+	users, err := extAPI.GetUsers()
+	assert.NoError(t, err)
+	assert.Len(t, *users, 3)
+
+	//matched by telegram_id, everything from hr
+	assert.Equal(t, domain.User{Username: "user1", TelegramID: 111, Birthday: hrBirthday,
+		Sources: domain.UserSources{TelegramID: "hr", Username: "hr", Birthday: "hr"}}, (*users)[0])
+	//matched by username, telegram_id from hr
+	assert.Equal(t, int64(222), (*users)[1].TelegramID)
+	assert.Equal(t, "hr", (*users)[1].Sources.TelegramID)
+	//only in csv
+	assert.Equal(t, domain.User{Username: "contractor", TelegramID: 333, Birthday: csvBirthday,
+		Sources: domain.UserSources{TelegramID: "csv", Username: "csv", Birthday: "csv"}}, (*users)[2])
+}
+
+func TestMergedExternalAPI_GetUsers_RowErrors(t *testing.T) {
+	hr := &stubExternalAPI{users: []domain.User{{Username: "user1", TelegramID: 111}}}
+	csv := &stubExternalAPI{
+		users: []domain.User{{Username: "user2", TelegramID: 222}},
+		err:   domain.RowErrors{{Row: 3, Err: domain.ErrValidation}},
+	}
+
+	extAPI, err := NewMergedExternalAPI(slog.New(slog.NewTextHandler(io.Discard, nil)),
+		[]NamedExternalAPI{{Name: "hr", API: hr}, {Name: "csv", API: csv}}, nil)
+	assert.NoError(t, err)
+
+	users, err := extAPI.GetUsers()
+	assert.Len(t, *users, 2)
+
+	var rowErrs domain.RowErrors
+	assert.True(t, errors.As(err, &rowErrs))
+	assert.Equal(t, 3, rowErrs[0].Row)
+	assert.Contains(t, rowErrs[0].Error(), "source csv")
+}
+
+func TestMergedExternalAPI_GetUsers_SourceDown(t *testing.T) {
+	hr := &stubExternalAPI{err: errors.New("test")}
+	csv := &stubExternalAPI{users: []domain.User{{Username: "user2", TelegramID: 222}}}
+
+	extAPI, err := NewMergedExternalAPI(slog.New(slog.NewTextHandler(io.Discard, nil)),
+		[]NamedExternalAPI{{Name: "hr", API: hr}, {Name: "csv", API: csv}}, nil)
+	assert.NoError(t, err)
+
+	users, err := extAPI.GetUsers()
+	assert.Nil(t, users)
+	assert.ErrorContains(t, err, "source hr")
+}
+
+func TestNewMergedExternalAPI_DuplicateName(t *testing.T) {
+	_, err := NewMergedExternalAPI(slog.New(slog.NewTextHandler(io.Discard, nil)),
+		[]NamedExternalAPI{{Name: "hr", API: &stubExternalAPI{}}, {Name: "hr", API: &stubExternalAPI{}}}, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"birthdayapp/internal/core/domain"
+	"birthdayapp/internal/core/port"
+	"errors"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"log/slog"
+	"slices"
+	"strings"
+)
+
+type CardHandler struct {
+	cs port.Card
+	us port.UserService
+}
+
+func NewCardHandler(cs port.Card, us port.UserService) *CardHandler {
+	return &CardHandler{
+		cs: cs,
+		us: us,
+	}
+}
+
+// Greeting takes a private message as the greeting the user was asked for, false means no greeting is awaited,
+// users greeting several celebrants are asked to name the celebrant with /greet
+func (ch *CardHandler) Greeting(log *slog.Logger, update tgbotapi.Update, tg port.Telegram) bool {
+	op := "handlers.Greeting"
+	log.With(slog.String("op", op))
+
+	if !update.Message.Chat.IsPrivate() {
+		return false
+	}
+
+	greetings, gogErr := ch.cs.GetOpenGreetings(update.SentFrom().ID)
+	if gogErr != nil {
+		log.Debug("error get open greetings", "error", gogErr)
+		return false
+	}
+	var celebrants []string
+	for _, greeting := range *greetings {
+		if celebrant := "@" + greeting.CelebrantUsername; !slices.Contains(celebrants, celebrant) {
+			celebrants = append(celebrants, celebrant)
+		}
+	}
+
+	switch len(celebrants) {
+	case 0:
+		return false
+	case 1:
+		ch.greet(log, update, tg, (*greetings)[0].CelebrantID, update.Message.Text)
+		return true
+	default:
+		tg.SendMessage(update.Message.Chat.ID, fmt.Sprintf("you greet %s, send /greet \"@username\" \"greeting\" to choose the card",
+			strings.Join(celebrants, ", ")))
+		return true
+	}
+}
+
+// Greet takes "@username" of the celebrant and the greeting, sending it again changes the greeting
+func (ch *CardHandler) Greet(log *slog.Logger, update tgbotapi.Update, tg port.Telegram) {
+	op := "handlers.Greet"
+	log.With(slog.String("op", op))
+
+	celebrant, text, _ := strings.Cut(strings.TrimSpace(update.Message.CommandArguments()), " ")
+	username, found := strings.CutPrefix(celebrant, "@")
+	if !found || username == "" {
+		tg.SendMessage(update.Message.Chat.ID, "send /greet \"@username\" \"greeting\"")
+		return
+	}
+
+	celebrantID, guErr := ch.us.GetTelegramIDByUsername(username)
+	if guErr != nil {
+		switch {
+		case errors.Is(guErr, domain.ErrNotFound):
+			tg.SendMessage(update.Message.Chat.ID, fmt.Sprintf("user @%s not register in service", username))
+			return
+		default:
+			log.Debug("error of get user by username", "error", guErr)
+			tg.SendMessage(update.Message.Chat.ID, "internal server error")
+			return
+		}
+	}
+
+	ch.greet(log, update, tg, celebrantID, text)
+}
+
+func (ch *CardHandler) greet(log *slog.Logger, update tgbotapi.Update, tg port.Telegram, celebrantID int64, text string) {
+	greeting, gErr := ch.cs.Greet(update.SentFrom().ID, celebrantID, text)
+	if gErr != nil {
+		switch {
+		case errors.Is(gErr, domain.ErrNotFound):
+			tg.SendMessage(update.Message.Chat.ID, "no greeting card awaits your greeting for this user")
+			return
+		case errors.Is(gErr, domain.ErrValidation):
+			tg.SendMessage(update.Message.Chat.ID, "greeting must be a text up to 500 characters")
+			return
+		default:
+			log.Debug("error save greeting", "error", gErr)
+			tg.SendMessage(update.Message.Chat.ID, "internal server error")
+			return
+		}
+	}
+
+	if greeting.Delivered {
+		tg.SendMessage(update.Message.Chat.ID, fmt.Sprintf("the card is already delivered, your greeting is sent to @%s separately", greeting.CelebrantUsername))
+		return
+	}
+	tg.SendMessage(update.Message.Chat.ID, fmt.Sprintf("success, your greeting for @%s is in the card, send /greet \"@%s\" \"greeting\" to change it",
+		greeting.CelebrantUsername, greeting.CelebrantUsername))
+}
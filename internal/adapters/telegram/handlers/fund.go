@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"birthdayapp/internal/config"
+	"birthdayapp/internal/core/domain"
+	"birthdayapp/internal/core/port"
+	"errors"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"log/slog"
+	"strconv"
+	"strings"
+)
+
+const fundsOffMessage = "gift funds open only for surprises, surprise mode is turned off"
+
+type FundHandler struct {
+	fs  port.Fund
+	us  port.UserService
+	cfg *config.Config
+}
+
+func NewFundHandler(fs port.Fund, us port.UserService, cfg *config.Config) *FundHandler {
+	return &FundHandler{
+		fs:  fs,
+		us:  us,
+		cfg: cfg,
+	}
+}
+
+// noFund explains a missing fund, without surprise mode funds never open
+func noFund(cfg *config.Config, message string) string {
+	if !cfg.Surprise.Enabled {
+		return fundsOffMessage
+	}
+	return message
+}
+
+// Pledge takes "amount" and an optional fund number, needed only when the user takes part in several funds
+func (fh *FundHandler) Pledge(log *slog.Logger, update tgbotapi.Update, tg port.Telegram) {
+	op := "handlers.Pledge"
+	log.With(slog.String("op", op))
+
+	args := strings.Fields(update.Message.CommandArguments())
+	if len(args) == 0 || len(args) > 2 {
+		tg.SendMessage(update.Message.Chat.ID, "send /pledge \"amount\", add the fund number if you take part in several funds")
+		return
+	}
+	amount, pErr := strconv.ParseInt(args[0], 10, 64)
+	if pErr != nil || amount <= 0 {
+		tg.SendMessage(update.Message.Chat.ID, "amount must be a positive whole number")
+		return
+	}
+	fundID, ok := fh.fundID(log, update, tg, args[1:])
+	if !ok {
+		return
+	}
+
+	fund, pErr := fh.fs.Pledge(update.SentFrom().ID, fundID, amount)
+	if pErr != nil {
+		switch {
+		case errors.Is(pErr, domain.ErrNotFound):
+			tg.SendMessage(update.Message.Chat.ID, noFund(fh.cfg, fmt.Sprintf("you don't take part in the gift fund #%d", fundID)))
+			return
+		case errors.Is(pErr, domain.ErrClosed):
+			tg.SendMessage(update.Message.Chat.ID, fmt.Sprintf("pledges to the gift fund #%d are closed", fundID))
+			return
+		case errors.Is(pErr, domain.ErrForbidden):
+			tg.SendMessage(update.Message.Chat.ID, "your pledge is already paid")
+			return
+		default:
+			log.Debug("error pledge", "error", pErr)
+			tg.SendMessage(update.Message.Chat.ID, "internal server error")
+			return
+		}
+	}
+
+	message := fmt.Sprintf("success, you pledge %d to the gift fund #%d for %s", amount, fund.ID, fund.Celebrants)
+	if fund.OrganizerID == update.SentFrom().ID {
+		message += ". You organize the fund, send /fund to see the pledges and /paid \"@username\" when a pledge is paid"
+	}
+	tg.SendMessage(update.Message.Chat.ID, message)
+}
+
+// Fund shows the organizer who pledged, who paid and who hasn't
+func (fh *FundHandler) Fund(log *slog.Logger, update tgbotapi.Update, tg port.Telegram) {
+	op := "handlers.Fund"
+	log.With(slog.String("op", op))
+
+	fundID, ok := fh.fundID(log, update, tg, strings.Fields(update.Message.CommandArguments()))
+	if !ok {
+		return
+	}
+
+	fund, contributions, gfErr := fh.fs.GetFund(update.SentFrom().ID, fundID)
+	if gfErr != nil {
+		switch {
+		case errors.Is(gfErr, domain.ErrNotFound):
+			tg.SendMessage(update.Message.Chat.ID, noFund(fh.cfg, fmt.Sprintf("no gift fund #%d", fundID)))
+			return
+		case errors.Is(gfErr, domain.ErrForbidden):
+			tg.SendMessage(update.Message.Chat.ID, "only the organizer of the fund sees the pledges")
+			return
+		default:
+			log.Debug("error get fund", "error", gfErr)
+			tg.SendMessage(update.Message.Chat.ID, "internal server error")
+			return
+		}
+	}
+
+	tg.SendMessage(update.Message.Chat.ID, domain.FundSummary(fund, *contributions))
+}
+
+// Paid takes "@username" or "telegram_id" of the member and an optional fund number
+func (fh *FundHandler) Paid(log *slog.Logger, update tgbotapi.Update, tg port.Telegram) {
+	op := "handlers.Paid"
+	log.With(slog.String("op", op))
+
+	args := strings.Fields(update.Message.CommandArguments())
+	if len(args) == 0 || len(args) > 2 {
+		tg.SendMessage(update.Message.Chat.ID, "send /paid \"@username\" or \"telegram_id\", add the fund number if you take part in several funds")
+		return
+	}
+
+	var telegramID int64
+	if username, found := strings.CutPrefix(args[0], "@"); found {
+		var guErr error
+		telegramID, guErr = fh.us.GetTelegramIDByUsername(username)
+		if guErr != nil {
+			switch {
+			case errors.Is(guErr, domain.ErrNotFound):
+				tg.SendMessage(update.Message.Chat.ID, fmt.Sprintf("user @%s not register in service", username))
+				return
+			default:
+				log.Debug("error of get user by username", "error", guErr)
+				tg.SendMessage(update.Message.Chat.ID, "internal server error")
+				return
+			}
+		}
+	} else {
+		var pErr error
+		telegramID, pErr = strconv.ParseInt(args[0], 10, 64)
+		if pErr != nil {
+			tg.SendMessage(update.Message.Chat.ID, "user must be @username or telegram_id")
+			return
+		}
+	}
+	fundID, ok := fh.fundID(log, update, tg, args[1:])
+	if !ok {
+		return
+	}
+
+	if mpErr := fh.fs.MarkPaid(update.SentFrom().ID, fundID, telegramID); mpErr != nil {
+		switch {
+		case errors.Is(mpErr, domain.ErrNotFound):
+			tg.SendMessage(update.Message.Chat.ID, noFund(fh.cfg, fmt.Sprintf("no pledge of %s in the gift fund #%d", args[0], fundID)))
+			return
+		case errors.Is(mpErr, domain.ErrForbidden):
+			tg.SendMessage(update.Message.Chat.ID, "only the organizer of the fund marks pledges paid")
+			return
+		default:
+			log.Debug("error mark paid", "error", mpErr)
+			tg.SendMessage(update.Message.Chat.ID, "internal server error")
+			return
+		}
+	}
+
+	tg.SendMessage(update.Message.Chat.ID, fmt.Sprintf("success, the pledge of %s is paid", args[0]))
+}
+
+// fundID parses the fund number, without it the only open fund of the user is taken, closed funds need the number
+func (fh *FundHandler) fundID(log *slog.Logger, update tgbotapi.Update, tg port.Telegram, args []string) (int, bool) {
+	if len(args) > 0 {
+		fundID, pErr := strconv.Atoi(strings.TrimPrefix(args[0], "#"))
+		if pErr != nil || fundID <= 0 {
+			tg.SendMessage(update.Message.Chat.ID, "fund number must be a positive number")
+			return 0, false
+		}
+		return fundID, true
+	}
+
+	funds, gmfErr := fh.fs.GetMemberFunds(update.SentFrom().ID)
+	if gmfErr != nil {
+		log.Debug("error get member funds", "error", gmfErr)
+		tg.SendMessage(update.Message.Chat.ID, "internal server error")
+		return 0, false
+	}
+
+	switch len(*funds) {
+	case 0:
+		tg.SendMessage(update.Message.Chat.ID, noFund(fh.cfg, "you take part in no open gift fund, add the number of a closed fund"))
+		return 0, false
+	case 1:
+		return (*funds)[0].ID, true
+	default:
+		fundList := make([]string, 0, len(*funds))
+		for _, fund := range *funds {
+			fundList = append(fundList, fmt.Sprintf("#%d for %s", fund.ID, fund.Celebrants))
+		}
+		tg.SendMessage(update.Message.Chat.ID, fmt.Sprintf("you take part in several gift funds, add the fund number: %s", strings.Join(fundList, ", ")))
+		return 0, false
+	}
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"birthdayapp/internal/core/port"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"log/slog"
+	"time"
+)
+
+type GroupHandler struct {
+	bs port.Birthday
+}
+
+func NewGroupHandler(bs port.Birthday) *GroupHandler {
+	return &GroupHandler{
+		bs: bs,
+	}
+}
+
+// JoinRequest approves join requests of the expected members of current celebrations and declines everyone else
+func (gh *GroupHandler) JoinRequest(log *slog.Logger, update tgbotapi.Update, tg port.Telegram) {
+	op := "handlers.JoinRequest"
+	log.With(slog.String("op", op))
+
+	chatID, userID := update.ChatJoinRequest.Chat.ID, update.ChatJoinRequest.From.ID
+	username := update.ChatJoinRequest.From.UserName
+
+	expected, ieErr := gh.bs.IsExpectedMember(chatID, userID)
+	if ieErr != nil {
+		//the request stays pending for group admins
+		log.Error("error check join request", "chat_id", chatID, "telegram_id", userID, "error", ieErr)
+		return
+	}
+
+	if !expected {
+		log.Info("join request declined", "chat_id", chatID, "telegram_id", userID, "username", username)
+		if djErr := tg.DeclineJoinRequest(chatID, userID); djErr != nil {
+			log.Error("error decline join request", "chat_id", chatID, "telegram_id", userID, "error", djErr)
+		}
+		return
+	}
+
+	log.Info("join request approved", "chat_id", chatID, "telegram_id", userID, "username", username)
+	if ajErr := tg.ApproveJoinRequest(chatID, userID); ajErr != nil {
+		log.Error("error approve join request", "chat_id", chatID, "telegram_id", userID, "error", ajErr)
+	}
+}
+
+// ChatMember records users joining and leaving birthday groups
+func (gh *GroupHandler) ChatMember(log *slog.Logger, update tgbotapi.Update) {
+	op := "handlers.ChatMember"
+	log.With(slog.String("op", op))
+
+	member := update.ChatMember
+	wasIn, isIn := inChat(member.OldChatMember), inChat(member.NewChatMember)
+	if wasIn == isIn || member.NewChatMember.User == nil {
+		//promotions, restrictions and other changes without joining or leaving
+		return
+	}
+
+	chatID, userID := member.Chat.ID, member.NewChatMember.User.ID
+	at := time.Unix(int64(member.Date), 0)
+	if isIn {
+		if mjErr := gh.bs.MemberJoined(chatID, userID, at); mjErr != nil {
+			log.Error("error record join", "chat_id", chatID, "telegram_id", userID, "error", mjErr)
+		}
+		return
+	}
+	if mlErr := gh.bs.MemberLeft(chatID, userID, at); mlErr != nil {
+		log.Error("error record leave", "chat_id", chatID, "telegram_id", userID, "error", mlErr)
+	}
+}
+
+func inChat(member tgbotapi.ChatMember) bool {
+	switch member.Status {
+	case "creator", "administrator", "member":
+		return true
+	case "restricted":
+		return member.IsMember
+	default:
+		//left or kicked
+		return false
+	}
+}
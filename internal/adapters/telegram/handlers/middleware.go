@@ -1,27 +1,46 @@
 package handlers
 
 import (
+	"birthdayapp/internal/config"
 	"birthdayapp/internal/core/domain"
 	"birthdayapp/internal/core/port"
+	"fmt"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
 type Middleware struct {
-	ur port.UserRepo
+	ur  port.UserRepo
+	cfg *config.Config
 }
 
-func NewMiddleware(ur port.UserRepo) *Middleware {
+func NewMiddleware(ur port.UserRepo, cfg *config.Config) *Middleware {
 	return &Middleware{
-		ur: ur,
+		ur:  ur,
+		cfg: cfg,
 	}
 }
 
 func (m *Middleware) UserMiddleware(update tgbotapi.Update) error {
 	user := &domain.User{TelegramID: update.SentFrom().ID}
-	_, guErr := m.ur.GetUserByTelegramID(user)
+	uUser, guErr := m.ur.GetUserByTelegramID(user)
 	if guErr != nil {
 		//domain.ErrNotFound
 		return guErr
 	}
+	if !uUser.Active && uUser.IsSelfRegistered() {
+		return fmt.Errorf("telegram_id %d: %w", user.TelegramID, domain.ErrNotApproved)
+	}
+	if !uUser.Active {
+		return fmt.Errorf("telegram_id %d deactivated: %w", user.TelegramID, domain.ErrNotFound)
+	}
 	return nil
 }
+
+func (m *Middleware) AdminMiddleware(update tgbotapi.Update) error {
+	for _, adminID := range m.cfg.Admins {
+		if adminID == update.SentFrom().ID {
+			return nil
+		}
+	}
+	return fmt.Errorf("telegram_id %d is not admin: %w", update.SentFrom().ID, domain.ErrForbidden)
+}
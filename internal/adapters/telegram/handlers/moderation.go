@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"birthdayapp/internal/core/domain"
+	"birthdayapp/internal/core/port"
+	"bytes"
+	"errors"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"log/slog"
+	"strconv"
+	"strings"
+)
+
+type ModerationHandler struct {
+	ms port.Moderation
+}
+
+func NewModerationHandler(ms port.Moderation) *ModerationHandler {
+	return &ModerationHandler{
+		ms: ms,
+	}
+}
+
+// DeadLetters lists kicks and unbans that failed every retry
+func (mh *ModerationHandler) DeadLetters(log *slog.Logger, update tgbotapi.Update, tg port.Telegram) {
+	op := "handlers.DeadLetters"
+	log.With(slog.String("op", op))
+
+	actions, gdaErr := mh.ms.GetDeadActions()
+	if gdaErr != nil {
+		log.Debug("error get dead actions", "error", gdaErr)
+		tg.SendMessage(update.Message.Chat.ID, "internal server error")
+		return
+	}
+	if len(*actions) == 0 {
+		tg.SendMessage(update.Message.Chat.ID, "no dead letters")
+		return
+	}
+
+	var message bytes.Buffer
+	message.WriteString("dead letters, send /retry \"id\" or \"all\" to queue them again:")
+	for _, action := range *actions {
+		message.WriteString(fmt.Sprintf("\n%d: %s telegram_id %d in chat %d, %d attempts, last error: %s",
+			action.ID, action.Action, action.TelegramID, action.ChatID, action.Attempts, action.LastError))
+	}
+	tg.SendMessage(update.Message.Chat.ID, message.String())
+}
+
+// Retry queues the dead letter with the id again, "all" queues every dead letter
+func (mh *ModerationHandler) Retry(log *slog.Logger, update tgbotapi.Update, tg port.Telegram) {
+	op := "handlers.Retry"
+	log.With(slog.String("op", op))
+
+	arg := strings.TrimSpace(update.Message.CommandArguments())
+	id := 0
+	if arg != "all" {
+		var pErr error
+		id, pErr = strconv.Atoi(arg)
+		if pErr != nil || id <= 0 {
+			tg.SendMessage(update.Message.Chat.ID, "arg must be id of the dead letter or \"all\"")
+			return
+		}
+	}
+
+	retried, rdaErr := mh.ms.RetryDeadActions(id)
+	if rdaErr != nil {
+		switch {
+		case errors.Is(rdaErr, domain.ErrNotFound):
+			tg.SendMessage(update.Message.Chat.ID, "no dead letter with this id")
+			return
+		default:
+			log.Debug("error retry dead actions", "error", rdaErr)
+			tg.SendMessage(update.Message.Chat.ID, "internal server error")
+			return
+		}
+	}
+
+	tg.SendMessage(update.Message.Chat.ID, fmt.Sprintf("success, %d dead letters are queued again", retried))
+}
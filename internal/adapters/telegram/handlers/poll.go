@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"birthdayapp/internal/config"
+	"birthdayapp/internal/core/domain"
+	"birthdayapp/internal/core/port"
+	"errors"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"log/slog"
+	"strings"
+)
+
+const pollUsage = `send /poll gift "option1" "option2" … in the birthday group, from 2 to 10 options up to 100 characters`
+
+type PollHandler struct {
+	ps  port.Poll
+	cfg *config.Config
+}
+
+func NewPollHandler(ps port.Poll, cfg *config.Config) *PollHandler {
+	return &PollHandler{
+		ps:  ps,
+		cfg: cfg,
+	}
+}
+
+// Poll takes gift and quoted options, the poll is posted in the group the command is sent to
+func (ph *PollHandler) Poll(log *slog.Logger, update tgbotapi.Update, tg port.Telegram) {
+	op := "handlers.Poll"
+	log.With(slog.String("op", op))
+
+	kind, arg, _ := strings.Cut(strings.TrimSpace(update.Message.CommandArguments()), " ")
+	options, ok := quotedArgs(arg)
+	if kind != "gift" || !ok {
+		tg.SendMessage(update.Message.Chat.ID, pollUsage)
+		return
+	}
+
+	if _, spErr := ph.ps.StartPoll(update.SentFrom().ID, update.Message.Chat.ID, options); spErr != nil {
+		switch {
+		case errors.Is(spErr, domain.ErrValidation):
+			tg.SendMessage(update.Message.Chat.ID, pollUsage)
+			return
+		case errors.Is(spErr, domain.ErrNotFound):
+			tg.SendMessage(update.Message.Chat.ID, noFund(ph.cfg, "send /poll in the birthday group of an open gift fund you take part in"))
+			return
+		case errors.Is(spErr, domain.ErrForbidden):
+			tg.SendMessage(update.Message.Chat.ID, "only the organizer of the gift fund starts the poll, the first one who pledges organizes the fund")
+			return
+		case errors.Is(spErr, domain.ErrClosed):
+			tg.SendMessage(update.Message.Chat.ID, "it's too late for a gift poll, it must end well before the celebrants join")
+			return
+		case errors.Is(spErr, domain.ErrAlreadyExist):
+			tg.SendMessage(update.Message.Chat.ID, "the gift poll of this fund is already started")
+			return
+		default:
+			log.Debug("error start poll", "error", spErr)
+			tg.SendMessage(update.Message.Chat.ID, "internal server error")
+			return
+		}
+	}
+}
+
+// PollAnswer stores votes of gift polls, answers to polls not posted by the bot are ignored
+func (ph *PollHandler) PollAnswer(log *slog.Logger, update tgbotapi.Update) {
+	op := "handlers.PollAnswer"
+	log.With(slog.String("op", op))
+
+	answer := update.PollAnswer
+	if vErr := ph.ps.Vote(answer.PollID, answer.User.ID, answer.OptionIDs); vErr != nil &&
+		!errors.Is(vErr, domain.ErrNotFound) && !errors.Is(vErr, domain.ErrClosed) {
+		log.Error("error save poll answer", "poll_id", answer.PollID, "telegram_id", answer.User.ID, "error", vErr)
+	}
+}
+
+// quotedArgs splits `"option 1" "option 2"` into options, words out of quotes are options too,
+// typographic quotes of mobile keyboards count as plain ones
+func quotedArgs(arg string) ([]string, bool) {
+	arg = strings.NewReplacer("“", `"`, "”", `"`, "«", `"`, "»", `"`).Replace(arg)
+	parts := strings.Split(arg, `"`)
+	//unbalanced quotes
+	if len(parts)%2 == 0 {
+		return nil, false
+	}
+
+	var args []string
+	for i, part := range parts {
+		if i%2 == 1 {
+			args = append(args, part)
+			continue
+		}
+		args = append(args, strings.Fields(part)...)
+	}
+	return args, true
+}
@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"birthdayapp/internal/config"
+	"birthdayapp/internal/core/domain"
+	"birthdayapp/internal/core/port"
+	"errors"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var birthdayInputLayouts = []string{"02.01.2006", "2006-01-02"}
+
+type RegistrationHandler struct {
+	us  port.UserService
+	cfg *config.Config
+
+	//users who sent /register without birthday and should send it next
+	mu       sync.Mutex
+	awaiting map[int64]bool
+}
+
+func NewRegistrationHandler(us port.UserService, cfg *config.Config) *RegistrationHandler {
+	return &RegistrationHandler{
+		us:       us,
+		cfg:      cfg,
+		awaiting: make(map[int64]bool),
+	}
+}
+
+func (rh *RegistrationHandler) Register(log *slog.Logger, update tgbotapi.Update, tg port.Telegram) {
+	op := "handlers.Register"
+	log.With(slog.String("op", op))
+
+	if !rh.cfg.Registration.Enabled {
+		tg.SendMessage(update.Message.Chat.ID, "registration is disabled, contact support")
+		return
+	}
+	if !update.Message.Chat.IsPrivate() {
+		tg.SendMessage(update.Message.Chat.ID, "please, send /register to me in private chat")
+		return
+	}
+
+	if update.Message.CommandArguments() == "" {
+		rh.setAwaiting(update.SentFrom().ID, true)
+		tg.SendMessage(update.Message.Chat.ID, "send your birthday in format DD.MM.YYYY")
+		return
+	}
+	rh.register(log, update, tg, update.Message.CommandArguments())
+}
+
+// Birthday handles the message sent after /register, returns false if the user isn't registering
+func (rh *RegistrationHandler) Birthday(log *slog.Logger, update tgbotapi.Update, tg port.Telegram) bool {
+	if !update.Message.Chat.IsPrivate() || !rh.isAwaiting(update.SentFrom().ID) {
+		return false
+	}
+	rh.register(log, update, tg, update.Message.Text)
+	return true
+}
+
+func (rh *RegistrationHandler) register(log *slog.Logger, update tgbotapi.Update, tg port.Telegram, birthdayText string) {
+
+	birthday, pbErr := parseBirthday(birthdayText)
+	if pbErr != nil {
+		rh.setAwaiting(update.SentFrom().ID, true)
+		tg.SendMessage(update.Message.Chat.ID, "birthday must be in format DD.MM.YYYY, try again")
+		return
+	}
+
+	if update.SentFrom().UserName == "" {
+		rh.setAwaiting(update.SentFrom().ID, false)
+		tg.SendMessage(update.Message.Chat.ID, "please, set telegram username and send /register again")
+		return
+	}
+
+	user := &domain.User{
+		Username:   update.SentFrom().UserName,
+		TelegramID: update.SentFrom().ID,
+		Birthday:   birthday,
+	}
+
+	registered, rErr := rh.us.Register(user)
+	if rErr != nil {
+		switch {
+		case errors.Is(rErr, domain.ErrValidation):
+			rh.setAwaiting(update.SentFrom().ID, true)
+			tg.SendMessage(update.Message.Chat.ID, "birthday is not valid, try again")
+			return
+		case errors.Is(rErr, domain.ErrAlreadyExist):
+			rh.setAwaiting(update.SentFrom().ID, false)
+			tg.SendMessage(update.Message.Chat.ID, "you or your username are already registered, contact support if it's wrong")
+			return
+		case errors.Is(rErr, domain.ErrNotApproved):
+			rh.setAwaiting(update.SentFrom().ID, false)
+			tg.SendMessage(update.Message.Chat.ID, "your registration is waiting for admin approval")
+			return
+		default:
+			log.Debug("error register user", "error", rErr)
+			tg.SendMessage(update.Message.Chat.ID, "internal server error")
+			return
+		}
+	}
+	rh.setAwaiting(update.SentFrom().ID, false)
+
+	if registered.Active {
+		tg.SendMessage(update.Message.Chat.ID, "success, you are registered. Send /help to get a list of commands")
+		return
+	}
+
+	tg.SendMessage(update.Message.Chat.ID, "registration sent to admins, you'll get a message after approval")
+	for _, adminID := range rh.cfg.Admins {
+		tg.SendMessage(adminID, fmt.Sprintf("@%s (%d) wants to register with birthday %s: /approve %d or /decline %d",
+			registered.Username, registered.TelegramID, registered.Birthday.Format("02.01.2006"), registered.TelegramID, registered.TelegramID))
+	}
+}
+
+func (rh *RegistrationHandler) Approve(log *slog.Logger, update tgbotapi.Update, tg port.Telegram) {
+	op := "handlers.Approve"
+	log.With(slog.String("op", op))
+
+	telegramID, pErr := strconv.ParseInt(strings.TrimSpace(update.Message.CommandArguments()), 10, 64)
+	if pErr != nil {
+		tg.SendMessage(update.Message.Chat.ID, "arg must be telegram_id")
+		return
+	}
+
+	user, arErr := rh.us.ApproveRegistration(telegramID)
+	if arErr != nil {
+		switch {
+		case errors.Is(arErr, domain.ErrNotFound):
+			tg.SendMessage(update.Message.Chat.ID, "no registration waiting for approval")
+			return
+		default:
+			log.Debug("error approve registration", "error", arErr)
+			tg.SendMessage(update.Message.Chat.ID, "internal server error")
+			return
+		}
+	}
+
+	tg.SendMessage(user.TelegramID, "your registration is approved. Send /help to get a list of commands")
+	tg.SendMessage(update.Message.Chat.ID, fmt.Sprintf("success, @%s is registered", user.Username))
+}
+
+func (rh *RegistrationHandler) Decline(log *slog.Logger, update tgbotapi.Update, tg port.Telegram) {
+	op := "handlers.Decline"
+	log.With(slog.String("op", op))
+
+	telegramID, pErr := strconv.ParseInt(strings.TrimSpace(update.Message.CommandArguments()), 10, 64)
+	if pErr != nil {
+		tg.SendMessage(update.Message.Chat.ID, "arg must be telegram_id")
+		return
+	}
+
+	user, drErr := rh.us.DeclineRegistration(telegramID)
+	if drErr != nil {
+		switch {
+		case errors.Is(drErr, domain.ErrNotFound):
+			tg.SendMessage(update.Message.Chat.ID, "no registration waiting for approval")
+			return
+		default:
+			log.Debug("error decline registration", "error", drErr)
+			tg.SendMessage(update.Message.Chat.ID, "internal server error")
+			return
+		}
+	}
+
+	tg.SendMessage(user.TelegramID, "your registration is declined, contact support")
+	tg.SendMessage(update.Message.Chat.ID, fmt.Sprintf("success, registration of @%s declined", user.Username))
+}
+
+func (rh *RegistrationHandler) isAwaiting(telegramID int64) bool {
+	rh.mu.Lock()
+	defer rh.mu.Unlock()
+	return rh.awaiting[telegramID]
+}
+
+func (rh *RegistrationHandler) setAwaiting(telegramID int64, awaiting bool) {
+	rh.mu.Lock()
+	defer rh.mu.Unlock()
+	if awaiting {
+		rh.awaiting[telegramID] = true
+		return
+	}
+	delete(rh.awaiting, telegramID)
+}
+
+func parseBirthday(text string) (time.Time, error) {
+	var pErr error
+	for _, layout := range birthdayInputLayouts {
+		var birthday time.Time
+		birthday, pErr = time.Parse(layout, strings.TrimSpace(text))
+		if pErr == nil {
+			return birthday, nil
+		}
+	}
+	return time.Time{}, pErr
+}
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"birthdayapp/internal/config"
+	"birthdayapp/internal/core/domain"
+	"birthdayapp/internal/core/port"
+	"errors"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"log/slog"
+	"strconv"
+	"strings"
+)
+
+type SettingsHandler struct {
+	us  port.UserService
+	cfg *config.Config
+}
+
+func NewSettingsHandler(us port.UserService, cfg *config.Config) *SettingsHandler {
+	return &SettingsHandler{
+		us:  us,
+		cfg: cfg,
+	}
+}
+
+// Timezone sets the user timezone, "default" resets it to the service default
+func (sh *SettingsHandler) Timezone(log *slog.Logger, update tgbotapi.Update, tg port.Telegram) {
+	op := "handlers.Timezone"
+	log.With(slog.String("op", op))
+
+	timezone := strings.TrimSpace(update.Message.CommandArguments())
+	if timezone == "" {
+		tg.SendMessage(update.Message.Chat.ID, "arg must be IANA timezone like 'Europe/Moscow' or 'default'")
+		return
+	}
+	if timezone == "default" {
+		timezone = ""
+	}
+
+	user := &domain.User{TelegramID: update.SentFrom().ID, Timezone: timezone}
+	ctErr := sh.us.ChangeTimezone(user)
+	if ctErr != nil {
+		switch {
+		case errors.Is(ctErr, domain.ErrValidation):
+			tg.SendMessage(update.Message.Chat.ID, "unknown timezone, use IANA name like 'Europe/Moscow'")
+			return
+		default:
+			log.Debug("error change timezone", "error", ctErr)
+			tg.SendMessage(update.Message.Chat.ID, "couldn't change timezone, try again later")
+			return
+		}
+	}
+
+	if timezone == "" {
+		timezone = sh.cfg.DefaultTimezone
+	}
+	tg.SendMessage(update.Message.Chat.ID, fmt.Sprintf("success, your birthday is celebrated at %02d:00 %s", sh.cfg.NotifyHour, timezone))
+}
+
+// Digest turns the weekly digest of subscribed birthdays on or off
+func (sh *SettingsHandler) Digest(log *slog.Logger, update tgbotapi.Update, tg port.Telegram) {
+	op := "handlers.Digest"
+	log.With(slog.String("op", op))
+
+	user := &domain.User{TelegramID: update.SentFrom().ID}
+
+	switch update.Message.CommandArguments() {
+	case "on":
+		user.DigestOptOut = false
+	case "off":
+		user.DigestOptOut = true
+	default:
+		tg.SendMessage(update.Message.Chat.ID, "arg must be 'on' or 'off'")
+		return
+	}
+
+	cdErr := sh.us.ChangeDigestOptOut(user)
+	if cdErr != nil {
+		log.Debug("error change digest opt-out", "error", cdErr)
+		tg.SendMessage(update.Message.Chat.ID, "couldn't change digest, try again later")
+		return
+	}
+
+	if user.DigestOptOut {
+		tg.SendMessage(update.Message.Chat.ID, "success, weekly digest is turned off")
+		return
+	}
+	tg.SendMessage(update.Message.Chat.ID, "success, weekly digest is turned on")
+}
+
+// Reminders sets days before subscribed birthdays to remind, "off" turns reminders off and "default" resets them
+func (sh *SettingsHandler) Reminders(log *slog.Logger, update tgbotapi.Update, tg port.Telegram) {
+	op := "handlers.Reminders"
+	log.With(slog.String("op", op))
+
+	user := &domain.User{TelegramID: update.SentFrom().ID}
+
+	switch arg := strings.TrimSpace(update.Message.CommandArguments()); arg {
+	case "":
+		tg.SendMessage(update.Message.Chat.ID, "arg must be days before birthday like '7,1', 'off' or 'default'")
+		return
+	case "default":
+		user.ReminderDays = nil
+	case "off":
+		user.ReminderDays = []int{}
+	default:
+		user.ReminderDays = []int{}
+		for _, dayStr := range strings.Split(arg, ",") {
+			day, aErr := strconv.Atoi(strings.TrimSpace(dayStr))
+			if aErr != nil {
+				tg.SendMessage(update.Message.Chat.ID, "arg must be days before birthday like '7,1', 'off' or 'default'")
+				return
+			}
+			user.ReminderDays = append(user.ReminderDays, day)
+		}
+	}
+
+	crErr := sh.us.ChangeReminderDays(user)
+	if crErr != nil {
+		switch {
+		case errors.Is(crErr, domain.ErrValidation):
+			tg.SendMessage(update.Message.Chat.ID, fmt.Sprintf("days must be from 1 to %d", domain.MaxReminderDays))
+			return
+		default:
+			log.Debug("error change reminder days", "error", crErr)
+			tg.SendMessage(update.Message.Chat.ID, "couldn't change reminders, try again later")
+			return
+		}
+	}
+
+	days := user.RemindersOn(sh.cfg.ReminderDays)
+	if len(days) == 0 {
+		tg.SendMessage(update.Message.Chat.ID, "success, reminders are turned off")
+		return
+	}
+	daysStr := make([]string, len(days))
+	for i, day := range days {
+		daysStr[i] = strconv.Itoa(day)
+	}
+	tg.SendMessage(update.Message.Chat.ID, fmt.Sprintf("success, you'll be reminded %s days before birthdays", strings.Join(daysStr, ", ")))
+}
@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"birthdayapp/internal/core/domain"
+	"birthdayapp/internal/core/port"
+	"errors"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"log/slog"
+	"strconv"
+	"strings"
+)
+
+const wishlistUsage = `send /wishlist add "gift", /wishlist remove "number", /wishlist list or /wishlist list "@username",
+/wishlist claim "number" to buy a gift of a user you subscribed to and /wishlist unclaim "number" to cancel`
+
+type WishlistHandler struct {
+	ws port.Wishlist
+	us port.UserService
+}
+
+func NewWishlistHandler(ws port.Wishlist, us port.UserService) *WishlistHandler {
+	return &WishlistHandler{
+		ws: ws,
+		us: us,
+	}
+}
+
+// Wishlist dispatches the add, remove, list, claim and unclaim actions
+func (wh *WishlistHandler) Wishlist(log *slog.Logger, update tgbotapi.Update, tg port.Telegram) {
+	op := "handlers.Wishlist"
+	log.With(slog.String("op", op))
+
+	action, arg, _ := strings.Cut(strings.TrimSpace(update.Message.CommandArguments()), " ")
+	arg = strings.Trim(strings.TrimSpace(arg), `"`)
+
+	switch action {
+	case "add":
+		wh.add(log, update, tg, arg)
+	case "remove":
+		wh.remove(log, update, tg, arg)
+	case "list":
+		wh.list(log, update, tg, arg)
+	case "claim":
+		wh.claim(log, update, tg, arg)
+	case "unclaim":
+		wh.unclaim(log, update, tg, arg)
+	default:
+		tg.SendMessage(update.Message.Chat.ID, wishlistUsage)
+	}
+}
+
+func (wh *WishlistHandler) add(log *slog.Logger, update tgbotapi.Update, tg port.Telegram, title string) {
+	item, aiErr := wh.ws.AddItem(update.SentFrom().ID, title)
+	if aiErr != nil {
+		switch {
+		case errors.Is(aiErr, domain.ErrValidation):
+			tg.SendMessage(update.Message.Chat.ID, "gift must be a text up to 200 characters")
+			return
+		default:
+			log.Debug("error add wishlist item", "error", aiErr)
+			tg.SendMessage(update.Message.Chat.ID, "internal server error")
+			return
+		}
+	}
+
+	tg.SendMessage(update.Message.Chat.ID, fmt.Sprintf("success, #%d %s is in your wishlist", item.ID, item.Title))
+}
+
+func (wh *WishlistHandler) remove(log *slog.Logger, update tgbotapi.Update, tg port.Telegram, arg string) {
+	id, ok := itemID(update, tg, arg)
+	if !ok {
+		return
+	}
+
+	if riErr := wh.ws.RemoveItem(update.SentFrom().ID, id); riErr != nil {
+		switch {
+		case errors.Is(riErr, domain.ErrNotFound):
+			tg.SendMessage(update.Message.Chat.ID, fmt.Sprintf("no #%d in your wishlist", id))
+			return
+		default:
+			log.Debug("error remove wishlist item", "error", riErr)
+			tg.SendMessage(update.Message.Chat.ID, "internal server error")
+			return
+		}
+	}
+
+	tg.SendMessage(update.Message.Chat.ID, fmt.Sprintf("success, #%d is removed from your wishlist", id))
+}
+
+// list shows the own wishlist without claims or the wishlist of a user the sender subscribed to
+func (wh *WishlistHandler) list(log *slog.Logger, update tgbotapi.Update, tg port.Telegram, arg string) {
+	viewer := domain.User{TelegramID: update.SentFrom().ID}
+	owner := viewer
+	if username, found := strings.CutPrefix(arg, "@"); found {
+		telegramID, guErr := wh.us.GetTelegramIDByUsername(username)
+		if guErr != nil {
+			switch {
+			case errors.Is(guErr, domain.ErrNotFound):
+				tg.SendMessage(update.Message.Chat.ID, fmt.Sprintf("user @%s not register in service", username))
+				return
+			default:
+				log.Debug("error of get user by username", "error", guErr)
+				tg.SendMessage(update.Message.Chat.ID, "internal server error")
+				return
+			}
+		}
+		owner = domain.User{TelegramID: telegramID, Username: username}
+	} else if arg != "" {
+		tg.SendMessage(update.Message.Chat.ID, "username must be @username")
+		return
+	}
+
+	items, giErr := wh.ws.GetItems(viewer.TelegramID, owner.TelegramID)
+	if giErr != nil {
+		switch {
+		case errors.Is(giErr, domain.ErrForbidden):
+			tg.SendMessage(update.Message.Chat.ID, fmt.Sprintf("subscribe to @%s to see the wishlist", owner.Username))
+			return
+		default:
+			log.Debug("error get wishlist", "error", giErr)
+			tg.SendMessage(update.Message.Chat.ID, "internal server error")
+			return
+		}
+	}
+
+	switch {
+	case len(*items) == 0 && owner.TelegramID == viewer.TelegramID:
+		tg.SendMessage(update.Message.Chat.ID, "your wishlist is empty, send /wishlist add \"gift\" to add one")
+	case len(*items) == 0:
+		tg.SendMessage(update.Message.Chat.ID, fmt.Sprintf("wishlist of @%s is empty", owner.Username))
+	case owner.TelegramID == viewer.TelegramID:
+		var message strings.Builder
+		message.WriteString("your wishlist:")
+		for _, item := range *items {
+			message.WriteString(fmt.Sprintf("\n#%d %s", item.ID, item.Title))
+		}
+		tg.SendMessage(update.Message.Chat.ID, message.String())
+	default:
+		wishlists := map[int64][]domain.WishlistItem{owner.TelegramID: *items}
+		tg.SendMessage(update.Message.Chat.ID, strings.TrimPrefix(domain.WishlistMessage(viewer.TelegramID, []domain.User{owner}, wishlists), "\n"))
+	}
+}
+
+func (wh *WishlistHandler) claim(log *slog.Logger, update tgbotapi.Update, tg port.Telegram, arg string) {
+	id, ok := itemID(update, tg, arg)
+	if !ok {
+		return
+	}
+
+	item, cErr := wh.ws.Claim(update.SentFrom().ID, id)
+	if cErr != nil {
+		switch {
+		case errors.Is(cErr, domain.ErrNotFound):
+			tg.SendMessage(update.Message.Chat.ID, fmt.Sprintf("no wishlist item #%d", id))
+			return
+		case errors.Is(cErr, domain.ErrForbidden):
+			tg.SendMessage(update.Message.Chat.ID, "you can claim only gifts of users you subscribed to")
+			return
+		case errors.Is(cErr, domain.ErrAlreadyExist):
+			tg.SendMessage(update.Message.Chat.ID, fmt.Sprintf("someone already buys #%d, choose another gift", id))
+			return
+		default:
+			log.Debug("error claim wishlist item", "error", cErr)
+			tg.SendMessage(update.Message.Chat.ID, "internal server error")
+			return
+		}
+	}
+
+	tg.SendMessage(update.Message.Chat.ID, fmt.Sprintf("success, you buy #%d %s", item.ID, item.Title))
+}
+
+func (wh *WishlistHandler) unclaim(log *slog.Logger, update tgbotapi.Update, tg port.Telegram, arg string) {
+	id, ok := itemID(update, tg, arg)
+	if !ok {
+		return
+	}
+
+	if uErr := wh.ws.Unclaim(update.SentFrom().ID, id); uErr != nil {
+		switch {
+		case errors.Is(uErr, domain.ErrNotFound):
+			tg.SendMessage(update.Message.Chat.ID, fmt.Sprintf("you don't buy #%d", id))
+			return
+		default:
+			log.Debug("error unclaim wishlist item", "error", uErr)
+			tg.SendMessage(update.Message.Chat.ID, "internal server error")
+			return
+		}
+	}
+
+	tg.SendMessage(update.Message.Chat.ID, fmt.Sprintf("success, #%d is free for other subscribers", id))
+}
+
+func itemID(update tgbotapi.Update, tg port.Telegram, arg string) (int, bool) {
+	id, pErr := strconv.Atoi(strings.TrimPrefix(arg, "#"))
+	if pErr != nil || id <= 0 {
+		tg.SendMessage(update.Message.Chat.ID, "arg must be number of the wishlist item")
+		return 0, false
+	}
+	return id, true
+}
@@ -11,8 +11,16 @@ import (
 )
 
 type Handlers struct {
-	SubscribeHandler *handlers.SubscriptionsHandler
-	Middleware       *handlers.Middleware
+	SubscribeHandler    *handlers.SubscriptionsHandler
+	RegistrationHandler *handlers.RegistrationHandler
+	SettingsHandler     *handlers.SettingsHandler
+	GroupHandler        *handlers.GroupHandler
+	ModerationHandler   *handlers.ModerationHandler
+	FundHandler         *handlers.FundHandler
+	WishlistHandler     *handlers.WishlistHandler
+	CardHandler         *handlers.CardHandler
+	PollHandler         *handlers.PollHandler
+	Middleware          *handlers.Middleware
 }
 
 func NewRouter(ctx context.Context, wg *sync.WaitGroup, log *slog.Logger, h *Handlers, tg *Telegram) {
@@ -22,6 +30,9 @@ func NewRouter(ctx context.Context, wg *sync.WaitGroup, log *slog.Logger, h *Han
 
 	u := tgbotapi.NewUpdate(0)
 	u.Timeout = 60
+	//chat_member updates come only when asked for explicitly and the bot is an admin of the group,
+	//poll_answer updates come for polls of the bot that aren't anonymous
+	u.AllowedUpdates = []string{"message", "chat_join_request", "chat_member", "poll_answer"}
 
 	updates := tg.bot.GetUpdatesChan(u)
 	for {
@@ -32,16 +43,64 @@ func NewRouter(ctx context.Context, wg *sync.WaitGroup, log *slog.Logger, h *Han
 		case update := <-updates:
 
 			go func() {
+				if update.ChatJoinRequest != nil {
+					h.GroupHandler.JoinRequest(log, update, tg)
+					return
+				}
+				if update.ChatMember != nil {
+					h.GroupHandler.ChatMember(log, update)
+					return
+				}
+				if update.PollAnswer != nil {
+					h.PollHandler.PollAnswer(log, update)
+					return
+				}
 				if update.Message == nil { // ignore any non-Message updates
 					return
 				}
-				if !update.Message.IsCommand() { // ignore any non-command Messages
+				if !update.Message.IsCommand() { // non-command Messages are answers to the bot questions
+					if !h.RegistrationHandler.Birthday(log, update, tg) {
+						h.CardHandler.Greeting(log, update, tg)
+					}
 					return
 				}
+
+				//commands available without registration
+				switch update.Message.Command() {
+				case "register":
+					h.RegistrationHandler.Register(log, update, tg)
+					return
+				case "approve", "decline":
+					if aErr := h.Middleware.AdminMiddleware(update); aErr != nil {
+						tg.SendMessage(update.Message.Chat.ID, "command is available only for admins")
+						return
+					}
+					if update.Message.Command() == "approve" {
+						h.RegistrationHandler.Approve(log, update, tg)
+						return
+					}
+					h.RegistrationHandler.Decline(log, update, tg)
+					return
+				case "deadletters", "retry":
+					if aErr := h.Middleware.AdminMiddleware(update); aErr != nil {
+						tg.SendMessage(update.Message.Chat.ID, "command is available only for admins")
+						return
+					}
+					if update.Message.Command() == "deadletters" {
+						h.ModerationHandler.DeadLetters(log, update, tg)
+						return
+					}
+					h.ModerationHandler.Retry(log, update, tg)
+					return
+				}
+
 				if mErr := h.Middleware.UserMiddleware(update); mErr != nil {
 					switch {
 					case errors.Is(mErr, domain.ErrNotFound):
-						tg.SendMessage(update.Message.Chat.ID, "You are not register in this service")
+						tg.SendMessage(update.Message.Chat.ID, "You are not register in this service, send /register to sign up")
+						return
+					case errors.Is(mErr, domain.ErrNotApproved):
+						tg.SendMessage(update.Message.Chat.ID, "your registration is waiting for admin approval")
 						return
 					default:
 						log.Debug("error userMiddleware", "error", mErr)
@@ -59,6 +118,24 @@ func NewRouter(ctx context.Context, wg *sync.WaitGroup, log *slog.Logger, h *Han
 					h.SubscribeHandler.UnSubscribeFrom(log, update, tg)
 				case "subscribeToNotifications":
 					h.SubscribeHandler.SubscribeToNotifications(log, update, tg)
+				case "timezone":
+					h.SettingsHandler.Timezone(log, update, tg)
+				case "reminders":
+					h.SettingsHandler.Reminders(log, update, tg)
+				case "digest":
+					h.SettingsHandler.Digest(log, update, tg)
+				case "pledge":
+					h.FundHandler.Pledge(log, update, tg)
+				case "fund":
+					h.FundHandler.Fund(log, update, tg)
+				case "paid":
+					h.FundHandler.Paid(log, update, tg)
+				case "wishlist":
+					h.WishlistHandler.Wishlist(log, update, tg)
+				case "poll":
+					h.PollHandler.Poll(log, update, tg)
+				case "greet":
+					h.CardHandler.Greet(log, update, tg)
 				default:
 					tg.SendMessage(update.Message.Chat.ID, "unknown command, please send /help to get a list of commands")
 				}
@@ -70,9 +147,18 @@ func NewRouter(ctx context.Context, wg *sync.WaitGroup, log *slog.Logger, h *Han
 
 func Help(update tgbotapi.Update, tg *Telegram) {
 	helpMessage := `u can use commands: 
+	/register "DD.MM.YYYY" for sign up with your birthday
 	/subscribeToNotifications "true" for turn on and "false" for turn off notifications
 	/subscribeTo "telegram_id" or "@username" for subscribe to user birthday,
 	/unSubscribeFrom "telegram_id" or "@username" for unsubscribe from user
+	/reminders "7,1" for reminders before birthdays of your subscriptions, "off" or "default"
+	/digest "on" or "off" for the weekly digest of birthdays of your subscriptions
+	/timezone "Europe/Moscow" for celebrate your birthday in your timezone, "default" for reset
+	/pledge "amount" for pledge to the gift fund of a surprise you plan, funds open only in surprise mode
+	/fund for the organizer to see the gift fund, /paid "@username" to mark a pledge paid
+	/wishlist add "gift", remove "number" or list for your wishlist, list "@username" and claim "number" for wishlists of your subscriptions
+	/poll gift "option1" "option2" for the organizer of a gift fund to start a gift poll in the birthday group
+	/greet "@username" "greeting" for the greeting card you were asked for, send it again to change the greeting
 	`
 	tg.SendMessage(update.Message.Chat.ID, helpMessage)
 }
@@ -3,9 +3,12 @@ package telegram
 import (
 	"birthdayapp/internal/core/domain"
 	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"log/slog"
+	"time"
 )
 
 type Telegram struct {
@@ -24,20 +27,66 @@ func NewTelegramBot(log *slog.Logger, token string) (*Telegram, error) {
 	}, nil
 }
 
-func (t *Telegram) GetInviteLink(chatID int64, birthdayUsernames string) (string, error) {
-	inviteLink, err := t.bot.GetInviteLink(tgbotapi.ChatInviteLinkConfig{
-		ChatConfig: tgbotapi.ChatConfig{
-			ChatID:             chatID,
-			SuperGroupUsername: "Primary Link",
-		},
+// CreateInviteLink creates a link that sends join requests to the chat until expireDate,
+// telegram doesn't allow a member limit on such links so requests are approved by the bot
+func (t *Telegram) CreateInviteLink(chatID int64, name string, expireDate time.Time) (string, error) {
+	//telegram limits the link name to 32 characters
+	if runes := []rune(name); len(runes) > 32 {
+		name = string(runes[:32])
+	}
+
+	resp, err := t.bot.Request(tgbotapi.CreateChatInviteLinkConfig{
+		ChatConfig:         tgbotapi.ChatConfig{ChatID: chatID},
+		Name:               name,
+		ExpireDate:         int(expireDate.Unix()),
+		CreatesJoinRequest: true,
 	})
 	if err != nil {
-		t.log.Debug("error of create join link", "error", err)
-		return inviteLink, fmt.Errorf("error of create invite link: %w", err)
+		t.log.Debug("error of create invite link", "error", err)
+		return "", fmt.Errorf("error of create invite link: %w", err)
+	}
+
+	var inviteLink tgbotapi.ChatInviteLink
+	if err := json.Unmarshal(resp.Result, &inviteLink); err != nil {
+		return "", fmt.Errorf("error of decode invite link: %w", err)
+	}
+	return inviteLink.InviteLink, nil
+}
+
+func (t *Telegram) RevokeInviteLink(chatID int64, link string) error {
+	revokeConfig := tgbotapi.RevokeChatInviteLinkConfig{
+		ChatConfig: tgbotapi.ChatConfig{ChatID: chatID},
+		InviteLink: link,
+	}
+
+	if _, err := t.bot.Request(revokeConfig); err != nil {
+		return fmt.Errorf("error revoke invite link: %w", err)
+	}
+	return nil
+}
+
+func (t *Telegram) ApproveJoinRequest(chatID int64, userID int64) error {
+	approveConfig := tgbotapi.ApproveChatJoinRequestConfig{
+		ChatConfig: tgbotapi.ChatConfig{ChatID: chatID},
+		UserID:     userID,
+	}
+
+	if _, err := t.bot.Request(approveConfig); err != nil {
+		return fmt.Errorf("error approve join request: %w", err)
+	}
+	return nil
+}
+
+func (t *Telegram) DeclineJoinRequest(chatID int64, userID int64) error {
+	declineConfig := tgbotapi.DeclineChatJoinRequest{
+		ChatConfig: tgbotapi.ChatConfig{ChatID: chatID},
+		UserID:     userID,
 	}
 
-	inviteLink = fmt.Sprintf("Join the group to congratulate the birthday for users: %s. Link: %s", birthdayUsernames, inviteLink)
-	return inviteLink, nil
+	if _, err := t.bot.Request(declineConfig); err != nil {
+		return fmt.Errorf("error decline join request: %w", err)
+	}
+	return nil
 }
 
 func (t *Telegram) SendLinkForJoinBirthdayGroup(userID int64, chatID int64, birthdayUsers *[]domain.User) error {
@@ -45,7 +94,7 @@ func (t *Telegram) SendLinkForJoinBirthdayGroup(userID int64, chatID int64, birt
 	t.log.With(slog.String("op", op))
 
 	inviteLink, err := t.bot.GetInviteLink(tgbotapi.ChatInviteLinkConfig{
-		tgbotapi.ChatConfig{
+		ChatConfig: tgbotapi.ChatConfig{
 			ChatID:             chatID,
 			SuperGroupUsername: "Invite Link",
 		},
@@ -77,7 +126,7 @@ func (t *Telegram) KickUser(chatID int64, userID int64) error {
 	}
 
 	if _, err := t.bot.Request(kickConfig); err != nil {
-		return fmt.Errorf("error kick user:%w", err)
+		return fmt.Errorf("error kick user:%w", retryAfter(err))
 	}
 	return nil
 }
@@ -86,15 +135,77 @@ func (t *Telegram) UnBanUser(chatID int64, userID int64) error {
 	op := "Telegram.UnBanUser"
 	t.log.With(slog.String("op", op))
 
+	//without only_if_banned telegram removes a user who is in the chat
 	kickConfig := tgbotapi.UnbanChatMemberConfig{
 		ChatMemberConfig: tgbotapi.ChatMemberConfig{
 			ChatID: chatID,
 			UserID: userID,
 		},
+		OnlyIfBanned: true,
 	}
 
 	if _, err := t.bot.Request(kickConfig); err != nil {
-		return fmt.Errorf("error unBan user: %w", err)
+		return fmt.Errorf("error unBan user: %w", retryAfter(err))
+	}
+	return nil
+}
+
+// GetChatAdministrators returns telegram ids of the chat creator and administrators, bots included
+func (t *Telegram) GetChatAdministrators(chatID int64) ([]int64, error) {
+	admins, err := t.bot.GetChatAdministrators(tgbotapi.ChatAdministratorsConfig{
+		ChatConfig: tgbotapi.ChatConfig{ChatID: chatID},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error get chat administrators: %w", err)
+	}
+
+	adminIDs := make([]int64, 0, len(admins))
+	for _, admin := range admins {
+		if admin.User != nil {
+			adminIDs = append(adminIDs, admin.User.ID)
+		}
+	}
+	return adminIDs, nil
+}
+
+// IsChatMember reports whether the user is in the chat now, restricted users count while they are in the chat
+func (t *Telegram) IsChatMember(chatID int64, userID int64) (bool, error) {
+	member, err := t.bot.GetChatMember(tgbotapi.GetChatMemberConfig{
+		ChatConfigWithUser: tgbotapi.ChatConfigWithUser{ChatID: chatID, UserID: userID},
+	})
+	if err != nil {
+		return false, fmt.Errorf("error get chat member: %w", err)
+	}
+
+	switch member.Status {
+	case "creator", "administrator", "member":
+		return true, nil
+	case "restricted":
+		return member.IsMember, nil
+	default:
+		return false, nil
+	}
+}
+
+// SendPoll posts a single-answer poll and returns the telegram poll identifier with the message id,
+// the poll isn't anonymous since votes come as poll_answer updates only for such polls
+func (t *Telegram) SendPoll(chatID int64, question string, options []string) (string, int, error) {
+	pollConfig := tgbotapi.NewPoll(chatID, question, options...)
+	pollConfig.IsAnonymous = false
+
+	msg, err := t.bot.Send(pollConfig)
+	if err != nil {
+		return "", 0, fmt.Errorf("error send poll: %w", retryAfter(err))
+	}
+	if msg.Poll == nil {
+		return "", 0, errors.New("error send poll: no poll in the message")
+	}
+	return msg.Poll.ID, msg.MessageID, nil
+}
+
+func (t *Telegram) StopPoll(chatID int64, messageID int) error {
+	if _, err := t.bot.Request(tgbotapi.NewStopPoll(chatID, messageID)); err != nil {
+		return fmt.Errorf("error stop poll: %w", err)
 	}
 	return nil
 }
@@ -111,3 +222,12 @@ func (t *Telegram) SendMessage(chatID int64, text string) {
 		t.log.Debug("", "error", err)
 	}
 }
+
+// retryAfter keeps the delay telegram asks for when the bot hits the flood limits
+func retryAfter(err error) error {
+	var apiErr *tgbotapi.Error
+	if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+		return domain.RetryAfterError{After: time.Duration(apiErr.RetryAfter) * time.Second, Err: err}
+	}
+	return err
+}
@@ -7,8 +7,12 @@ import (
 	"birthdayapp/internal/adapters/telegram"
 	"birthdayapp/internal/adapters/telegram/handlers"
 	"birthdayapp/internal/config"
+	"birthdayapp/internal/core/domain"
+	"birthdayapp/internal/core/port"
 	"birthdayapp/internal/core/service"
+	"birthdayapp/internal/scheduler"
 	"context"
+	"fmt"
 	"log/slog"
 	"os"
 	"sync"
@@ -25,6 +29,15 @@ func MustNew(ctx context.Context, log *slog.Logger, cfg config.Config) {
 	op := "App.New"
 	log.With(slog.String("op", op))
 
+	if _, lErr := time.LoadLocation(cfg.DefaultTimezone); lErr != nil {
+		log.Debug("error load default timezone", "error", lErr)
+		panic(lErr)
+	}
+	if lpErr := domain.ValidateLeapDayPolicy(cfg.LeapDayPolicy); lpErr != nil {
+		log.Debug("error leap day policy", "error", lpErr)
+		panic(lpErr)
+	}
+
 	//init db connection
 	dbConnection, cErr := database.NewConnection(&cfg)
 	if cErr != nil {
@@ -54,53 +67,81 @@ func MustNew(ctx context.Context, log *slog.Logger, cfg config.Config) {
 	//dependencies injection
 	userRepo := repository.NewUserRepository(dbConnection)
 	subRepo := repository.NewSubscriptionsRepository(dbConnection)
+	jobRunRepo := repository.NewJobRunRepository(dbConnection)
+	kickRepo := repository.NewKickRepository(dbConnection)
+	celebrationRepo := repository.NewCelebrationRepository(dbConnection)
+	groupRepo := repository.NewGroupRepository(dbConnection)
+	inviteLinkRepo := repository.NewInviteLinkRepository(dbConnection)
+	membershipRepo := repository.NewMembershipRepository(dbConnection)
+	moderationRepo := repository.NewModerationRepository(dbConnection)
+	fundRepo := repository.NewFundRepository(dbConnection)
+	wishlistRepo := repository.NewWishlistRepository(dbConnection)
+	greetingRepo := repository.NewGreetingRepository(dbConnection)
+	pollRepo := repository.NewPollRepository(dbConnection)
 
-	extApi := adapters.NewExternalAPI()
-	birthdayService := service.NewBirthdayService(log, userRepo, tg, &cfg)
-	userService := service.NewUserService(userRepo, extApi)
+	extApi, eaErr := newExternalAPI(log, &cfg)
+	if eaErr != nil {
+		log.Debug("error init external api", "error", eaErr)
+		panic(eaErr)
+	}
+	workCalendar, wcErr := adapters.NewWorkCalendar(cfg.Calendar)
+	if wcErr != nil {
+		log.Debug("error init work calendar", "error", wcErr)
+		panic(wcErr)
+	}
+	moderationService := service.NewModerationService(log, moderationRepo, tg, &cfg)
+	wishlistService := service.NewWishlistService(log, wishlistRepo, userRepo)
+	fundService := service.NewFundService(log, fundRepo, wishlistService, tg, &cfg)
+	cardService := service.NewCardService(log, greetingRepo, userRepo, tg, &cfg)
+	pollService := service.NewPollService(log, pollRepo, fundRepo, tg, &cfg)
+	birthdayService := service.NewBirthdayService(log, userRepo, jobRunRepo, kickRepo, celebrationRepo, groupRepo, inviteLinkRepo, membershipRepo, moderationService, fundService, wishlistService, cardService, tg, workCalendar, &cfg)
+	userService := service.NewUserService(log, userRepo, extApi, &cfg)
+	digestService := service.NewDigestService(log, userRepo, jobRunRepo, tg, &cfg)
 	subService := service.NewSubscriptionService(subRepo)
 
 	subHandler := handlers.NewSubscriptionsHandler(subService, userService)
-	middleware := handlers.NewMiddleware(userRepo)
+	registrationHandler := handlers.NewRegistrationHandler(userService, &cfg)
+	settingsHandler := handlers.NewSettingsHandler(userService, &cfg)
+	groupHandler := handlers.NewGroupHandler(birthdayService)
+	moderationHandler := handlers.NewModerationHandler(moderationService)
+	fundHandler := handlers.NewFundHandler(fundService, userService, &cfg)
+	wishlistHandler := handlers.NewWishlistHandler(wishlistService, userService)
+	cardHandler := handlers.NewCardHandler(cardService, userService)
+	pollHandler := handlers.NewPollHandler(pollService, &cfg)
+	middleware := handlers.NewMiddleware(userRepo, &cfg)
 	tgHandlers := telegram.Handlers{
-		SubscribeHandler: subHandler,
-		Middleware:       middleware,
-	}
-
-	//update fake users
-	if uErr := userService.UpdateUsers(); uErr != nil {
-		log.Debug("error update users", "error", cErr)
-		panic(uErr)
+		SubscribeHandler:    subHandler,
+		RegistrationHandler: registrationHandler,
+		SettingsHandler:     settingsHandler,
+		GroupHandler:        groupHandler,
+		ModerationHandler:   moderationHandler,
+		FundHandler:         fundHandler,
+		WishlistHandler:     wishlistHandler,
+		CardHandler:         cardHandler,
+		PollHandler:         pollHandler,
+		Middleware:          middleware,
 	}
 
 	var wg sync.WaitGroup
 	wg.Add(1)
 	go telegram.NewRouter(ctx, &wg, log, &tgHandlers, tg)
-	go func() {
 
-		//auto check birthdays every day in 8:00AM
-		now := time.Now()
-		nextUpdate := time.Date(now.Year(), now.Month(), now.Day(), 8, 0, 0, 0, now.Location())
-		if now.After(nextUpdate) {
-			nextUpdate = nextUpdate.Add(24 * time.Hour)
-		}
-		timeToNextUpdate := nextUpdate.Sub(now)
-		time.Sleep(timeToNextUpdate)
-
-		//add ticker
-		ticker := time.NewTicker(24 * time.Hour)
-		defer ticker.Stop()
-
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			case <-ticker.C:
-				wg.Add(1)
-				go birthdayService.BirthdayNotify(ctx, &wg)
-			}
-		}
-	}()
+	jobs, sErr := newScheduler(log, &cfg, birthdayService, userService, digestService, moderationService, fundService, pollService)
+	if sErr != nil {
+		log.Debug("error init scheduler", "error", sErr)
+		panic(sErr)
+	}
+
+	//sync users from external api on start, then by schedule
+	wg.Add(1)
+	go userService.SyncUsers(ctx, &wg)
+	//catch up birthdays and reminders missed while the service was down
+	wg.Add(1)
+	go birthdayService.BirthdayNotify(ctx, &wg)
+	wg.Add(1)
+	go birthdayService.RemindUpcoming(ctx, &wg)
+	wg.Add(1)
+	go jobs.Run(ctx, &wg)
 
 	<-ctx.Done()
 	log.Info("server shutting down...")
@@ -109,3 +150,108 @@ func MustNew(ctx context.Context, log *slog.Logger, cfg config.Config) {
 		log.Error("error close connection", "error", ccErr)
 	}
 }
+
+func newScheduler(log *slog.Logger, cfg *config.Config, bs *service.BirthdayService, us *service.UserService, ds *service.DigestService, ms *service.ModerationService, fs *service.FundService, ps *service.PollService) (*scheduler.Scheduler, error) {
+	loc, lErr := time.LoadLocation(cfg.Schedule.Timezone)
+	if lErr != nil {
+		return nil, fmt.Errorf("schedule timezone: %w", lErr)
+	}
+
+	birthdayCheck, pcErr := scheduler.ParseCron(cfg.Schedule.BirthdayCheck, loc)
+	if pcErr != nil {
+		return nil, fmt.Errorf("schedule birthday_check: %w", pcErr)
+	}
+
+	var userSync scheduler.Schedule = scheduler.Every(cfg.UserSync.Interval)
+	if cfg.Schedule.UserSync != "" {
+		userSync, pcErr = scheduler.ParseCron(cfg.Schedule.UserSync, loc)
+		if pcErr != nil {
+			return nil, fmt.Errorf("schedule user_sync: %w", pcErr)
+		}
+	}
+
+	kicks, pcErr := scheduler.ParseCron(cfg.Schedule.Kicks, loc)
+	if pcErr != nil {
+		return nil, fmt.Errorf("schedule kicks: %w", pcErr)
+	}
+
+	celebrations, pcErr := scheduler.ParseCron(cfg.Schedule.Celebrations, loc)
+	if pcErr != nil {
+		return nil, fmt.Errorf("schedule celebrations: %w", pcErr)
+	}
+
+	moderation, pcErr := scheduler.ParseCron(cfg.Schedule.Moderation, loc)
+	if pcErr != nil {
+		return nil, fmt.Errorf("schedule moderation: %w", pcErr)
+	}
+
+	funds, pcErr := scheduler.ParseCron(cfg.Schedule.Funds, loc)
+	if pcErr != nil {
+		return nil, fmt.Errorf("schedule funds: %w", pcErr)
+	}
+
+	polls, pcErr := scheduler.ParseCron(cfg.Schedule.Polls, loc)
+	if pcErr != nil {
+		return nil, fmt.Errorf("schedule polls: %w", pcErr)
+	}
+
+	weeklyDigest, pcErr := scheduler.ParseCron(cfg.Schedule.WeeklyDigest, loc)
+	if pcErr != nil {
+		return nil, fmt.Errorf("schedule weekly_digest: %w", pcErr)
+	}
+	monthlyDigest, pcErr := scheduler.ParseCron(cfg.Schedule.MonthlyDigest, loc)
+	if pcErr != nil {
+		return nil, fmt.Errorf("schedule monthly_digest: %w", pcErr)
+	}
+
+	jobs := scheduler.New(log, scheduler.SystemClock{})
+	jobs.Add("birthday_check", birthdayCheck, bs.BirthdayNotify)
+	jobs.Add("birthday_reminders", birthdayCheck, bs.RemindUpcoming)
+	jobs.Add("kicks", kicks, bs.KickDue)
+	jobs.Add("celebrations", celebrations, bs.CelebrationDue)
+	jobs.Add("moderation", moderation, ms.ModerationDue)
+	jobs.Add("funds", funds, fs.FundDue)
+	jobs.Add("polls", polls, ps.PollDue)
+	jobs.Add("weekly_digest", weeklyDigest, ds.WeeklyDigest)
+	jobs.Add("monthly_digest", monthlyDigest, ds.MonthlyDigest)
+	jobs.Add("user_sync", userSync, us.SyncUsers)
+	return jobs, nil
+}
+
+func newExternalAPI(log *slog.Logger, cfg *config.Config) (port.ExternalAPI, error) {
+	apiConfigs := cfg.ExternalAPIs
+	if len(apiConfigs) == 0 {
+		apiConfigs = []config.ExternalAPI{{Name: "fake", Type: "fake"}}
+	}
+
+	sources := make([]adapters.NamedExternalAPI, 0, len(apiConfigs))
+	for _, apiConfig := range apiConfigs {
+		var api port.ExternalAPI
+		var naErr error
+		switch apiConfig.Type {
+		case "", "fake":
+			api = adapters.NewExternalAPI()
+		case "http":
+			tokenEnv := apiConfig.HTTP.TokenEnv
+			if tokenEnv == "" {
+				tokenEnv = "EXTERNAL_API_TOKEN"
+			}
+			api, naErr = adapters.NewHTTPExternalAPI(apiConfig.HTTP, os.Getenv(tokenEnv))
+		case "file":
+			api, naErr = adapters.NewFileExternalAPI(apiConfig.File)
+		default:
+			naErr = fmt.Errorf("unknown external api type %q", apiConfig.Type)
+		}
+		if naErr != nil {
+			return nil, fmt.Errorf("external api %s: %w", apiConfig.Name, naErr)
+		}
+
+		name := apiConfig.Name
+		if name == "" {
+			name = apiConfig.Type
+		}
+		sources = append(sources, adapters.NamedExternalAPI{Name: name, API: api})
+	}
+
+	return adapters.NewMergedExternalAPI(log, sources, cfg.SourcePrecedence)
+}
@@ -3,8 +3,10 @@ package config
 import (
 	"errors"
 	"flag"
+	"fmt"
 	"github.com/ilyakaznacheev/cleanenv"
 	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
 	"os"
 	"time"
 )
@@ -14,12 +16,128 @@ const (
 )
 
 type Config struct {
-	Env             string        `yaml:"env"`
-	EnvPath         string        `yaml:"env_path"`
-	StoragePath     string        `yaml:"storage_path"`
-	BirthdayGroupID int64         `yaml:"birthday_group_id"`
-	GroupOwnerID    int64         `yaml:"group_owner_id"`
-	TimeToKick      time.Duration `yaml:"time_to_kick"`
+	Env              string        `yaml:"env"`
+	EnvPath          string        `yaml:"env_path"`
+	StoragePath      string        `yaml:"storage_path"`
+	BirthdayGroupID  int64         `yaml:"birthday_group_id"`
+	BirthdayGroupIDs []int64       `yaml:"birthday_group_ids"`
+	ProtectedMembers []int64       `yaml:"protected_members"`
+	TimeToKick       time.Duration `yaml:"time_to_kick"`
+	LeapDayPolicy    string        `yaml:"leap_day_policy" env-default:"feb28"`
+	DefaultTimezone  string        `yaml:"default_timezone" env-default:"UTC"`
+	NotifyHour       int           `yaml:"notify_hour"`
+	CatchUpWindow    time.Duration `yaml:"catch_up_window" env-default:"24h"`
+	ReminderDays     ReminderDays  `yaml:"reminder_days"`
+	GreetingCard     bool          `yaml:"greeting_card"`
+	ExternalAPIs     []ExternalAPI `yaml:"external_apis"`
+	SourcePrecedence []string      `yaml:"source_precedence"`
+	UserSync         UserSync      `yaml:"user_sync"`
+	Schedule         Schedule      `yaml:"schedule"`
+	Admins           []int64       `yaml:"admins"`
+	Registration     Registration  `yaml:"registration"`
+	Digest           Digest        `yaml:"digest"`
+	Calendar         Calendar      `yaml:"calendar"`
+	Surprise         Surprise      `yaml:"surprise"`
+	Moderation       Moderation    `yaml:"moderation"`
+}
+
+// ReminderDays are days before a birthday when subscribers are reminded, "off" or [] turns the reminders off
+type ReminderDays []int
+
+func (rd *ReminderDays) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode && value.Value == "off" {
+		*rd = ReminderDays{}
+		return nil
+	}
+
+	var days []int
+	if dErr := value.Decode(&days); dErr != nil {
+		return fmt.Errorf("reminder_days must be a list of days or off: %w", dErr)
+	}
+	*rd = days
+	return nil
+}
+
+// Moderation configures retries of failed kicks and unbans, actions still failing after retries go to the dead letters
+type Moderation struct {
+	Retries    int           `yaml:"retries" env-default:"5"`
+	Backoff    time.Duration `yaml:"backoff" env-default:"1m"`
+	MaxBackoff time.Duration `yaml:"max_backoff" env-default:"1h"`
+}
+
+// Surprise invites subscribers planning_lead before the reveal, the celebrant joins at reveal_hour of the celebration day,
+// gift polls of the planners end poll_lead before the reveal
+type Surprise struct {
+	Enabled      bool          `yaml:"enabled"`
+	PlanningLead time.Duration `yaml:"planning_lead" env-default:"24h"`
+	RevealHour   int           `yaml:"reveal_hour"`
+	PollLead     time.Duration `yaml:"poll_lead" env-default:"3h"`
+}
+
+// Calendar moves celebrations from weekends and holidays to the previous or the next working day
+type Calendar struct {
+	WeekendShift string `yaml:"weekend_shift" env-default:"none"`
+	HolidaysPath string `yaml:"holidays_path"`
+}
+
+// Digest configures the scheduled digests, the monthly overview is posted only when team_chat_id is set
+type Digest struct {
+	TeamChatID int64 `yaml:"team_chat_id"`
+	WeekDays   int   `yaml:"week_days" env-default:"7"`
+}
+
+// Registration configures the /register command, with approval new users wait for an admin
+type Registration struct {
+	Enabled         bool `yaml:"enabled"`
+	RequireApproval bool `yaml:"require_approval"`
+}
+
+// Schedule holds cron expressions of the background jobs, evaluated on the wall clock of timezone
+type Schedule struct {
+	Timezone      string `yaml:"timezone" env-default:"UTC"`
+	BirthdayCheck string `yaml:"birthday_check" env-default:"0 * * * *"`
+	UserSync      string `yaml:"user_sync"`
+	Kicks         string `yaml:"kicks" env-default:"* * * * *"`
+	Celebrations  string `yaml:"celebrations" env-default:"* * * * *"`
+	Moderation    string `yaml:"moderation" env-default:"* * * * *"`
+	Funds         string `yaml:"funds" env-default:"* * * * *"`
+	Polls         string `yaml:"polls" env-default:"* * * * *"`
+	WeeklyDigest  string `yaml:"weekly_digest" env-default:"0 9 * * 1"`
+	MonthlyDigest string `yaml:"monthly_digest" env-default:"0 9 1 * *"`
+}
+
+// UserSync configures the periodic users sync, in dry-run mode the diff is only logged
+type UserSync struct {
+	Interval   time.Duration `yaml:"interval" env-default:"24h"`
+	Retries    int           `yaml:"retries" env-default:"5"`
+	Backoff    time.Duration `yaml:"backoff" env-default:"1m"`
+	MaxBackoff time.Duration `yaml:"max_backoff" env-default:"30m"`
+	DryRun     bool          `yaml:"dry_run"`
+}
+
+// ExternalAPI selects and configures a named source of users
+type ExternalAPI struct {
+	Name string  `yaml:"name"`
+	Type string  `yaml:"type" env-default:"fake"`
+	HTTP HTTPAPI `yaml:"http"`
+	File FileAPI `yaml:"file"`
+}
+
+// HTTPAPI configures the HR employee-directory client, auth token is read from the token_env variable
+type HTTPAPI struct {
+	URL        string        `yaml:"url"`
+	TokenEnv   string        `yaml:"token_env" env-default:"EXTERNAL_API_TOKEN"`
+	AuthHeader string        `yaml:"auth_header" env-default:"Authorization"`
+	AuthScheme string        `yaml:"auth_scheme" env-default:"Bearer"`
+	PageSize   int           `yaml:"page_size" env-default:"100"`
+	MaxPages   int           `yaml:"max_pages" env-default:"1000"`
+	Timeout    time.Duration `yaml:"timeout" env-default:"10s"`
+}
+
+// FileAPI configures the users file, format is taken from the extension: .csv, .yaml, .yml or .json
+type FileAPI struct {
+	Path        string   `yaml:"path"`
+	DateFormats []string `yaml:"date_formats" env-default:"2006-01-02,02.01.2006,02/01/2006,2006/01/02"`
 }
 
 func LoadConfig() (*Config, error) {
@@ -29,9 +147,8 @@ func LoadConfig() (*Config, error) {
 		return nil, fcpErr
 	}
 
-	var cfg Config
-
-	if rcErr := cleanenv.ReadConfig(configPath, &cfg); rcErr != nil {
+	cfg, rcErr := readConfig(configPath)
+	if rcErr != nil {
 		return nil, rcErr
 	}
 
@@ -40,9 +157,37 @@ func LoadConfig() (*Config, error) {
 		return nil, envLoadErr
 	}
 
+	return cfg, nil
+}
+
+// readConfig reads the config file over defaultConfig, env-default can't keep a false or 0 set in the file
+func readConfig(configPath string) (*Config, error) {
+	cfg := defaultConfig()
+
+	if rcErr := cleanenv.ReadConfig(configPath, &cfg); rcErr != nil {
+		return nil, rcErr
+	}
+
+	if cfg.NotifyHour < 0 || cfg.NotifyHour > 23 {
+		return nil, fmt.Errorf("notify_hour %d is not an hour of the day", cfg.NotifyHour)
+	}
+	if cfg.Surprise.RevealHour < 0 || cfg.Surprise.RevealHour > 23 {
+		return nil, fmt.Errorf("surprise.reveal_hour %d is not an hour of the day", cfg.Surprise.RevealHour)
+	}
+
 	return &cfg, nil
 }
 
+// defaultConfig holds defaults of the fields whose zero value is a valid setting
+func defaultConfig() Config {
+	return Config{
+		NotifyHour:   8,
+		ReminderDays: ReminderDays{7, 1},
+		Registration: Registration{Enabled: true},
+		Surprise:     Surprise{RevealHour: 12},
+	}
+}
+
 // fetchConfigPath return config file path with priority: flag > env > default
 func fetchConfigPath(defaultConfigPath string) string {
 	var configPath string
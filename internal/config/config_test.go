@@ -0,0 +1,117 @@
+package config
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestReadConfig_Registration(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		enabled bool
+	}{
+		{name: "enabled by default", content: "env: test\n", enabled: true},
+		{name: "enabled", content: "registration:\n  enabled: true\n", enabled: true},
+		{name: "disabled", content: "registration:\n  enabled: false\n", enabled: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := readConfig(writeConfig(t, tt.content))
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, tt.enabled, cfg.Registration.Enabled)
+		})
+	}
+}
+
+func TestReadConfig_NotifyHour(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		hour    int
+		wantErr bool
+	}{
+		{name: "default", content: "env: test\n", hour: 8},
+		{name: "midnight", content: "notify_hour: 0\n", hour: 0},
+		{name: "evening", content: "notify_hour: 20\n", hour: 20},
+		{name: "out of day", content: "notify_hour: 24\n", wantErr: true},
+		{name: "negative", content: "notify_hour: -1\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := readConfig(writeConfig(t, tt.content))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, tt.hour, cfg.NotifyHour)
+		})
+	}
+}
+
+func TestReadConfig_RevealHour(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		hour    int
+		wantErr bool
+	}{
+		{name: "default", content: "surprise:\n  enabled: true\n", hour: 12},
+		{name: "midnight", content: "surprise:\n  reveal_hour: 0\n", hour: 0},
+		{name: "out of day", content: "surprise:\n  reveal_hour: 24\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := readConfig(writeConfig(t, tt.content))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, tt.hour, cfg.Surprise.RevealHour)
+		})
+	}
+}
+
+func TestReadConfig_ReminderDays(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		days    ReminderDays
+		wantErr bool
+	}{
+		{name: "default", content: "env: test\n", days: ReminderDays{7, 1}},
+		{name: "custom", content: "reminder_days: [3]\n", days: ReminderDays{3}},
+		{name: "off", content: "reminder_days: off\n", days: ReminderDays{}},
+		{name: "empty", content: "reminder_days: []\n", days: ReminderDays{}},
+		{name: "invalid", content: "reminder_days: soon\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := readConfig(writeConfig(t, tt.content))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, tt.days, cfg.ReminderDays)
+		})
+	}
+}
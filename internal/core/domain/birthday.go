@@ -0,0 +1,90 @@
+package domain
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Leap day policies for people born on Feb 29 in non-leap years
+const (
+	LeapDayFeb28 = "feb28"
+	LeapDayMar1  = "mar1"
+)
+
+// ValidateLeapDayPolicy rejects unknown policies, an empty one means feb28
+func ValidateLeapDayPolicy(policy string) error {
+	switch policy {
+	case "", LeapDayFeb28, LeapDayMar1:
+		return nil
+	default:
+		return fmt.Errorf("unknown leap day policy %q, expected feb28 or mar1: %w", policy, ErrValidation)
+	}
+}
+
+// CelebrationDate returns the date the birthday is celebrated in the year, in loc
+func CelebrationDate(birthday time.Time, year int, policy string, loc *time.Location) time.Time {
+	month, day := birthday.Month(), birthday.Day()
+	if month == time.February && day == 29 && !isLeapYear(year) {
+		if policy == LeapDayMar1 {
+			month, day = time.March, 1
+		} else {
+			day = 28
+		}
+	}
+	return time.Date(year, month, day, 0, 0, 0, 0, loc)
+}
+
+// NextCelebrationDate returns the first celebration of the birthday on or after the date
+func NextCelebrationDate(birthday time.Time, date time.Time, policy string) time.Time {
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+	next := CelebrationDate(birthday, day.Year(), policy, day.Location())
+	if next.Before(day) {
+		next = CelebrationDate(birthday, day.Year()+1, policy, day.Location())
+	}
+	return next
+}
+
+// UpcomingBirthday is a user celebrating on the date
+type UpcomingBirthday struct {
+	User User
+	Date time.Time
+}
+
+// UpcomingBirthdays returns users celebrating within the days starting from the date, ordered by date and username
+func UpcomingBirthdays(users []User, from time.Time, days int, policy string) []UpcomingBirthday {
+	to := time.Date(from.Year(), from.Month(), from.Day()+days, 0, 0, 0, 0, from.Location())
+	var upcoming []UpcomingBirthday
+	for _, user := range users {
+		date := NextCelebrationDate(user.Birthday, from, policy)
+		if date.Before(to) {
+			upcoming = append(upcoming, UpcomingBirthday{User: user, Date: date})
+		}
+	}
+	sort.SliceStable(upcoming, func(i, j int) bool {
+		if !upcoming[i].Date.Equal(upcoming[j].Date) {
+			return upcoming[i].Date.Before(upcoming[j].Date)
+		}
+		return upcoming[i].User.Username < upcoming[j].User.Username
+	})
+	return upcoming
+}
+
+// BirthdayMonthDays returns "MM-DD" of birthdays celebrated on the date
+func BirthdayMonthDays(date time.Time, policy string) []string {
+	monthDays := []string{date.Format("01-02")}
+	if isLeapYear(date.Year()) {
+		return monthDays
+	}
+	switch {
+	case policy == LeapDayMar1 && date.Month() == time.March && date.Day() == 1:
+		monthDays = append(monthDays, "02-29")
+	case policy != LeapDayMar1 && date.Month() == time.February && date.Day() == 28:
+		monthDays = append(monthDays, "02-29")
+	}
+	return monthDays
+}
+
+func isLeapYear(year int) bool {
+	return year%4 == 0 && (year%100 != 0 || year%400 == 0)
+}
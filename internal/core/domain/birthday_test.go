@@ -0,0 +1,91 @@
+package domain
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestBirthdayMonthDays(t *testing.T) {
+	tests := []struct {
+		date     time.Time
+		policy   string
+		expected []string
+	}{
+		{time.Date(2023, 2, 28, 0, 0, 0, 0, time.UTC), LeapDayFeb28, []string{"02-28", "02-29"}},
+		{time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC), LeapDayFeb28, []string{"03-01"}},
+		{time.Date(2023, 2, 28, 0, 0, 0, 0, time.UTC), LeapDayMar1, []string{"02-28"}},
+		{time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC), LeapDayMar1, []string{"03-01", "02-29"}},
+		{time.Date(2024, 2, 28, 0, 0, 0, 0, time.UTC), LeapDayFeb28, []string{"02-28"}},
+		{time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC), LeapDayFeb28, []string{"02-29"}},
+		{time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), LeapDayMar1, []string{"03-01"}},
+		{time.Date(2100, 2, 28, 0, 0, 0, 0, time.UTC), LeapDayFeb28, []string{"02-28", "02-29"}},
+		{time.Date(2100, 3, 1, 0, 0, 0, 0, time.UTC), LeapDayMar1, []string{"03-01", "02-29"}},
+		{time.Date(2000, 2, 29, 0, 0, 0, 0, time.UTC), LeapDayMar1, []string{"02-29"}},
+		{time.Date(2000, 3, 1, 0, 0, 0, 0, time.UTC), LeapDayMar1, []string{"03-01"}},
+		{time.Date(2025, 6, 21, 0, 0, 0, 0, time.UTC), LeapDayFeb28, []string{"06-21"}},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, BirthdayMonthDays(tt.date, tt.policy), "%s %s", tt.date.Format(time.DateOnly), tt.policy)
+	}
+}
+
+func TestCelebrationDate_LeapDay(t *testing.T) {
+	birthday := time.Date(2000, 2, 29, 0, 0, 0, 0, time.UTC)
+
+	for year := 2021; year <= 2029; year++ {
+		feb28 := CelebrationDate(birthday, year, LeapDayFeb28, time.UTC)
+		mar1 := CelebrationDate(birthday, year, LeapDayMar1, time.UTC)
+		if isLeapYear(year) {
+			assert.Equal(t, time.Date(year, 2, 29, 0, 0, 0, 0, time.UTC), feb28)
+			assert.Equal(t, time.Date(year, 2, 29, 0, 0, 0, 0, time.UTC), mar1)
+			continue
+		}
+		assert.Equal(t, time.Date(year, 2, 28, 0, 0, 0, 0, time.UTC), feb28)
+		assert.Equal(t, time.Date(year, 3, 1, 0, 0, 0, 0, time.UTC), mar1)
+	}
+
+	assert.Equal(t, time.Date(1900, 2, 28, 0, 0, 0, 0, time.UTC), CelebrationDate(birthday, 1900, LeapDayFeb28, time.UTC))
+	assert.Equal(t, time.Date(2000, 2, 29, 0, 0, 0, 0, time.UTC), CelebrationDate(birthday, 2000, LeapDayFeb28, time.UTC))
+}
+
+func TestNextCelebrationDate(t *testing.T) {
+	birthday := time.Date(2000, 2, 29, 0, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC),
+		NextCelebrationDate(birthday, time.Date(2023, 2, 1, 10, 0, 0, 0, time.UTC), LeapDayMar1))
+	assert.Equal(t, time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC),
+		NextCelebrationDate(birthday, time.Date(2023, 3, 2, 0, 0, 0, 0, time.UTC), LeapDayMar1))
+	assert.Equal(t, time.Date(2023, 2, 28, 0, 0, 0, 0, time.UTC),
+		NextCelebrationDate(birthday, time.Date(2023, 2, 28, 23, 0, 0, 0, time.UTC), LeapDayFeb28))
+	assert.Equal(t, time.Date(2025, 2, 28, 0, 0, 0, 0, time.UTC),
+		NextCelebrationDate(birthday, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), LeapDayFeb28))
+}
+
+func TestUpcomingBirthdays(t *testing.T) {
+	users := []User{
+		{Username: "late", Birthday: time.Date(1990, 5, 16, 0, 0, 0, 0, time.UTC)},
+		{Username: "b", Birthday: time.Date(1991, 5, 10, 0, 0, 0, 0, time.UTC)},
+		{Username: "a", Birthday: time.Date(1992, 5, 10, 0, 0, 0, 0, time.UTC)},
+		{Username: "outside", Birthday: time.Date(1993, 5, 17, 0, 0, 0, 0, time.UTC)},
+		{Username: "passed", Birthday: time.Date(1994, 5, 9, 0, 0, 0, 0, time.UTC)},
+	}
+	from := time.Date(2024, 5, 10, 9, 0, 0, 0, time.UTC)
+
+	upcoming := UpcomingBirthdays(users, from, 7, LeapDayFeb28)
+
+	var usernames []string
+	for _, birthday := range upcoming {
+		usernames = append(usernames, birthday.User.Username)
+	}
+	assert.Equal(t, []string{"a", "b", "late"}, usernames)
+	assert.Equal(t, time.Date(2024, 5, 16, 0, 0, 0, 0, time.UTC), upcoming[2].Date)
+}
+
+func TestValidateLeapDayPolicy(t *testing.T) {
+	assert.NoError(t, ValidateLeapDayPolicy(LeapDayFeb28))
+	assert.NoError(t, ValidateLeapDayPolicy(LeapDayMar1))
+	assert.NoError(t, ValidateLeapDayPolicy(""))
+	assert.ErrorIs(t, ValidateLeapDayPolicy("mar01"), ErrValidation)
+}
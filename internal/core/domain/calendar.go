@@ -0,0 +1,79 @@
+package domain
+
+import "time"
+
+// Shift policies for celebrations falling on weekends and holidays
+const (
+	ShiftNone     = "none"
+	ShiftPrevious = "previous" // to the previous working day, Friday for weekends
+	ShiftNext     = "next"     // to the next working day, Monday for weekends
+)
+
+// maxShiftDays bounds the search of a working day through long holidays
+const maxShiftDays = 31
+
+// WorkCalendar moves celebrations from weekends and holidays to working days, nil calendar doesn't move them
+type WorkCalendar struct {
+	Shift    string
+	Holidays map[string]bool // "YYYY-MM-DD" for a single date, "MM-DD" for every year
+}
+
+// IsWorkingDay reports whether the date is neither a weekend nor a holiday
+func (c *WorkCalendar) IsWorkingDay(date time.Time) bool {
+	if c == nil {
+		return true
+	}
+	if date.Weekday() == time.Saturday || date.Weekday() == time.Sunday {
+		return false
+	}
+	return !c.Holidays[date.Format(time.DateOnly)] && !c.Holidays[date.Format("01-02")]
+}
+
+// CelebrationDay returns the day when a birthday on the date is celebrated
+func (c *WorkCalendar) CelebrationDay(date time.Time) time.Time {
+	step := c.step()
+	if step == 0 {
+		return date
+	}
+	day := date
+	for i := 0; i < maxShiftDays && !c.IsWorkingDay(day); i++ {
+		day = day.AddDate(0, 0, step)
+	}
+	return day
+}
+
+// CelebratedOn returns birthday dates celebrated on the day, the day itself first
+func (c *WorkCalendar) CelebratedOn(day time.Time) []time.Time {
+	step := c.step()
+	if step == 0 {
+		return []time.Time{day}
+	}
+	if !c.IsWorkingDay(day) {
+		return nil
+	}
+
+	dates := []time.Time{day}
+	//birthdays moved to the day come from the non-working days after it for "previous" and before it for "next"
+	for i := 1; i <= maxShiftDays; i++ {
+		date := day.AddDate(0, 0, -step*i)
+		if c.IsWorkingDay(date) {
+			break
+		}
+		dates = append(dates, date)
+	}
+	return dates
+}
+
+func (c *WorkCalendar) step() int {
+	if c == nil {
+		return 0
+	}
+	switch c.Shift {
+	case ShiftPrevious:
+		return -1
+	case ShiftNext:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,70 @@
+package domain
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func calendarDate(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 8, 0, 0, 0, time.UTC)
+}
+
+func TestWorkCalendar_CelebrationDay(t *testing.T) {
+	holidays := map[string]bool{"2024-05-09": true, "01-01": true}
+
+	tests := []struct {
+		name     string
+		shift    string
+		date     time.Time
+		expected time.Time
+	}{
+		{"working day", ShiftPrevious, calendarDate(2024, 5, 14), calendarDate(2024, 5, 14)},
+		{"saturday to friday", ShiftPrevious, calendarDate(2024, 5, 18), calendarDate(2024, 5, 17)},
+		{"sunday to friday", ShiftPrevious, calendarDate(2024, 5, 19), calendarDate(2024, 5, 17)},
+		{"saturday to monday", ShiftNext, calendarDate(2024, 5, 18), calendarDate(2024, 5, 20)},
+		{"sunday to monday", ShiftNext, calendarDate(2024, 5, 19), calendarDate(2024, 5, 20)},
+		{"holiday to previous", ShiftPrevious, calendarDate(2024, 5, 9), calendarDate(2024, 5, 8)},
+		{"yearly holiday to next", ShiftNext, calendarDate(2025, 1, 1), calendarDate(2025, 1, 2)},
+		{"holiday before weekend", ShiftNext, calendarDate(2024, 5, 10), calendarDate(2024, 5, 10)},
+		{"without shift", ShiftNone, calendarDate(2024, 5, 18), calendarDate(2024, 5, 18)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calendar := &WorkCalendar{Shift: tt.shift, Holidays: holidays}
+			assert.Equal(t, tt.expected, calendar.CelebrationDay(tt.date))
+		})
+	}
+}
+
+func TestWorkCalendar_CelebratedOn(t *testing.T) {
+	holidays := map[string]bool{"2024-05-09": true, "2024-05-10": true}
+
+	tests := []struct {
+		name     string
+		shift    string
+		day      time.Time
+		expected []time.Time
+	}{
+		{"friday takes weekend", ShiftPrevious, calendarDate(2024, 5, 17), []time.Time{calendarDate(2024, 5, 17), calendarDate(2024, 5, 18), calendarDate(2024, 5, 19)}},
+		{"monday takes weekend", ShiftNext, calendarDate(2024, 5, 20), []time.Time{calendarDate(2024, 5, 20), calendarDate(2024, 5, 19), calendarDate(2024, 5, 18)}},
+		{"monday takes holidays and weekend", ShiftNext, calendarDate(2024, 5, 13), []time.Time{calendarDate(2024, 5, 13), calendarDate(2024, 5, 12), calendarDate(2024, 5, 11), calendarDate(2024, 5, 10), calendarDate(2024, 5, 9)}},
+		{"midweek", ShiftNext, calendarDate(2024, 5, 15), []time.Time{calendarDate(2024, 5, 15)}},
+		{"weekend celebrates nobody", ShiftPrevious, calendarDate(2024, 5, 18), nil},
+		{"without shift", ShiftNone, calendarDate(2024, 5, 18), []time.Time{calendarDate(2024, 5, 18)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calendar := &WorkCalendar{Shift: tt.shift, Holidays: holidays}
+			assert.Equal(t, tt.expected, calendar.CelebratedOn(tt.day))
+		})
+	}
+}
+
+func TestWorkCalendar_Nil(t *testing.T) {
+	var calendar *WorkCalendar
+	assert.True(t, calendar.IsWorkingDay(calendarDate(2024, 5, 18)))
+	assert.Equal(t, []time.Time{calendarDate(2024, 5, 18)}, calendar.CelebratedOn(calendarDate(2024, 5, 18)))
+}
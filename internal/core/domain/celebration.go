@@ -0,0 +1,16 @@
+package domain
+
+import "time"
+
+// Celebration is a surprise birthday of a user: subscribers are invited to plan at PlanAt,
+// the celebrant is invited at RevealAt
+type Celebration struct {
+	ID         int
+	TelegramID int64
+	ChatID     int64     // group allocated at planning, 0 before it
+	Date       time.Time // birthday date, differs from the reveal day when moved from a weekend or a holiday
+	Timezone   string
+	PlanAt     time.Time
+	RevealAt   time.Time
+	Planned    bool
+}
@@ -1,7 +1,63 @@
 package domain
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
 
 var ErrAlreadyExist = errors.New("already exists")
 var ErrNotFound = errors.New("not found")
 var ErrUserRecursion = errors.New("user recursion")
+var ErrValidation = errors.New("validation error")
+var ErrNotApproved = errors.New("waiting for approval")
+var ErrForbidden = errors.New("forbidden")
+var ErrClosed = errors.New("closed")
+
+// RetryAfterError is returned when the request may be repeated only after the delay
+type RetryAfterError struct {
+	After time.Duration
+	Err   error
+}
+
+func (re RetryAfterError) Error() string {
+	return fmt.Sprintf("retry after %s: %v", re.After, re.Err)
+}
+
+func (re RetryAfterError) Unwrap() error {
+	return re.Err
+}
+
+// RowError describes a single source record rejected by validation
+type RowError struct {
+	Row int
+	Err error
+}
+
+func (re RowError) Error() string {
+	return fmt.Sprintf("row %d: %v", re.Row, re.Err)
+}
+
+func (re RowError) Unwrap() error {
+	return re.Err
+}
+
+// RowErrors is returned together with the valid users when some source records were rejected
+type RowErrors []RowError
+
+func (re RowErrors) Error() string {
+	messages := make([]string, len(re))
+	for i, rowErr := range re {
+		messages[i] = rowErr.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (re RowErrors) Unwrap() []error {
+	errs := make([]error, len(re))
+	for i, rowErr := range re {
+		errs[i] = rowErr
+	}
+	return errs
+}
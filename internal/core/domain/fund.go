@@ -0,0 +1,62 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Fund collects gift money of a celebration, members pledge until PledgesUntil and the fund closes at EndsAt,
+// the first member who pledges organizes the fund, wishlist items of CelebrantIDs claimed for the gift are given when it closes
+type Fund struct {
+	ID           int
+	ChatID       int64
+	Celebrants   string
+	CelebrantIDs []int64
+	OrganizerID  int64
+	PledgesUntil time.Time
+	EndsAt       time.Time
+	Timezone     string
+	Closed       bool
+}
+
+// Contribution is a ledger entry of a fund member, Amount is zero until the member pledges
+type Contribution struct {
+	ID         int
+	FundID     int
+	TelegramID int64
+	Username   string
+	Amount     int64
+	Paid       bool
+}
+
+// FundSummary describes the total of the fund, who paid, who hasn't paid and who hasn't pledged
+func FundSummary(fund *Fund, contributions []Contribution) string {
+	var pledged, paid int64
+	var paidList, unpaidList, noPledgeList []string
+	for _, contribution := range contributions {
+		switch {
+		case contribution.Amount == 0:
+			noPledgeList = append(noPledgeList, fmt.Sprintf("@%s", contribution.Username))
+		case contribution.Paid:
+			pledged += contribution.Amount
+			paid += contribution.Amount
+			paidList = append(paidList, fmt.Sprintf("@%s %d", contribution.Username, contribution.Amount))
+		default:
+			pledged += contribution.Amount
+			unpaidList = append(unpaidList, fmt.Sprintf("@%s %d", contribution.Username, contribution.Amount))
+		}
+	}
+
+	summary := fmt.Sprintf("gift fund #%d for %s: pledged %d, paid %d", fund.ID, fund.Celebrants, pledged, paid)
+	if len(paidList) > 0 {
+		summary += "\npaid: " + strings.Join(paidList, ", ")
+	}
+	if len(unpaidList) > 0 {
+		summary += "\nnot paid: " + strings.Join(unpaidList, ", ")
+	}
+	if len(noPledgeList) > 0 {
+		summary += "\nno pledge: " + strings.Join(noPledgeList, ", ")
+	}
+	return summary
+}
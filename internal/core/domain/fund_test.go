@@ -0,0 +1,20 @@
+package domain
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestFundSummary(t *testing.T) {
+	fund := &Fund{ID: 3, Celebrants: "@user1"}
+	contributions := []Contribution{
+		{Username: "sub1", Amount: 500, Paid: true},
+		{Username: "sub2", Amount: 300},
+		{Username: "sub3"},
+		{Username: "sub4", Amount: 200, Paid: true},
+	}
+
+	assert.Equal(t, "gift fund #3 for @user1: pledged 1000, paid 700\npaid: @sub1 500, @sub4 200\nnot paid: @sub2 300\nno pledge: @sub3",
+		FundSummary(fund, contributions))
+	assert.Equal(t, "gift fund #3 for @user1: pledged 0, paid 0", FundSummary(fund, nil))
+}
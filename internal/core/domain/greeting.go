@@ -0,0 +1,38 @@
+package domain
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+)
+
+// Greeting is a subscriber's part of the greeting card of a celebrant, Text is empty until the subscriber replies,
+// the card is delivered at DeliverAt and late greetings go to the celebrant separately until ExpiresAt
+type Greeting struct {
+	ID                int
+	ChatID            int64
+	CelebrantID       int64
+	CelebrantUsername string
+	TelegramID        int64
+	Username          string
+	Text              string
+	DeliverAt         time.Time
+	ExpiresAt         time.Time
+	Delivered         bool
+}
+
+// GreetingCard compiles greetings of a card into a message, greetings not sent yet are skipped
+// and an empty string means nothing to deliver
+func GreetingCard(greetings []Greeting) string {
+	var card bytes.Buffer
+	for _, greeting := range greetings {
+		if greeting.Text == "" {
+			continue
+		}
+		if card.Len() == 0 {
+			card.WriteString(fmt.Sprintf("greeting card for @%s:", greeting.CelebrantUsername))
+		}
+		card.WriteString(fmt.Sprintf("\n@%s: %s", greeting.Username, greeting.Text))
+	}
+	return card.String()
+}
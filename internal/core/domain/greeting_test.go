@@ -0,0 +1,22 @@
+package domain
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestGreetingCard(t *testing.T) {
+	greetings := []Greeting{
+		{CelebrantUsername: "user1", Username: "user2", Text: "happy birthday"},
+		{CelebrantUsername: "user1", Username: "user3"},
+		{CelebrantUsername: "user1", Username: "user4", Text: "all the best"},
+	}
+
+	assert.Equal(t, "greeting card for @user1:\n@user2: happy birthday\n@user4: all the best", GreetingCard(greetings))
+}
+
+func TestGreetingCard_NoGreetings(t *testing.T) {
+	greetings := []Greeting{{CelebrantUsername: "user1", Username: "user2"}}
+
+	assert.Equal(t, "", GreetingCard(greetings))
+}
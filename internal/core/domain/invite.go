@@ -0,0 +1,12 @@
+package domain
+
+import "time"
+
+// InviteLink is a join-request link of a user to a birthday group, revoked when the celebration ends
+type InviteLink struct {
+	ID         int
+	ChatID     int64
+	TelegramID int64
+	Link       string
+	ExpiresAt  time.Time
+}
@@ -0,0 +1,19 @@
+package domain
+
+import "time"
+
+const (
+	JobBirthdayNotify   = "birthday_notify"
+	JobBirthdayReminder = "birthday_reminder"
+	JobWeeklyDigest     = "weekly_digest"
+	JobMonthlyDigest    = "monthly_digest"
+)
+
+// JobRun marks a date of a job as processed in a timezone, a date is claimed only once
+type JobRun struct {
+	ID        int
+	Job       string
+	Date      time.Time
+	Timezone  string
+	StartedAt time.Time
+}
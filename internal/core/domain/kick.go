@@ -0,0 +1,11 @@
+package domain
+
+import "time"
+
+// Kick is a pending removal of a user from a chat after the celebration
+type Kick struct {
+	ID         int
+	ChatID     int64
+	TelegramID int64
+	DueAt      time.Time
+}
@@ -0,0 +1,12 @@
+package domain
+
+import "time"
+
+// Membership is a stay of a user in a birthday group, LeftAt is zero while the user is in the group
+type Membership struct {
+	ID         int
+	ChatID     int64
+	TelegramID int64
+	JoinedAt   time.Time
+	LeftAt     time.Time
+}
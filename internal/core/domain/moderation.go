@@ -0,0 +1,22 @@
+package domain
+
+import "time"
+
+// Moderation actions on members of birthday groups
+const (
+	ActionKick  = "kick"
+	ActionUnban = "unban"
+)
+
+// ModerationAction is a failed kick or unban of a user waiting for a retry,
+// an action that failed every retry is dead and waits for an admin
+type ModerationAction struct {
+	ID            int
+	Action        string
+	ChatID        int64
+	TelegramID    int64
+	Attempts      int
+	NextAttemptAt time.Time
+	LastError     string
+	Dead          bool
+}
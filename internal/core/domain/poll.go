@@ -0,0 +1,51 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Poll is the gift-choice poll of a fund posted in the group of the celebration, PollID is the telegram poll identifier
+type Poll struct {
+	ID         int
+	FundID     int
+	ChatID     int64
+	MessageID  int
+	PollID     string
+	Celebrants string
+	Options    []PollOption
+	EndsAt     time.Time
+	Closed     bool
+}
+
+// PollOption is an answer of a poll, ID is its 0-based position in the telegram poll
+type PollOption struct {
+	ID    int
+	Text  string
+	Votes int
+}
+
+// PollResult announces the options with the most votes, several options win in a tie
+func PollResult(poll *Poll, options []PollOption) string {
+	var votes int
+	var winners []string
+	for _, option := range options {
+		switch {
+		case option.Votes > votes:
+			votes = option.Votes
+			winners = []string{fmt.Sprintf("%q", option.Text)}
+		case option.Votes == votes && votes > 0:
+			winners = append(winners, fmt.Sprintf("%q", option.Text))
+		}
+	}
+
+	switch len(winners) {
+	case 0:
+		return fmt.Sprintf("the gift poll for %s is over without votes", poll.Celebrants)
+	case 1:
+		return fmt.Sprintf("the gift poll for %s is over, %s wins with %d votes", poll.Celebrants, winners[0], votes)
+	default:
+		return fmt.Sprintf("the gift poll for %s is over, a tie between %s with %d votes each", poll.Celebrants, strings.Join(winners, ", "), votes)
+	}
+}
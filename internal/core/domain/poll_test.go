@@ -0,0 +1,25 @@
+package domain
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestPollResult(t *testing.T) {
+	poll := &Poll{Celebrants: "@user1"}
+	tests := []struct {
+		name    string
+		options []PollOption
+		result  string
+	}{
+		{name: "winner", options: []PollOption{{Text: "book", Votes: 1}, {Text: "pen", Votes: 3}}, result: "the gift poll for @user1 is over, \"pen\" wins with 3 votes"},
+		{name: "tie", options: []PollOption{{Text: "book", Votes: 2}, {Text: "pen", Votes: 2}, {Text: "cup", Votes: 1}}, result: "the gift poll for @user1 is over, a tie between \"book\", \"pen\" with 2 votes each"},
+		{name: "no votes", options: []PollOption{{Text: "book"}, {Text: "pen"}}, result: "the gift poll for @user1 is over without votes"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.result, PollResult(poll, tt.options))
+		})
+	}
+}
@@ -2,10 +2,56 @@ package domain
 
 import "time"
 
+// SourceSelf is the source name of fields entered by the user with /register
+const SourceSelf = "self"
+
+// MaxReminderDays limits how many days before a birthday a reminder can be sent
+const MaxReminderDays = 30
+
 type User struct {
 	ID             int
 	Username       string
 	TelegramID     int64
 	Birthday       time.Time
 	NotifyBirthday bool
+	Active         bool
+	Sources        UserSources
+	Timezone       string // IANA zone name, empty for the configured default zone
+	ReminderDays   []int  // days before subscribed birthdays to remind, nil for the configured default, empty for none
+	DigestOptOut   bool   // the user doesn't get the weekly digest
+}
+
+// RemindersOn returns the user reminder days or the default ones if the user didn't set them
+func (u *User) RemindersOn(defaultDays []int) []int {
+	if u.ReminderDays == nil {
+		return defaultDays
+	}
+	return u.ReminderDays
+}
+
+// UserSources holds the name of the source each user field came from
+type UserSources struct {
+	TelegramID string
+	Username   string
+	Birthday   string
+}
+
+// IsSelfRegistered reports whether the user was created with /register instead of an external source
+func (u *User) IsSelfRegistered() bool {
+	return u.Sources.TelegramID == SourceSelf
+}
+
+// UserSyncDiff is the set of changes needed to bring the users table in line with the external source
+type UserSyncDiff struct {
+	ToAdd        []User
+	ToUpdate     []User
+	ToDeactivate []User
+}
+
+// SyncSummary reports the result of a users sync
+type SyncSummary struct {
+	Added       int
+	Updated     int
+	Deactivated int
+	Errors      []error
 }
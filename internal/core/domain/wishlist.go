@@ -0,0 +1,42 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+)
+
+// WishlistItem is a gift the user wishes, ClaimedBy is the telegram id of the subscriber buying it and zero while nobody is
+type WishlistItem struct {
+	ID         int
+	TelegramID int64
+	Title      string
+	ClaimedBy  int64
+}
+
+// WishlistMessage lists wishlists of the celebrants for the recipient, the own wishlist of a celebrant is never shown
+// so celebrants don't learn who is buying what
+func WishlistMessage(recipientID int64, celebrants []User, wishlists map[int64][]WishlistItem) string {
+	var message strings.Builder
+	for _, celebrant := range celebrants {
+		items := wishlists[celebrant.TelegramID]
+		if celebrant.TelegramID == recipientID || len(items) == 0 {
+			continue
+		}
+		message.WriteString(fmt.Sprintf("\nwishlist of @%s:", celebrant.Username))
+		for _, item := range items {
+			message.WriteString(fmt.Sprintf("\n#%d %s", item.ID, item.Title))
+			switch item.ClaimedBy {
+			case 0:
+			case recipientID:
+				message.WriteString(" (claimed by you)")
+			default:
+				message.WriteString(" (claimed)")
+			}
+		}
+	}
+	if message.Len() == 0 {
+		return ""
+	}
+	message.WriteString("\nsend /wishlist claim \"number\" to buy a gift")
+	return message.String()
+}
@@ -0,0 +1,21 @@
+package domain
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestWishlistMessage(t *testing.T) {
+	celebrants := []User{{Username: "user1", TelegramID: 1}, {Username: "user2", TelegramID: 2}}
+	wishlists := map[int64][]WishlistItem{
+		1: {{ID: 1, TelegramID: 1, Title: "book", ClaimedBy: 3}},
+		2: {{ID: 2, TelegramID: 2, Title: "pen", ClaimedBy: 1}},
+	}
+
+	//a celebrant sees wishlists of the other celebrants only
+	assert.Equal(t, "\nwishlist of @user2:\n#2 pen (claimed by you)\nsend /wishlist claim \"number\" to buy a gift",
+		WishlistMessage(1, celebrants, wishlists))
+	assert.Equal(t, "\nwishlist of @user1:\n#1 book (claimed by you)\nwishlist of @user2:\n#2 pen (claimed)\nsend /wishlist claim \"number\" to buy a gift",
+		WishlistMessage(3, celebrants, wishlists))
+	assert.Equal(t, "", WishlistMessage(2, celebrants[1:], wishlists))
+}
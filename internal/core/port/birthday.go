@@ -1,12 +1,21 @@
 package port
 
 import (
+	"birthdayapp/internal/core/domain"
 	"context"
 	"sync"
+	"time"
 )
 
 //go:generate mockgen -source=./birthday.go -destination=mock/birthday.go -package=mock
 
 type Birthday interface {
 	BirthdayNotify(ctx context.Context, wg *sync.WaitGroup)
+	RemindUpcoming(ctx context.Context, wg *sync.WaitGroup)
+	KickDue(ctx context.Context, wg *sync.WaitGroup)
+	CelebrationDue(ctx context.Context, wg *sync.WaitGroup)
+	IsExpectedMember(chatID int64, telegramID int64) (bool, error)
+	MemberJoined(chatID int64, telegramID int64, at time.Time) error
+	MemberLeft(chatID int64, telegramID int64, at time.Time) error
+	Attendance(chatID int64, from time.Time, to time.Time) ([]domain.Membership, error)
 }
@@ -0,0 +1,15 @@
+package port
+
+import (
+	"birthdayapp/internal/core/domain"
+	"time"
+)
+
+//go:generate mockgen -source=./celebration.go -destination=mock/celebration.go -package=mock
+
+type CelebrationRepo interface {
+	ScheduleCelebration(celebration *domain.Celebration) error
+	GetDueCelebrations(now time.Time) (*[]domain.Celebration, error)
+	MarkCelebrationPlanned(celebration *domain.Celebration) error
+	DeleteCelebration(celebration *domain.Celebration) error
+}
@@ -0,0 +1,13 @@
+package port
+
+import (
+	"context"
+	"sync"
+)
+
+//go:generate mockgen -source=./digest.go -destination=mock/digest.go -package=mock
+
+type Digest interface {
+	WeeklyDigest(ctx context.Context, wg *sync.WaitGroup)
+	MonthlyDigest(ctx context.Context, wg *sync.WaitGroup)
+}
@@ -2,6 +2,8 @@ package port
 
 import "birthdayapp/internal/core/domain"
 
+//go:generate mockgen -source=./external-database.go -destination=mock/external-database.go -package=mock
+
 type ExternalAPI interface {
 	GetUsers() (*[]domain.User, error)
 }
@@ -0,0 +1,32 @@
+package port
+
+import (
+	"birthdayapp/internal/core/domain"
+	"context"
+	"sync"
+	"time"
+)
+
+//go:generate mockgen -source=./fund.go -destination=mock/fund.go -package=mock
+
+type FundRepo interface {
+	CreateFund(fund *domain.Fund, members []int64) (*domain.Fund, bool, error)
+	GetFund(id int) (*domain.Fund, error)
+	GetFundCelebrants(fundID int) ([]int64, error)
+	GetMemberFunds(telegramID int64, now time.Time) (*[]domain.Fund, error)
+	GetEndedFunds(now time.Time) (*[]domain.Fund, error)
+	GetContributions(fundID int) (*[]domain.Contribution, error)
+	SetOrganizer(fundID int, telegramID int64) error
+	Pledge(fundID int, telegramID int64, amount int64) error
+	MarkPaid(fundID int, telegramID int64) error
+	CloseFund(fundID int) error
+}
+
+type Fund interface {
+	OpenFund(chatID int64, celebrants string, celebrantUsers *[]domain.User, members *[]domain.User, pledgesUntil time.Time, endsAt time.Time)
+	GetMemberFunds(telegramID int64) (*[]domain.Fund, error)
+	Pledge(telegramID int64, fundID int, amount int64) (*domain.Fund, error)
+	GetFund(telegramID int64, fundID int) (*domain.Fund, *[]domain.Contribution, error)
+	MarkPaid(organizerID int64, fundID int, telegramID int64) error
+	FundDue(ctx context.Context, wg *sync.WaitGroup)
+}
@@ -0,0 +1,24 @@
+package port
+
+import (
+	"birthdayapp/internal/core/domain"
+	"time"
+)
+
+//go:generate mockgen -source=./greeting.go -destination=mock/greeting.go -package=mock
+
+type GreetingRepo interface {
+	SaveGreetingRequest(greeting *domain.Greeting) (bool, error)
+	GetOpenGreetings(telegramID int64, now time.Time) (*[]domain.Greeting, error)
+	SaveGreetingText(id int, text string) error
+	GetDueGreetings(now time.Time) (*[]domain.Greeting, error)
+	MarkGreetingsDelivered(celebrantID int64, deliverAt time.Time) error
+	DeleteExpiredGreetings(now time.Time) error
+}
+
+type Card interface {
+	RequestGreetings(chatID int64, celebrant domain.User, planners *[]domain.User, deliverAt time.Time, expiresAt time.Time)
+	GetOpenGreetings(telegramID int64) (*[]domain.Greeting, error)
+	Greet(telegramID int64, celebrantID int64, text string) (*domain.Greeting, error)
+	DeliverCards()
+}
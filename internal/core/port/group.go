@@ -0,0 +1,10 @@
+package port
+
+import "time"
+
+//go:generate mockgen -source=./group.go -destination=mock/group.go -package=mock
+
+type GroupRepo interface {
+	AllocateGroup(pool []int64, now time.Time, releaseAfter time.Time) (chatID int64, shared bool, err error)
+	ReleaseGroups(now time.Time) error
+}
@@ -0,0 +1,15 @@
+package port
+
+import (
+	"birthdayapp/internal/core/domain"
+	"time"
+)
+
+//go:generate mockgen -source=./invite.go -destination=mock/invite.go -package=mock
+
+type InviteLinkRepo interface {
+	SaveInviteLink(link *domain.InviteLink) error
+	GetExpiredInviteLinks(now time.Time) (*[]domain.InviteLink, error)
+	HasActiveInviteLink(chatID int64, telegramID int64, now time.Time) (bool, error)
+	DeleteInviteLink(link *domain.InviteLink) error
+}
@@ -0,0 +1,10 @@
+package port
+
+import "birthdayapp/internal/core/domain"
+
+//go:generate mockgen -source=./job.go -destination=mock/job.go -package=mock
+
+type JobRunRepo interface {
+	ClaimJobRun(run *domain.JobRun) (bool, error)
+	ReleaseJobRun(run *domain.JobRun) error
+}
@@ -0,0 +1,14 @@
+package port
+
+import (
+	"birthdayapp/internal/core/domain"
+	"time"
+)
+
+//go:generate mockgen -source=./kick.go -destination=mock/kick.go -package=mock
+
+type KickRepo interface {
+	ScheduleKick(kick *domain.Kick) error
+	GetDueKicks(now time.Time) (*[]domain.Kick, error)
+	DeleteKick(kick *domain.Kick) error
+}
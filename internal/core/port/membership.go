@@ -0,0 +1,15 @@
+package port
+
+import (
+	"birthdayapp/internal/core/domain"
+	"time"
+)
+
+//go:generate mockgen -source=./membership.go -destination=mock/membership.go -package=mock
+
+type MembershipRepo interface {
+	RecordJoin(chatID int64, telegramID int64, at time.Time) error
+	RecordLeave(chatID int64, telegramID int64, at time.Time) error
+	IsMember(chatID int64, telegramID int64) (bool, bool, error)
+	GetAttendance(chatID int64, from time.Time, to time.Time) (*[]domain.Membership, error)
+}
@@ -0,0 +1,27 @@
+package port
+
+import (
+	"birthdayapp/internal/core/domain"
+	"context"
+	"sync"
+	"time"
+)
+
+//go:generate mockgen -source=./moderation.go -destination=mock/moderation.go -package=mock
+
+type ModerationRepo interface {
+	SaveAction(action *domain.ModerationAction) error
+	GetDueActions(now time.Time) (*[]domain.ModerationAction, error)
+	DeleteActions(chatID int64, telegramID int64) error
+	GetDeadActions() (*[]domain.ModerationAction, error)
+	RetryDeadActions(id int, now time.Time) (int64, error)
+}
+
+type Moderation interface {
+	Kick(chatID int64, telegramID int64) error
+	Unban(chatID int64, telegramID int64) error
+	QueueUnban(chatID int64, telegramID int64, reason string) error
+	ModerationDue(ctx context.Context, wg *sync.WaitGroup)
+	GetDeadActions() (*[]domain.ModerationAction, error)
+	RetryDeadActions(id int) (int64, error)
+}
@@ -0,0 +1,27 @@
+package port
+
+import (
+	"birthdayapp/internal/core/domain"
+	"context"
+	"sync"
+	"time"
+)
+
+//go:generate mockgen -source=./poll.go -destination=mock/poll.go -package=mock
+
+type PollRepo interface {
+	CreatePoll(poll *domain.Poll) (*domain.Poll, error)
+	GetPoll(pollID string) (*domain.Poll, error)
+	GetFundPoll(fundID int) (*domain.Poll, error)
+	GetEndedPolls(now time.Time) (*[]domain.Poll, error)
+	GetPollResults(id int) (*[]domain.PollOption, error)
+	SaveVote(id int, telegramID int64, optionID int) error
+	DeleteVote(id int, telegramID int64) error
+	ClosePoll(id int) error
+}
+
+type Poll interface {
+	StartPoll(telegramID int64, chatID int64, options []string) (*domain.Poll, error)
+	Vote(pollID string, telegramID int64, optionIDs []int) error
+	PollDue(ctx context.Context, wg *sync.WaitGroup)
+}
@@ -1,10 +1,19 @@
 package port
 
+import "time"
+
 //go:generate mockgen -source=./telegram.go -destination=mock/telegram.go -package=mock
 
 type Telegram interface {
-	GetInviteLink(chatID int64, birthdayUsernames string) (string, error)
+	CreateInviteLink(chatID int64, name string, expireDate time.Time) (string, error)
+	RevokeInviteLink(chatID int64, link string) error
+	ApproveJoinRequest(chatID int64, userID int64) error
+	DeclineJoinRequest(chatID int64, userID int64) error
 	KickUser(chatID int64, userID int64) error
 	UnBanUser(chatID int64, userID int64) error
+	GetChatAdministrators(chatID int64) ([]int64, error)
+	IsChatMember(chatID int64, userID int64) (bool, error)
+	SendPoll(chatID int64, question string, options []string) (string, int, error)
+	StopPoll(chatID int64, messageID int) error
 	SendMessage(chatID int64, text string)
 }
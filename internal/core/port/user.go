@@ -1,23 +1,41 @@
 package port
 
-import "birthdayapp/internal/core/domain"
+import (
+	"birthdayapp/internal/core/domain"
+	"time"
+)
 
 //go:generate mockgen -source=./user.go -destination=mock/user.go -package=mock
 
 type UserRepo interface {
 	InsertUser(user *domain.User) (*domain.User, error)
-	InsertUsers(users *[]domain.User) error
+	UpdateUserByTelegramID(user *domain.User) (*domain.User, error)
+	DeactivateUserByTelegramID(user *domain.User) error
+	DeleteUserByTelegramID(user *domain.User) error
 	ChangeNotifyBirthdayByTelegramID(user *domain.User) (*domain.User, error)
+	ChangeTimezoneByTelegramID(user *domain.User) (*domain.User, error)
+	ChangeReminderDaysByTelegramID(user *domain.User) (*domain.User, error)
+	ChangeDigestOptOutByTelegramID(user *domain.User) (*domain.User, error)
+	GetAllUsers() (*[]domain.User, error)
 	GetUserByTelegramID(user *domain.User) (*domain.User, error)
 	GetUserByUsername(user *domain.User) (*domain.User, error)
 	GetUsersToSubscribeByTelegramID(user *domain.User) (*[]domain.User, error)
-	GetUsersWithBirthdayToday() (*[]domain.User, error)
+	GetTimezones() ([]string, error)
+	GetUsersWithBirthdayOn(date time.Time, timezone string) (*[]domain.User, error)
+	GetUsersWithBirthdayIn(date time.Time, days int, timezone string) (*[]domain.User, error)
 	GetUsersSubscribedToUsers(birthdayUsers *[]domain.User) (*[]domain.User, error)
+	GetSubscriptionsByTelegramID(user *domain.User) (*[]domain.User, error)
 }
 
 type UserService interface {
-	UpdateUsers() error
+	UpdateUsers() (*domain.SyncSummary, error)
 	GetUsers(user *domain.User) (*[]domain.User, error)
 	GetTelegramIDByUsername(username string) (int64, error)
 	ChangeNotify(user *domain.User) error
+	ChangeTimezone(user *domain.User) error
+	ChangeReminderDays(user *domain.User) error
+	ChangeDigestOptOut(user *domain.User) error
+	Register(user *domain.User) (*domain.User, error)
+	ApproveRegistration(telegramID int64) (*domain.User, error)
+	DeclineRegistration(telegramID int64) (*domain.User, error)
 }
@@ -0,0 +1,25 @@
+package port
+
+import "birthdayapp/internal/core/domain"
+
+//go:generate mockgen -source=./wishlist.go -destination=mock/wishlist.go -package=mock
+
+type WishlistRepo interface {
+	AddItem(item *domain.WishlistItem) (*domain.WishlistItem, error)
+	DeleteItem(id int, telegramID int64) error
+	GetItem(id int) (*domain.WishlistItem, error)
+	GetItems(telegramIDs []int64) (*[]domain.WishlistItem, error)
+	ClaimItem(id int, telegramID int64) error
+	UnclaimItem(id int, telegramID int64) error
+	DeleteClaimedItems(telegramIDs []int64) error
+}
+
+type Wishlist interface {
+	AddItem(telegramID int64, title string) (*domain.WishlistItem, error)
+	RemoveItem(telegramID int64, id int) error
+	GetItems(viewerID int64, ownerID int64) (*[]domain.WishlistItem, error)
+	Claim(telegramID int64, id int) (*domain.WishlistItem, error)
+	Unclaim(telegramID int64, id int) error
+	GetWishlists(celebrants *[]domain.User) (map[int64][]domain.WishlistItem, error)
+	GiveClaimedItems(celebrantIDs []int64) error
+}
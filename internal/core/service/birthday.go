@@ -6,8 +6,10 @@ import (
 	"birthdayapp/internal/core/port"
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
 	"sync"
 	"time"
 )
@@ -16,106 +18,786 @@ type BirthdayService struct {
 	log *slog.Logger
 	cfg *config.Config
 	ur  port.UserRepo
+	jr  port.JobRunRepo
+	kr  port.KickRepo
+	cr  port.CelebrationRepo
+	gr  port.GroupRepo
+	ir  port.InviteLinkRepo
+	mr  port.MembershipRepo
+	ms  port.Moderation
+	fs  port.Fund
+	ws  port.Wishlist
+	cs  port.Card
 	tg  port.Telegram
+	now func() time.Time
+
+	calendar *domain.WorkCalendar
 }
 
-func NewBirthdayService(log *slog.Logger, ur port.UserRepo, tg port.Telegram, cfg *config.Config) *BirthdayService {
+func NewBirthdayService(log *slog.Logger, ur port.UserRepo, jr port.JobRunRepo, kr port.KickRepo, cr port.CelebrationRepo, gr port.GroupRepo, ir port.InviteLinkRepo, mr port.MembershipRepo, ms port.Moderation, fs port.Fund, ws port.Wishlist, cs port.Card, tg port.Telegram, calendar *domain.WorkCalendar, cfg *config.Config) *BirthdayService {
 	return &BirthdayService{
 		log: log,
 		cfg: cfg,
 		ur:  ur,
+		jr:  jr,
+		kr:  kr,
+		cr:  cr,
+		gr:  gr,
+		ir:  ir,
+		mr:  mr,
+		ms:  ms,
+		fs:  fs,
+		ws:  ws,
+		cs:  cs,
 		tg:  tg,
+		now: time.Now,
+
+		calendar: calendar,
 	}
 }
 
+// BirthdayNotify starts celebrations of every due date in every zone, in surprise mode they are scheduled for CelebrationDue.
+// Each date is claimed in the run log first so it's never celebrated twice
 func (bs *BirthdayService) BirthdayNotify(ctx context.Context, wg *sync.WaitGroup) {
 	defer wg.Done()
 	op := "birthdayService.BirthdayNotify"
 	bs.log.With(slog.String("op", op))
 
-	birthdayUsers, btErr := bs.ur.GetUsersWithBirthdayToday()
-	if btErr != nil {
-		bs.log.Error("GetUsersWithBirthdayToday error: ", "error", btErr.Error())
+	bs.forEachDueDate(domain.JobBirthdayNotify, func(date time.Time, timezone string) error {
+		if bs.cfg.Surprise.Enabled {
+			return bs.scheduleSurprises(date, timezone)
+		}
+
+		celebrants, coErr := bs.celebrantsOn(date, timezone)
+		if coErr != nil {
+			return coErr
+		}
+		if len(celebrants) == 0 {
+			//without birthday today
+			return nil
+		}
+		if ctx.Err() != nil {
+			//the released date is celebrated on the next start
+			return ctx.Err()
+		}
+
+		return bs.celebrate(celebrants)
+	})
+}
+
+// celebrantsOn returns users celebrating on the day,
+// birthdays on weekends and holidays are celebrated on the working day they are moved to
+func (bs *BirthdayService) celebrantsOn(day time.Time, timezone string) ([]domain.UpcomingBirthday, error) {
+	var celebrants []domain.UpcomingBirthday
+	for _, birthdayDate := range bs.calendar.CelebratedOn(day) {
+		birthdayUsers, btErr := bs.ur.GetUsersWithBirthdayOn(birthdayDate, timezone)
+		if btErr != nil {
+			return nil, fmt.Errorf("GetUsersWithBirthdayOn: %w", btErr)
+		}
+		for _, user := range *birthdayUsers {
+			celebrants = append(celebrants, domain.UpcomingBirthday{User: user, Date: birthdayDate})
+		}
+	}
+	return celebrants, nil
+}
+
+// scheduleSurprises stores surprise celebrations whose planning starts before the next notify hour
+func (bs *BirthdayService) scheduleSurprises(date time.Time, timezone string) error {
+	revealDay := date.AddDate(0, 0, bs.surpriseLeadDays())
+	celebrants, coErr := bs.celebrantsOn(revealDay, timezone)
+	if coErr != nil {
+		return coErr
+	}
+
+	revealAt := time.Date(revealDay.Year(), revealDay.Month(), revealDay.Day(), bs.cfg.Surprise.RevealHour, 0, 0, 0, revealDay.Location())
+	for _, celebrant := range celebrants {
+		celebration := &domain.Celebration{
+			TelegramID: celebrant.User.TelegramID,
+			Date:       celebrant.Date,
+			Timezone:   timezone,
+			PlanAt:     revealAt.Add(-bs.cfg.Surprise.PlanningLead),
+			RevealAt:   revealAt,
+		}
+		if scErr := bs.cr.ScheduleCelebration(celebration); scErr != nil {
+			return fmt.Errorf("ScheduleCelebration: %w", scErr)
+		}
+	}
+	return nil
+}
+
+// surpriseLeadDays returns the days between a notify hour and the reveal whose planning starts before the next notify hour
+func (bs *BirthdayService) surpriseLeadDays() int {
+	sinceNotify := bs.cfg.Surprise.PlanningLead - time.Duration(bs.cfg.Surprise.RevealHour-bs.cfg.NotifyHour)*time.Hour
+	if sinceNotify <= 0 {
+		return 0
+	}
+	return int((sinceNotify + 24*time.Hour - 1) / (24 * time.Hour))
+}
+
+// CelebrationDue invites subscribers of surprise celebrations at the planning time and celebrants at the reveal
+// where greeting cards collected from the subscribers are delivered, phases left by a shutdown or restart run on the next call
+func (bs *BirthdayService) CelebrationDue(ctx context.Context, wg *sync.WaitGroup) {
+	defer wg.Done()
+	op := "birthdayService.CelebrationDue"
+	bs.log.With(slog.String("op", op))
+
+	now := bs.now()
+	celebrations, gdcErr := bs.cr.GetDueCelebrations(now)
+	if gdcErr != nil {
+		bs.log.Error("GetDueCelebrations error: ", "error", gdcErr.Error())
 		return
 	}
-	if len(*birthdayUsers) == 0 {
-		//without birthday today
+
+	//celebrants revealed at the same time share the planning and the greeting
+	var group []domain.Celebration
+	for i, celebration := range *celebrations {
+		group = append(group, celebration)
+		if i+1 < len(*celebrations) && (*celebrations)[i+1].RevealAt.Equal(celebration.RevealAt) {
+			continue
+		}
+		if ctx.Err() != nil {
+			//pending phases stay for the next start
+			return
+		}
+		bs.surprise(group, now)
+		group = nil
+	}
+
+	//cards follow the greeting of their celebration, requested ones are delivered even after greeting_card is turned off
+	bs.cs.DeliverCards()
+}
+
+func (bs *BirthdayService) surprise(celebrations []domain.Celebration, now time.Time) {
+	op := "birthdayService.surprise"
+	bs.log.With(slog.String("op", op))
+
+	var celebrants []domain.UpcomingBirthday
+	var pending []domain.Celebration
+	for _, celebration := range celebrations {
+		user, guErr := bs.ur.GetUserByTelegramID(&domain.User{TelegramID: celebration.TelegramID})
+		if guErr != nil {
+			bs.log.Error("GetUserByTelegramID error: ", "telegram_id", celebration.TelegramID, "error", guErr)
+			if errors.Is(guErr, domain.ErrNotFound) {
+				bs.deleteCelebration(&celebration)
+			}
+			continue
+		}
+		celebrants = append(celebrants, domain.UpcomingBirthday{User: *user, Date: celebration.Date})
+		pending = append(pending, celebration)
+	}
+	if len(pending) == 0 {
 		return
 	}
 
+	birthdayUsers := &[]domain.User{}
+	for _, celebrant := range celebrants {
+		*birthdayUsers = append(*birthdayUsers, celebrant.User)
+	}
 	subscribers, gsuErr := bs.ur.GetUsersSubscribedToUsers(birthdayUsers)
 	if gsuErr != nil {
-		bs.log.Error("GetUsersSubscribedToUsers error from birthdayUsers: ", "error", birthdayUsers, gsuErr)
+		bs.log.Error("GetUsersSubscribedToUsers error from birthdayUsers: ", "birthday_users", birthdayUsers, "error", gsuErr)
 		return
 	}
+	//celebrants subscribed to each other learn about the surprise at the reveal
+	var planners []domain.User
+	for _, subscriber := range *subscribers {
+		if !slices.ContainsFunc(*birthdayUsers, func(user domain.User) bool { return user.TelegramID == subscriber.TelegramID }) {
+			planners = append(planners, subscriber)
+		}
+	}
+
+	loc, lErr := time.LoadLocation(pending[0].Timezone)
+	if lErr != nil {
+		loc = time.UTC
+	}
+	revealAt := pending[0].RevealAt.In(loc)
+	birthdayUsernamesString := bs.celebrantNames(celebrants)
+	//links, the group and kicks end together even when the reveal runs late
+	endAt := revealAt.Add(bs.cfg.TimeToKick)
+
+	//the group is allocated at planning and kept until the kicks after the reveal
+	var chatID int64
+	for _, celebration := range pending {
+		if celebration.ChatID != 0 {
+			chatID = celebration.ChatID
+			break
+		}
+	}
+	if chatID == 0 {
+		chatID = bs.allocateGroup(endAt)
+	}
+
+	if slices.ContainsFunc(pending, func(celebration domain.Celebration) bool { return !celebration.Planned }) {
+		if len(planners) > 0 {
+			//planning again after a failure or for a new celebrant invites only planners without a link
+			if uninvited := bs.uninvited(chatID, planners); len(uninvited) > 0 {
+				bs.sendInviteForUsers(chatID, &uninvited, birthdayUsers, birthdayUsernamesString, endAt)
+				bs.tg.SendMessage(chatID, fmt.Sprintf("surprise for %s, they join the group on %s",
+					birthdayUsernamesString, revealAt.Format("02.01 at 15:04")))
+			}
+			//planners pledge before the celebrants join
+			bs.fs.OpenFund(chatID, birthdayUsernamesString, birthdayUsers, &planners, revealAt, endAt)
+			if bs.cfg.GreetingCard {
+				for _, celebrant := range *birthdayUsers {
+					bs.cs.RequestGreetings(chatID, celebrant, &planners, revealAt, endAt)
+				}
+			}
+		}
+		for _, celebration := range pending {
+			if celebration.Planned {
+				continue
+			}
+			celebration.ChatID = chatID
+			if mpErr := bs.cr.MarkCelebrationPlanned(&celebration); mpErr != nil {
+				bs.log.Error("error mark celebration planned of user with telegram_id: ", "telegram_id", celebration.TelegramID, "error", mpErr)
+			}
+		}
+	}
+
+	if revealAt.After(now) {
+		return
+	}
+	//if no one to wish happy birthday the celebration is dropped
+	if len(planners) > 0 || len(*birthdayUsers) > 1 {
+		bs.sendInviteForUsers(chatID, birthdayUsers, birthdayUsers, birthdayUsernamesString, endAt)
+		bs.tg.SendMessage(chatID, fmt.Sprintf("happy birthday %s", birthdayUsernamesString))
+
+		allUsers := append(*birthdayUsers, planners...)
+		bs.scheduleKicks(chatID, &allUsers, endAt)
+	}
+	for _, celebration := range pending {
+		bs.deleteCelebration(&celebration)
+	}
+}
+
+// uninvited returns users without an active invite link to the chat, users whose link can't be checked are invited
+func (bs *BirthdayService) uninvited(chatID int64, users []domain.User) []domain.User {
+	var uninvited []domain.User
+	for _, user := range users {
+		invited, haErr := bs.ir.HasActiveInviteLink(chatID, user.TelegramID, bs.now())
+		if haErr != nil {
+			bs.log.Error("HasActiveInviteLink error: ", "telegram_id", user.TelegramID, "error", haErr)
+		}
+		if !invited {
+			uninvited = append(uninvited, user)
+		}
+	}
+	return uninvited
+}
+
+func (bs *BirthdayService) deleteCelebration(celebration *domain.Celebration) {
+	if dcErr := bs.cr.DeleteCelebration(celebration); dcErr != nil {
+		bs.log.Error("error delete celebration of user with telegram_id: ", "telegram_id", celebration.TelegramID, "error", dcErr)
+	}
+}
+
+// RemindUpcoming reminds subscribers about birthdays in their reminder days,
+// celebrants of a zone are looked up at the notify hour of the zone
+func (bs *BirthdayService) RemindUpcoming(ctx context.Context, wg *sync.WaitGroup) {
+	defer wg.Done()
+	op := "birthdayService.RemindUpcoming"
+	bs.log.With(slog.String("op", op))
+
+	bs.forEachDueDate(domain.JobBirthdayReminder, func(date time.Time, timezone string) error {
+		for days := 1; days <= domain.MaxReminderDays; days++ {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			celebrants, biErr := bs.ur.GetUsersWithBirthdayIn(date, days, timezone)
+			if biErr != nil {
+				bs.log.Error("GetUsersWithBirthdayIn error: ", "timezone", timezone, "days", days, "error", biErr.Error())
+				continue
+			}
+			for _, celebrant := range *celebrants {
+				bs.remindSubscribers(celebrant, date.AddDate(0, 0, days), days)
+			}
+		}
+		return nil
+	})
+}
+
+func (bs *BirthdayService) remindSubscribers(celebrant domain.User, birthday time.Time, days int) {
+	subscribers, gsuErr := bs.ur.GetUsersSubscribedToUsers(&[]domain.User{celebrant})
+	if gsuErr != nil {
+		bs.log.Error("GetUsersSubscribedToUsers error: ", "telegram_id", celebrant.TelegramID, "error", gsuErr)
+		return
+	}
+
+	var wishlists map[int64][]domain.WishlistItem
+	var reminded []domain.User
+	for _, subscriber := range *subscribers {
+		if subscriber.TelegramID == celebrant.TelegramID || !slices.Contains(subscriber.RemindersOn(bs.cfg.ReminderDays), days) {
+			continue
+		}
+		if wishlists == nil {
+			wishlists = bs.wishlists(&[]domain.User{celebrant})
+		}
+		bs.tg.SendMessage(subscriber.TelegramID, fmt.Sprintf("reminder: @%s has birthday in %s, on %s%s",
+			celebrant.Username, daysText(days), birthday.Format("02.01"),
+			domain.WishlistMessage(subscriber.TelegramID, []domain.User{celebrant}, wishlists)))
+		reminded = append(reminded, subscriber)
+	}
+
+	//surprise planners are asked for greetings when they join the planning
+	if !bs.cfg.GreetingCard || bs.cfg.Surprise.Enabled || len(reminded) == 0 {
+		return
+	}
+	//the card comes with the celebration at the notify hour, the group greets the celebrant itself
+	celebrateAt := bs.calendar.CelebrationDay(birthday)
+	if !celebrateAt.After(bs.now()) {
+		return
+	}
+	bs.cs.RequestGreetings(0, celebrant, &reminded, celebrateAt, celebrateAt.Add(bs.cfg.TimeToKick))
+}
+
+func daysText(days int) string {
+	if days == 1 {
+		return "1 day"
+	}
+	return fmt.Sprintf("%d days", days)
+}
+
+// forEachDueDate claims every due date of the job in every zone and runs it,
+// the claim is released when run fails so the date is retried on the next call
+func (bs *BirthdayService) forEachDueDate(job string, run func(date time.Time, timezone string) error) {
+	timezones, gtErr := bs.ur.GetTimezones()
+	if gtErr != nil {
+		bs.log.Error("GetTimezones error: ", "error", gtErr.Error())
+		return
+	}
+
+	now := bs.now()
+	for _, timezone := range timezones {
+		loc, lErr := time.LoadLocation(timezone)
+		if lErr != nil {
+			bs.log.Error("error load timezone", "timezone", timezone, "error", lErr)
+			continue
+		}
+
+		for _, date := range bs.dueDates(now, loc) {
+			jobRun := &domain.JobRun{Job: job, Date: date, Timezone: timezone, StartedAt: now}
+			claimed, cjErr := bs.jr.ClaimJobRun(jobRun)
+			if cjErr != nil {
+				bs.log.Error("ClaimJobRun error: ", "job", job, "timezone", timezone, "date", date, "error", cjErr.Error())
+				continue
+			}
+			if !claimed {
+				//already processed
+				continue
+			}
+			if date.Before(now.Add(-time.Hour)) {
+				bs.log.Info("catch up missed run", "job", job, "timezone", timezone, "date", date)
+			}
+
+			if rErr := run(date, timezone); rErr != nil {
+				bs.log.Error("job run error: ", "job", job, "timezone", timezone, "date", date, "error", rErr.Error())
+				if rjErr := bs.jr.ReleaseJobRun(jobRun); rjErr != nil {
+					bs.log.Error("ReleaseJobRun error: ", "job", job, "timezone", timezone, "date", date, "error", rjErr.Error())
+				}
+			}
+		}
+	}
+}
+
+// dueDates returns notify times in loc that passed within the catch-up window, oldest first.
+// Without a window only the current notify hour is due
+func (bs *BirthdayService) dueDates(now time.Time, loc *time.Location) []time.Time {
+	window := bs.cfg.CatchUpWindow
+	if window < time.Hour {
+		window = time.Hour
+	}
+
+	localNow := now.In(loc)
+	var dates []time.Time
+	for days := 0; days <= int(window/(24*time.Hour))+1; days++ {
+		date := time.Date(localNow.Year(), localNow.Month(), localNow.Day()-days, bs.cfg.NotifyHour, 0, 0, 0, loc)
+		if date.After(localNow) {
+			continue
+		}
+		if localNow.Sub(date) >= window {
+			break
+		}
+		dates = append([]time.Time{date}, dates...)
+	}
+	return dates
+}
+
+// celebrate invites the celebrants and their subscribers to a birthday group, an error means the celebration is incomplete
+// and is retried in the same group, only users without a link are invited again
+func (bs *BirthdayService) celebrate(celebrants []domain.UpcomingBirthday) error {
+	op := "birthdayService.celebrate"
+	bs.log.With(slog.String("op", op))
+
+	birthdayUsers := &[]domain.User{}
+	celebrantIDs := make([]int64, 0, len(celebrants))
+	for _, celebrant := range celebrants {
+		*birthdayUsers = append(*birthdayUsers, celebrant.User)
+		celebrantIDs = append(celebrantIDs, celebrant.User.TelegramID)
+	}
+
+	subscribers, gsuErr := bs.ur.GetUsersSubscribedToUsers(birthdayUsers)
+	if gsuErr != nil {
+		return fmt.Errorf("GetUsersSubscribedToUsers: %w", gsuErr)
+	}
 	if len(*subscribers) == 0 && len(*birthdayUsers) == 1 {
 		//if no one to wish happy birthday
-		return
+		return nil
 	}
 
 	allUsers := append(*birthdayUsers, *subscribers...)
 
+	birthdayUsernamesString := bs.celebrantNames(celebrants)
+	endAt := bs.now().Add(bs.cfg.TimeToKick)
+	chatID, retry, cgErr := bs.celebrationGroup(*birthdayUsers)
+	if cgErr != nil {
+		return cgErr
+	}
+	usersForInvite := allUsers
+	if retry {
+		usersForInvite = bs.uninvited(chatID, allUsers)
+	} else {
+		chatID = bs.allocateGroup(endAt)
+	}
+
+	var errs []error
+	if len(usersForInvite) > 0 {
+		if siErr := bs.sendInviteForUsers(chatID, &usersForInvite, birthdayUsers, birthdayUsernamesString, endAt); siErr != nil {
+			errs = append(errs, siErr)
+		}
+	}
+	if !retry {
+		bs.tg.SendMessage(chatID, fmt.Sprintf("happy birthday %s", birthdayUsernamesString))
+
+		//gift funds open only with surprise planning, here the celebrants are in the group from the start
+		//and claimed gifts are given at the celebration
+		if gciErr := bs.ws.GiveClaimedItems(celebrantIDs); gciErr != nil {
+			bs.log.Error("GiveClaimedItems error: ", "celebrant_ids", celebrantIDs, "error", gciErr.Error())
+		}
+	}
+	if skErr := bs.scheduleKicks(chatID, &allUsers, endAt); skErr != nil {
+		errs = append(errs, skErr)
+	}
+	return errors.Join(errs...)
+}
+
+// celebrationGroup returns the group where one of the celebrants already has an active link, a retried celebration goes on there.
+// Subscribers may have links of another celebration in a shared group, so only the celebrants are checked
+func (bs *BirthdayService) celebrationGroup(celebrants []domain.User) (int64, bool, error) {
+	for _, chatID := range bs.groupPool() {
+		for _, celebrant := range celebrants {
+			invited, haErr := bs.ir.HasActiveInviteLink(chatID, celebrant.TelegramID, bs.now())
+			if haErr != nil {
+				return 0, false, fmt.Errorf("HasActiveInviteLink: %w", haErr)
+			}
+			if invited {
+				return chatID, true, nil
+			}
+		}
+	}
+	return 0, false, nil
+}
+
+// groupPool returns the birthday groups, birthday_group_id alone when the pool isn't configured
+func (bs *BirthdayService) groupPool() []int64 {
+	if len(bs.cfg.BirthdayGroupIDs) == 0 {
+		return []int64{bs.cfg.BirthdayGroupID}
+	}
+	return bs.cfg.BirthdayGroupIDs
+}
+
+// allocateGroup takes a free birthday group of the pool until releaseAfter,
+// when the pool is exhausted or unavailable celebrations share a group
+func (bs *BirthdayService) allocateGroup(releaseAfter time.Time) int64 {
+	pool := bs.groupPool()
+	chatID, shared, agErr := bs.gr.AllocateGroup(pool, bs.now(), releaseAfter)
+	if agErr != nil {
+		bs.log.Error("AllocateGroup error: ", "error", agErr.Error())
+		return pool[0]
+	}
+	if shared && len(pool) > 1 {
+		bs.log.Warn("pool of birthday groups is exhausted, celebrations share the group", "chat_id", chatID)
+	}
+	return chatID
+}
+
+// celebrantNames lists usernames of the celebrants, with the real date of birthdays moved from weekends and holidays
+func (bs *BirthdayService) celebrantNames(celebrants []domain.UpcomingBirthday) string {
 	var birthdayUsernamesBuffer bytes.Buffer
 
-	for i, birthdayUser := range *birthdayUsers {
+	for i, celebrant := range celebrants {
 		birthdayUsernamesBuffer.WriteString("@")
-		birthdayUsernamesBuffer.WriteString(birthdayUser.Username)
-		if i != len(*birthdayUsers)-1 {
+		birthdayUsernamesBuffer.WriteString(celebrant.User.Username)
+		if !sameDate(bs.calendar.CelebrationDay(celebrant.Date), celebrant.Date) {
+			//moved from a weekend or a holiday
+			birthdayUsernamesBuffer.WriteString(fmt.Sprintf(" (birthday on %s)", celebrant.Date.Format("02.01")))
+		}
+		if i != len(celebrants)-1 {
 			birthdayUsernamesBuffer.WriteString(", ")
 		}
 	}
-	birthdayUsernamesString := birthdayUsernamesBuffer.String()
-	bs.sendInviteForUsers(&allUsers, birthdayUsernamesString)
-	bs.tg.SendMessage(bs.cfg.BirthdayGroupID, fmt.Sprintf("happy birthday %s", birthdayUsernamesString))
+	return birthdayUsernamesBuffer.String()
+}
+
+// scheduleKicks stores kicks of the celebration members at the end of the celebration, KickDue executes them when due,
+// protected members aren't scheduled and KickDue checks the administrators again
+func (bs *BirthdayService) scheduleKicks(chatID int64, usersToKick *[]domain.User, dueAt time.Time) error {
+	op := "birthdayService.scheduleKicks"
+	bs.log.With(slog.String("op", op))
 
-	bs.kickUsers(ctx, &allUsers)
+	protected, pmErr := bs.protectedMembers(chatID)
+	if pmErr != nil {
+		bs.log.Error("protected members are unknown, KickDue skips them: ", "chat_id", chatID, "error", pmErr)
+	}
+	var failed int
+	var lastErr error
+	for _, user := range *usersToKick {
+		if protected[user.TelegramID] {
+			continue
+		}
+		kick := &domain.Kick{ChatID: chatID, TelegramID: user.TelegramID, DueAt: dueAt}
+		if skErr := bs.kr.ScheduleKick(kick); skErr != nil {
+			bs.log.Error("error schedule kick of user with telegram_id: ", "telegram_id", user.TelegramID, "error", skErr)
+			failed++
+			lastErr = skErr
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("kicks of %d users aren't scheduled: %w", failed, lastErr)
+	}
+	return nil
 }
 
-func (bs *BirthdayService) kickUsers(ctx context.Context, usersToKick *[]domain.User) {
-	op := "birthdayService.kickUsers"
+// KickDue kicks users whose kick time has come and who are still in the group, revokes their invite links and releases groups of finished celebrations,
+// kicks left by a shutdown or restart run on the next call
+func (bs *BirthdayService) KickDue(ctx context.Context, wg *sync.WaitGroup) {
+	defer wg.Done()
+	op := "birthdayService.KickDue"
 	bs.log.With(slog.String("op", op))
 
-	timeToKick := time.NewTimer(bs.cfg.TimeToKick)
+	kicks, gdkErr := bs.kr.GetDueKicks(bs.now())
+	if gdkErr != nil {
+		bs.log.Error("GetDueKicks error: ", "error", gdkErr.Error())
+		return
+	}
 
-	defer func() {
-		for _, user := range *usersToKick {
-			if user.TelegramID == bs.cfg.GroupOwnerID {
+	//administrators are looked up once per chat, kicks of a chat with unknown administrators stay for the next call
+	protected := make(map[int64]map[int64]bool)
+	unknown := make(map[int64]bool)
+	for _, kick := range *kicks {
+		if ctx.Err() != nil {
+			//pending kicks stay for the next start
+			return
+		}
+		if unknown[kick.ChatID] {
+			continue
+		}
+		if _, ok := protected[kick.ChatID]; !ok {
+			chatProtected, pmErr := bs.protectedMembers(kick.ChatID)
+			if pmErr != nil {
+				bs.log.Error("kicks are postponed, protected members are unknown: ", "chat_id", kick.ChatID, "error", pmErr)
+				unknown[kick.ChatID] = true
 				continue
 			}
-			kErr := bs.tg.KickUser(bs.cfg.BirthdayGroupID, user.TelegramID)
-			if kErr != nil {
-				bs.log.Error("error kick user with telegram_id: ", "error", user.TelegramID, kErr)
-				bs.tg.SendMessage(user.TelegramID, "please, leave from group. We'll wait for next birthday")
-			}
+			protected[kick.ChatID] = chatProtected
 		}
-	}()
-
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case <-timeToKick.C:
-			timeToKick.Stop()
-			return
+		if !bs.kick(kick, protected[kick.ChatID]) {
+			continue
+		}
+		if dkErr := bs.kr.DeleteKick(&kick); dkErr != nil {
+			bs.log.Error("error delete kick of user with telegram_id: ", "telegram_id", kick.TelegramID, "error", dkErr)
 		}
 	}
+
+	bs.revokeInviteLinks(bs.now())
+	//groups without pending kicks are free for the next celebrations
+	if rgErr := bs.gr.ReleaseGroups(bs.now()); rgErr != nil {
+		bs.log.Error("ReleaseGroups error: ", "error", rgErr.Error())
+	}
 }
 
-func (bs *BirthdayService) sendInviteForUsers(usersForSendInvite *[]domain.User, birthdayUsers string) {
+// sendInviteForUsers sends every user an own join-request link to the group with wishlists of the celebrants,
+// links expire at expireDate and are revoked by KickDue
+func (bs *BirthdayService) sendInviteForUsers(chatID int64, usersForSendInvite *[]domain.User, celebrants *[]domain.User, birthdayUsers string, expireDate time.Time) error {
 	op := "birthdayService.sendInviteForUsers"
 	bs.log.With(slog.String("op", op))
 
-	inviteLink, ilErr := bs.tg.GetInviteLink(bs.cfg.BirthdayGroupID, birthdayUsers)
-	if ilErr != nil {
-		bs.log.Error("error generate invite link", "error", ilErr)
-		inviteLink = fmt.Sprintf("to invite birthday group with users celebrating: %s, contact support", birthdayUsers)
+	protected, pmErr := bs.protectedMembers(chatID)
+	if pmErr != nil {
+		bs.log.Error("unbans are queued, protected members are unknown: ", "chat_id", chatID, "error", pmErr)
 	}
-
+	wishlists := bs.wishlists(celebrants)
+	var failed int
+	var lastErr error
 	for _, userForNotify := range *usersForSendInvite {
-		if ubErr := bs.tg.UnBanUser(bs.cfg.BirthdayGroupID, userForNotify.TelegramID); ubErr != nil && userForNotify.TelegramID != bs.cfg.GroupOwnerID {
+		switch {
+		case protected[userForNotify.TelegramID] || slices.Contains(bs.cfg.ProtectedMembers, userForNotify.TelegramID):
+			bs.log.Info("protected member is not unbanned", "chat_id", chatID, "telegram_id", userForNotify.TelegramID)
+		case pmErr != nil:
+			//the queued unban only lifts a ban, so members already in the chat are safe
+			if quErr := bs.ms.QueueUnban(chatID, userForNotify.TelegramID, pmErr.Error()); quErr != nil {
+				bs.log.Error("error queue unban of user with telegram_id: ", "telegram_id", userForNotify.TelegramID, "error", quErr)
+			}
+		default:
+			if ubErr := bs.ms.Unban(chatID, userForNotify.TelegramID); ubErr != nil {
+				//the unban is retried by the moderation queue, the link works once it succeeds
+				bs.log.Error("error unban user with telegram_id: ", "telegram_id", userForNotify.TelegramID, "error", ubErr)
+			}
+		}
+
+		inviteLink, ilErr := bs.tg.CreateInviteLink(chatID, fmt.Sprintf("birthday @%s", userForNotify.Username), expireDate)
+		if ilErr != nil {
+			bs.log.Error("error generate invite link", "telegram_id", userForNotify.TelegramID, "error", ilErr)
 			bs.tg.SendMessage(userForNotify.TelegramID, fmt.Sprintf("to invite birthday group with users celebrating: %s, contact support", birthdayUsers))
+			failed++
+			lastErr = ilErr
 			continue
 		}
-		bs.tg.SendMessage(userForNotify.TelegramID, inviteLink)
+		link := &domain.InviteLink{ChatID: chatID, TelegramID: userForNotify.TelegramID, Link: inviteLink, ExpiresAt: expireDate}
+		if slErr := bs.ir.SaveInviteLink(link); slErr != nil {
+			bs.log.Error("error save invite link of user with telegram_id: ", "telegram_id", userForNotify.TelegramID, "error", slErr)
+		}
+
+		bs.tg.SendMessage(userForNotify.TelegramID, fmt.Sprintf("Join the group to congratulate the birthday for users: %s. Link: %s%s",
+			birthdayUsers, inviteLink, domain.WishlistMessage(userForNotify.TelegramID, *celebrants, wishlists)))
+	}
+	if failed > 0 {
+		return fmt.Errorf("invite links of %d users aren't created: %w", failed, lastErr)
+	}
+	return nil
+}
+
+// wishlists returns wishlists of the celebrants, messages go without them when wishlists are unavailable
+func (bs *BirthdayService) wishlists(celebrants *[]domain.User) map[int64][]domain.WishlistItem {
+	wishlists, gwErr := bs.ws.GetWishlists(celebrants)
+	if gwErr != nil {
+		bs.log.Error("GetWishlists error: ", "error", gwErr.Error())
+		return map[int64][]domain.WishlistItem{}
+	}
+	return wishlists
+}
+
+// protectedMembers returns the configured protected members and the current administrators of the chat,
+// without the administrators nobody in the chat is known to be safe to kick or unban
+func (bs *BirthdayService) protectedMembers(chatID int64) (map[int64]bool, error) {
+	return protectedMembers(bs.tg, bs.cfg.ProtectedMembers, chatID)
+}
+
+func protectedMembers(tg port.Telegram, protectedIDs []int64, chatID int64) (map[int64]bool, error) {
+	admins, gcaErr := tg.GetChatAdministrators(chatID)
+	if gcaErr != nil {
+		return nil, fmt.Errorf("GetChatAdministrators: %w", gcaErr)
+	}
+
+	protected := make(map[int64]bool, len(protectedIDs)+len(admins))
+	for _, telegramID := range protectedIDs {
+		protected[telegramID] = true
+	}
+	for _, telegramID := range admins {
+		protected[telegramID] = true
+	}
+	return protected, nil
+}
+
+// kick removes the user from the group, protected members and users recorded leaving are skipped,
+// users without recorded stays may have joined before the tracking and are kicked
+// kick removes the user from the chat when they are in it, false means the membership is unknown and the kick stays for the next call
+func (bs *BirthdayService) kick(kick domain.Kick, protected map[int64]bool) bool {
+	if protected[kick.TelegramID] {
+		bs.log.Info("protected member is not kicked", "chat_id", kick.ChatID, "telegram_id", kick.TelegramID)
+		return true
+	}
+	member, imErr := bs.isMember(kick.ChatID, kick.TelegramID)
+	if imErr != nil {
+		bs.log.Error("kick is postponed, membership is unknown: ", "telegram_id", kick.TelegramID, "error", imErr)
+		return false
+	}
+	//users who never joined or have left aren't banned
+	if !member {
+		return true
+	}
+
+	if kErr := bs.ms.Kick(kick.ChatID, kick.TelegramID); kErr != nil {
+		//the kick is retried by the moderation queue
+		bs.log.Error("error kick user with telegram_id: ", "telegram_id", kick.TelegramID, "error", kErr)
+		return true
+	}
+	if rlErr := bs.mr.RecordLeave(kick.ChatID, kick.TelegramID, bs.now()); rlErr != nil {
+		bs.log.Error("error record leave of user with telegram_id: ", "telegram_id", kick.TelegramID, "error", rlErr)
+	}
+	return true
+}
+
+// isMember reports whether the user is in the chat, users without recorded stays joined before the tracking
+// and are looked up in telegram
+func (bs *BirthdayService) isMember(chatID int64, telegramID int64) (bool, error) {
+	member, tracked, imErr := bs.mr.IsMember(chatID, telegramID)
+	if imErr != nil {
+		return false, fmt.Errorf("IsMember: %w", imErr)
+	}
+	if tracked {
+		return member, nil
+	}
+	member, icmErr := bs.tg.IsChatMember(chatID, telegramID)
+	if icmErr != nil {
+		return false, fmt.Errorf("IsChatMember: %w", icmErr)
+	}
+	return member, nil
+}
+
+// MemberJoined records the user entering a birthday group, other chats of the bot are ignored
+func (bs *BirthdayService) MemberJoined(chatID int64, telegramID int64, at time.Time) error {
+	if !slices.Contains(bs.groupPool(), chatID) {
+		return nil
+	}
+	if rjErr := bs.mr.RecordJoin(chatID, telegramID, at); rjErr != nil {
+		return fmt.Errorf("RecordJoin: %w", rjErr)
+	}
+	return nil
+}
+
+// MemberLeft records the user leaving a birthday group, other chats of the bot are ignored
+func (bs *BirthdayService) MemberLeft(chatID int64, telegramID int64, at time.Time) error {
+	if !slices.Contains(bs.groupPool(), chatID) {
+		return nil
+	}
+	if rlErr := bs.mr.RecordLeave(chatID, telegramID, at); rlErr != nil {
+		return fmt.Errorf("RecordLeave: %w", rlErr)
+	}
+	return nil
+}
+
+// Attendance returns stays in the birthday group overlapping the period, LeftAt is zero for users still in the group
+func (bs *BirthdayService) Attendance(chatID int64, from time.Time, to time.Time) ([]domain.Membership, error) {
+	memberships, gaErr := bs.mr.GetAttendance(chatID, from, to)
+	if gaErr != nil {
+		return nil, fmt.Errorf("GetAttendance: %w", gaErr)
+	}
+	return *memberships, nil
+}
+
+// IsExpectedMember reports whether the user was invited to the group by a celebration that hasn't ended
+func (bs *BirthdayService) IsExpectedMember(chatID int64, telegramID int64) (bool, error) {
+	expected, haErr := bs.ir.HasActiveInviteLink(chatID, telegramID, bs.now())
+	if haErr != nil {
+		return false, fmt.Errorf("HasActiveInviteLink: %w", haErr)
+	}
+	return expected, nil
+}
+
+// revokeInviteLinks revokes links of ended celebrations, so unused links can't be shared later
+func (bs *BirthdayService) revokeInviteLinks(now time.Time) {
+	links, geErr := bs.ir.GetExpiredInviteLinks(now)
+	if geErr != nil {
+		bs.log.Error("GetExpiredInviteLinks error: ", "error", geErr.Error())
+		return
+	}
+
+	for _, link := range *links {
+		if rlErr := bs.tg.RevokeInviteLink(link.ChatID, link.Link); rlErr != nil {
+			bs.log.Error("error revoke invite link of user with telegram_id: ", "telegram_id", link.TelegramID, "error", rlErr)
+		}
+		if dlErr := bs.ir.DeleteInviteLink(&link); dlErr != nil {
+			bs.log.Error("error delete invite link of user with telegram_id: ", "telegram_id", link.TelegramID, "error", dlErr)
+		}
 	}
 }
@@ -9,6 +9,7 @@ import (
 	"errors"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"io"
 	"log/slog"
 	"strings"
 	"sync"
@@ -21,54 +22,87 @@ func TestSendInviteForUsers_Success(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockUserRepo := mock.NewMockUserRepo(ctrl)
+	mockIR := mock.NewMockInviteLinkRepo(ctrl)
 	mockTelegram := mock.NewMockTelegram(ctrl)
+	mockWS := mock.NewMockWishlist(ctrl)
+	mockMS := mock.NewMockModeration(ctrl)
 
 	var logBuf bytes.Buffer
 	log := slog.New(
 		slog.NewTextHandler(&logBuf, &slog.HandlerOptions{Level: slog.LevelDebug}))
 
 	cfg := &config.Config{
-		BirthdayGroupID: 12345,
-		GroupOwnerID:    67890,
+		BirthdayGroupID:  12345,
+		ProtectedMembers: []int64{67890},
 	}
 
 	bs := &BirthdayService{
 		tg:  mockTelegram,
+		ws:  mockWS,
+		ms:  mockMS,
 		ur:  mockUserRepo,
+		ir:  mockIR,
 		cfg: cfg,
 		log: log,
 	}
 
 	usersForSendInvite := &[]domain.User{
-		{TelegramID: 123},
-		{TelegramID: 456},
+		{Username: "sub1", TelegramID: 123},
+		{Username: "sub2", TelegramID: 456},
+		{Username: "owner", TelegramID: 67890},
+		{Username: "admin", TelegramID: 789},
 	}
 
+	celebrants := []domain.User{
+		{Username: "user1", TelegramID: 111},
+		{Username: "user2", TelegramID: 222},
+	}
 	birthdayUsers := "@user1, @user2"
-
-	inviteLink := "http://invite.com"
-	mockTelegram.EXPECT().GetInviteLink(gomock.Any(), birthdayUsers).Return(inviteLink, nil).Times(1)
-
-	mockTelegram.EXPECT().UnBanUser(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
-	mockTelegram.EXPECT().SendMessage(int64(123), gomock.Any()).Times(1)
-	mockTelegram.EXPECT().SendMessage(int64(456), gomock.Any()).Times(1)
-
-	bs.sendInviteForUsers(usersForSendInvite, birthdayUsers)
+	expireDate := time.Date(2024, 5, 10, 20, 0, 0, 0, time.UTC)
+
+	//the claim is shown to subscribers only
+	mockWS.EXPECT().GetWishlists(&celebrants).Return(map[int64][]domain.WishlistItem{
+		111: {{ID: 1, TelegramID: 111, Title: "book", ClaimedBy: 123}, {ID: 2, TelegramID: 111, Title: "pen"}},
+	}, nil)
+	wishlist := "\nwishlist of @user1:\n#1 book (claimed)\n#2 pen\nsend /wishlist claim \"number\" to buy a gift"
+	//protected members are invited without an unban
+	mockTelegram.EXPECT().GetChatAdministrators(cfg.BirthdayGroupID).Return([]int64{789}, nil)
+	mockMS.EXPECT().Unban(cfg.BirthdayGroupID, int64(123)).Return(nil)
+	mockMS.EXPECT().Unban(cfg.BirthdayGroupID, int64(456)).Return(nil)
+	//every user gets an own link
+	mockTelegram.EXPECT().CreateInviteLink(cfg.BirthdayGroupID, "birthday @sub1", expireDate).Return("http://invite.com/1", nil)
+	mockTelegram.EXPECT().CreateInviteLink(cfg.BirthdayGroupID, "birthday @sub2", expireDate).Return("http://invite.com/2", nil)
+	mockTelegram.EXPECT().CreateInviteLink(cfg.BirthdayGroupID, "birthday @owner", expireDate).Return("http://invite.com/3", nil)
+	mockTelegram.EXPECT().CreateInviteLink(cfg.BirthdayGroupID, "birthday @admin", expireDate).Return("http://invite.com/4", nil)
+	mockIR.EXPECT().SaveInviteLink(&domain.InviteLink{ChatID: cfg.BirthdayGroupID, TelegramID: 123, Link: "http://invite.com/1", ExpiresAt: expireDate}).Return(nil)
+	mockIR.EXPECT().SaveInviteLink(&domain.InviteLink{ChatID: cfg.BirthdayGroupID, TelegramID: 456, Link: "http://invite.com/2", ExpiresAt: expireDate}).Return(nil)
+	mockIR.EXPECT().SaveInviteLink(gomock.Any()).Return(nil).Times(2)
+	mockTelegram.EXPECT().SendMessage(int64(123), "Join the group to congratulate the birthday for users: @user1, @user2. Link: http://invite.com/1"+
+		"\nwishlist of @user1:\n#1 book (claimed by you)\n#2 pen\nsend /wishlist claim \"number\" to buy a gift").Times(1)
+	mockTelegram.EXPECT().SendMessage(int64(456), "Join the group to congratulate the birthday for users: @user1, @user2. Link: http://invite.com/2"+wishlist).Times(1)
+	mockTelegram.EXPECT().SendMessage(int64(67890), gomock.Any()).Times(1)
+	mockTelegram.EXPECT().SendMessage(int64(789), gomock.Any()).Times(1)
+
+	bs.sendInviteForUsers(cfg.BirthdayGroupID, usersForSendInvite, &celebrants, birthdayUsers, expireDate)
 
 	logSlice := strings.Split(logBuf.String(), "\n")
 	if len(logSlice) > 0 {
 		logSlice = logSlice[:len(logSlice)-1]
 	}
 
-	assert.Equal(t, len(logSlice), 0)
+	assert.Equal(t, len(logSlice), 2)
+	assert.Contains(t, logSlice[0], "protected member is not unbanned")
 }
 
-func TestSendInviteForUsers_ErrGetInviteLink(t *testing.T) {
+func TestSendInviteForUsers_ErrCreateInviteLink(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
 	mockUserRepo := mock.NewMockUserRepo(ctrl)
+	mockIR := mock.NewMockInviteLinkRepo(ctrl)
 	mockTelegram := mock.NewMockTelegram(ctrl)
+	mockWS := mock.NewMockWishlist(ctrl)
+	mockMS := mock.NewMockModeration(ctrl)
 
 	var logBuf bytes.Buffer
 	log := slog.New(
@@ -76,12 +110,14 @@ func TestSendInviteForUsers_ErrGetInviteLink(t *testing.T) {
 
 	cfg := &config.Config{
 		BirthdayGroupID: 12345,
-		GroupOwnerID:    67890,
 	}
 
 	bs := &BirthdayService{
 		tg:  mockTelegram,
+		ws:  mockWS,
+		ms:  mockMS,
 		ur:  mockUserRepo,
+		ir:  mockIR,
 		cfg: cfg,
 		log: log,
 	}
@@ -93,31 +129,66 @@ func TestSendInviteForUsers_ErrGetInviteLink(t *testing.T) {
 
 	birthdayUsers := "@user1, @user2"
 
-	inviteLink := "http://invite.com"
-	mockTelegram.EXPECT().GetInviteLink(gomock.Any(), birthdayUsers).Return(inviteLink, errors.New("test")).Times(1)
+	mockWS.EXPECT().GetWishlists(gomock.Any()).Return(map[int64][]domain.WishlistItem{}, nil).AnyTimes()
 
-	mockTelegram.EXPECT().UnBanUser(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
-	mockTelegram.EXPECT().SendMessage(int64(123), gomock.Any()).Times(1)
-	mockTelegram.EXPECT().SendMessage(int64(456), gomock.Any()).Times(1)
+	mockTelegram.EXPECT().GetChatAdministrators(cfg.BirthdayGroupID).Return(nil, nil)
+	mockMS.EXPECT().Unban(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	mockTelegram.EXPECT().CreateInviteLink(cfg.BirthdayGroupID, gomock.Any(), gomock.Any()).Return("", errors.New("test")).Times(2)
+	mockIR.EXPECT().SaveInviteLink(gomock.Any()).Times(0)
+	mockTelegram.EXPECT().SendMessage(int64(123), "to invite birthday group with users celebrating: @user1, @user2, contact support").Times(1)
+	mockTelegram.EXPECT().SendMessage(int64(456), "to invite birthday group with users celebrating: @user1, @user2, contact support").Times(1)
 
-	bs.sendInviteForUsers(usersForSendInvite, birthdayUsers)
+	bs.sendInviteForUsers(cfg.BirthdayGroupID, usersForSendInvite, &[]domain.User{}, birthdayUsers, time.Now())
 
 	logSlice := strings.Split(logBuf.String(), "\n")
 	if len(logSlice) > 0 {
 		logSlice = logSlice[:len(logSlice)-1]
 	}
 
-	assert.Equal(t, len(logSlice), 1)
+	assert.Equal(t, len(logSlice), 2)
 	assert.Contains(t, logSlice[0], "error generate invite link")
 }
 
-func TestKickUsers_Success(t *testing.T) {
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+func TestSendInviteForUsers_UnknownAdministrators(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
 
+	mockIR := mock.NewMockInviteLinkRepo(ctrl)
+	mockTg := mock.NewMockTelegram(ctrl)
+	mockWS := mock.NewMockWishlist(ctrl)
+	mockMS := mock.NewMockModeration(ctrl)
+
+	bs := &BirthdayService{
+		tg:  mockTg,
+		ws:  mockWS,
+		ms:  mockMS,
+		ir:  mockIR,
+		cfg: &config.Config{ProtectedMembers: []int64{44444}},
+		log: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+
+	expireDate := time.Date(2024, 5, 10, 20, 0, 0, 0, time.UTC)
+	mockWS.EXPECT().GetWishlists(gomock.Any()).Return(map[int64][]domain.WishlistItem{}, nil)
+	mockTg.EXPECT().GetChatAdministrators(int64(12345)).Return(nil, errors.New("test"))
+	//co-admins may be among the users, the unban waits for the moderation retries
+	mockMS.EXPECT().Unban(gomock.Any(), gomock.Any()).Times(0)
+	mockMS.EXPECT().QueueUnban(int64(12345), int64(22222), gomock.Any()).Return(nil)
+	//configured protected members are known without the administrators and are never queued
+	mockMS.EXPECT().QueueUnban(int64(12345), int64(44444), gomock.Any()).Times(0)
+	mockTg.EXPECT().CreateInviteLink(int64(12345), gomock.Any(), expireDate).Return("http://invite.com", nil).Times(2)
+	mockIR.EXPECT().SaveInviteLink(gomock.Any()).Return(nil).Times(2)
+	mockTg.EXPECT().SendMessage(int64(22222), "Join the group to congratulate the birthday for users: @user1. Link: http://invite.com")
+	mockTg.EXPECT().SendMessage(int64(44444), "Join the group to congratulate the birthday for users: @user1. Link: http://invite.com")
+
+	bs.sendInviteForUsers(12345, &[]domain.User{{TelegramID: 22222, Username: "user2"}, {TelegramID: 44444, Username: "user4"}},
+		&[]domain.User{}, "@user1", expireDate)
+}
+
+func TestScheduleKicks(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
+	mockKR := mock.NewMockKickRepo(ctrl)
 	mockTg := mock.NewMockTelegram(ctrl)
 
 	var logBuf bytes.Buffer
@@ -125,159 +196,387 @@ func TestKickUsers_Success(t *testing.T) {
 		slog.NewTextHandler(&logBuf, &slog.HandlerOptions{Level: slog.LevelDebug}))
 
 	cfg := config.Config{
-		TimeToKick:      1 * time.Second,
-		BirthdayGroupID: 12345,
-		GroupOwnerID:    11111,
+		TimeToKick:       12 * time.Hour,
+		BirthdayGroupID:  12345,
+		ProtectedMembers: []int64{44444},
 	}
 
+	now := time.Date(2024, 5, 10, 8, 0, 0, 0, time.UTC)
 	bs := &BirthdayService{
+		kr:  mockKR,
 		tg:  mockTg,
 		log: log,
 		cfg: &cfg,
+		now: fixedNow(now),
 	}
 
 	usersToKick := []domain.User{
 		{TelegramID: 22222},
 		{TelegramID: 33333},
-		{TelegramID: 11111}, //group owner
+		{TelegramID: 44444}, //protected member
+		{TelegramID: 55555}, //administrator
 	}
 
-	mockTg.EXPECT().KickUser(cfg.BirthdayGroupID, int64(33333)).Return(nil).Times(1)
-	mockTg.EXPECT().KickUser(cfg.BirthdayGroupID, int64(22222)).Return(nil).Times(1)
-	mockTg.EXPECT().KickUser(cfg.BirthdayGroupID, int64(11111)).Return(nil).Times(0)
+	dueAt := now.Add(cfg.TimeToKick)
+	mockTg.EXPECT().GetChatAdministrators(cfg.BirthdayGroupID).Return([]int64{55555}, nil)
+	mockKR.EXPECT().ScheduleKick(&domain.Kick{ChatID: cfg.BirthdayGroupID, TelegramID: 22222, DueAt: dueAt}).Return(nil).Times(1)
+	mockKR.EXPECT().ScheduleKick(&domain.Kick{ChatID: cfg.BirthdayGroupID, TelegramID: 33333, DueAt: dueAt}).Return(errors.New("test")).Times(1)
 
-	go bs.kickUsers(ctx, &usersToKick)
-
-	time.Sleep(3 * cfg.TimeToKick)
+	bs.scheduleKicks(cfg.BirthdayGroupID, &usersToKick, dueAt)
 
 	logSlice := strings.Split(logBuf.String(), "\n")
 	if len(logSlice) > 0 {
 		logSlice = logSlice[:len(logSlice)-1]
 	}
-	assert.Equal(t, 0, len(logSlice))
+	assert.Equal(t, 1, len(logSlice))
 }
 
-func TestKickUsers_CtxDone(t *testing.T) {
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
+func TestKickDue_Success(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
+	mockKR := mock.NewMockKickRepo(ctrl)
+	mockGR := mock.NewMockGroupRepo(ctrl)
+	mockIR := mock.NewMockInviteLinkRepo(ctrl)
+	mockMR := mock.NewMockMembershipRepo(ctrl)
 	mockTg := mock.NewMockTelegram(ctrl)
+	mockMS := mock.NewMockModeration(ctrl)
 
 	var logBuf bytes.Buffer
 	log := slog.New(
 		slog.NewTextHandler(&logBuf, &slog.HandlerOptions{Level: slog.LevelDebug}))
 
-	cfg := config.Config{
-		TimeToKick:      10 * time.Second,
-		BirthdayGroupID: 12345,
-		GroupOwnerID:    11111,
+	now := time.Date(2024, 5, 10, 20, 0, 0, 0, time.UTC)
+	bs := &BirthdayService{
+		kr:  mockKR,
+		gr:  mockGR,
+		ir:  mockIR,
+		mr:  mockMR,
+		tg:  mockTg,
+		ms:  mockMS,
+		log: log,
+		cfg: &config.Config{ProtectedMembers: []int64{11111}},
+		now: fixedNow(now),
+	}
+
+	kicks := []domain.Kick{
+		{ID: 1, ChatID: 12345, TelegramID: 22222, DueAt: now},
+		{ID: 2, ChatID: 12345, TelegramID: 33333, DueAt: now},
+		{ID: 3, ChatID: 12345, TelegramID: 11111, DueAt: now}, //protected member
+		{ID: 4, ChatID: 12345, TelegramID: 44444, DueAt: now}, //group administrator
+	}
+
+	mockKR.EXPECT().GetDueKicks(now).Return(&kicks, nil)
+	mockMR.EXPECT().IsMember(int64(12345), gomock.Any()).Return(true, true, nil).Times(2)
+	mockTg.EXPECT().GetChatAdministrators(int64(12345)).Return([]int64{44444}, nil).Times(1)
+	mockMS.EXPECT().Kick(int64(12345), int64(22222)).Return(nil).Times(1)
+	mockMS.EXPECT().Kick(int64(12345), int64(33333)).Return(nil).Times(1)
+	mockKR.EXPECT().DeleteKick(gomock.Any()).Return(nil).Times(4)
+	mockMR.EXPECT().RecordLeave(int64(12345), int64(22222), now).Return(nil)
+	mockMR.EXPECT().RecordLeave(int64(12345), int64(33333), now).Return(nil)
+
+	mockIR.EXPECT().GetExpiredInviteLinks(now).Return(&[]domain.InviteLink{}, nil)
+	mockGR.EXPECT().ReleaseGroups(now).Return(nil)
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	bs.KickDue(context.Background(), wg)
+	wg.Wait()
+
+	logSlice := strings.Split(logBuf.String(), "\n")
+	if len(logSlice) > 0 {
+		logSlice = logSlice[:len(logSlice)-1]
 	}
+	assert.Equal(t, 2, len(logSlice))
+	assert.Contains(t, logSlice[0], "protected member is not kicked")
+	assert.Contains(t, logSlice[1], "telegram_id=44444")
+}
 
+func TestKickDue_UnknownAdministrators(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockKR := mock.NewMockKickRepo(ctrl)
+	mockGR := mock.NewMockGroupRepo(ctrl)
+	mockIR := mock.NewMockInviteLinkRepo(ctrl)
+	mockMR := mock.NewMockMembershipRepo(ctrl)
+	mockTg := mock.NewMockTelegram(ctrl)
+	mockMS := mock.NewMockModeration(ctrl)
+
+	now := time.Date(2024, 5, 10, 20, 0, 0, 0, time.UTC)
 	bs := &BirthdayService{
+		kr:  mockKR,
+		gr:  mockGR,
+		ir:  mockIR,
+		mr:  mockMR,
 		tg:  mockTg,
-		log: log,
-		cfg: &cfg,
+		ms:  mockMS,
+		log: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		cfg: &config.Config{},
+		now: fixedNow(now),
 	}
 
-	usersToKick := []domain.User{
-		{TelegramID: 22222},
-		{TelegramID: 33333},
-		{TelegramID: 11111}, //group owner
+	kicks := []domain.Kick{
+		{ID: 1, ChatID: 12345, TelegramID: 22222, DueAt: now},
+		{ID: 2, ChatID: 12345, TelegramID: 33333, DueAt: now},
+		{ID: 3, ChatID: 54321, TelegramID: 44444, DueAt: now},
 	}
 
-	mockTg.EXPECT().KickUser(cfg.BirthdayGroupID, int64(33333)).Return(nil).Times(1)
-	mockTg.EXPECT().KickUser(cfg.BirthdayGroupID, int64(22222)).Return(nil).Times(1)
-	mockTg.EXPECT().KickUser(cfg.BirthdayGroupID, int64(11111)).Return(nil).Times(0)
+	mockKR.EXPECT().GetDueKicks(now).Return(&kicks, nil)
+	//administrators of the chat are looked up once, its kicks stay for the next call
+	mockTg.EXPECT().GetChatAdministrators(int64(12345)).Return(nil, errors.New("test")).Times(1)
+	mockMS.EXPECT().Kick(int64(12345), gomock.Any()).Times(0)
+	mockKR.EXPECT().DeleteKick(&kicks[0]).Times(0)
+	mockKR.EXPECT().DeleteKick(&kicks[1]).Times(0)
 
-	timeout := time.NewTimer(2 * time.Second)
-	defer timeout.Stop()
+	mockTg.EXPECT().GetChatAdministrators(int64(54321)).Return(nil, nil)
+	mockMR.EXPECT().IsMember(int64(54321), int64(44444)).Return(true, true, nil)
+	mockMS.EXPECT().Kick(int64(54321), int64(44444)).Return(nil)
+	mockMR.EXPECT().RecordLeave(int64(54321), int64(44444), now).Return(nil)
+	mockKR.EXPECT().DeleteKick(&kicks[2]).Return(nil)
 
-	done := make(chan struct{})
+	mockIR.EXPECT().GetExpiredInviteLinks(now).Return(&[]domain.InviteLink{}, nil)
+	mockGR.EXPECT().ReleaseGroups(now).Return(nil)
 
-	go func() {
-		defer close(done)
-		bs.kickUsers(ctx, &usersToKick)
-	}()
-	time.Sleep(500 * time.Millisecond)
-	cancel()
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	bs.KickDue(context.Background(), wg)
+	wg.Wait()
+}
 
-	select {
-	case <-done:
-		//test completed within the timeout
-	case <-timeout.C:
-		t.Fatal("test timed out")
+func TestKickDue_KickErr(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockKR := mock.NewMockKickRepo(ctrl)
+	mockGR := mock.NewMockGroupRepo(ctrl)
+	mockIR := mock.NewMockInviteLinkRepo(ctrl)
+	mockMR := mock.NewMockMembershipRepo(ctrl)
+	mockTg := mock.NewMockTelegram(ctrl)
+	mockMS := mock.NewMockModeration(ctrl)
+
+	var logBuf bytes.Buffer
+	log := slog.New(
+		slog.NewTextHandler(&logBuf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	now := time.Date(2024, 5, 10, 20, 0, 0, 0, time.UTC)
+	bs := &BirthdayService{
+		kr:  mockKR,
+		gr:  mockGR,
+		ir:  mockIR,
+		mr:  mockMR,
+		tg:  mockTg,
+		ms:  mockMS,
+		log: log,
+		cfg: &config.Config{},
+		now: fixedNow(now),
 	}
 
+	kicks := []domain.Kick{
+		{ID: 1, ChatID: 12345, TelegramID: 22222, DueAt: now},
+	}
+
+	mockKR.EXPECT().GetDueKicks(now).Return(&kicks, nil)
+	mockTg.EXPECT().GetChatAdministrators(int64(12345)).Return(nil, nil)
+	mockMR.EXPECT().IsMember(int64(12345), int64(22222)).Return(true, true, nil)
+	mockMS.EXPECT().Kick(int64(12345), int64(22222)).Return(errors.New("test"))
+	mockMR.EXPECT().RecordLeave(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+	//the moderation queue retries the kick and asks the user to leave when retries are over
+	mockTg.EXPECT().SendMessage(gomock.Any(), gomock.Any()).Times(0)
+	mockKR.EXPECT().DeleteKick(&kicks[0]).Return(nil)
+
+	mockIR.EXPECT().GetExpiredInviteLinks(now).Return(&[]domain.InviteLink{}, nil)
+	mockGR.EXPECT().ReleaseGroups(now).Return(nil)
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	bs.KickDue(context.Background(), wg)
+	wg.Wait()
+
 	logSlice := strings.Split(logBuf.String(), "\n")
 	if len(logSlice) > 0 {
 		logSlice = logSlice[:len(logSlice)-1]
 	}
-	assert.Equal(t, 0, len(logSlice))
+	assert.Equal(t, 1, len(logSlice))
 }
 
-func TestKickUsers_KickErr(t *testing.T) {
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
+func TestKickDue_KicksOnlyMembers(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
+	mockKR := mock.NewMockKickRepo(ctrl)
+	mockGR := mock.NewMockGroupRepo(ctrl)
+	mockIR := mock.NewMockInviteLinkRepo(ctrl)
+	mockMR := mock.NewMockMembershipRepo(ctrl)
 	mockTg := mock.NewMockTelegram(ctrl)
+	mockMS := mock.NewMockModeration(ctrl)
 
 	var logBuf bytes.Buffer
 	log := slog.New(
 		slog.NewTextHandler(&logBuf, &slog.HandlerOptions{Level: slog.LevelDebug}))
 
-	cfg := config.Config{
-		TimeToKick:      1 * time.Second,
-		BirthdayGroupID: 12345,
-		GroupOwnerID:    11111,
-	}
-
+	now := time.Date(2024, 5, 10, 20, 0, 0, 0, time.UTC)
 	bs := &BirthdayService{
+		kr:  mockKR,
+		gr:  mockGR,
+		ir:  mockIR,
+		mr:  mockMR,
 		tg:  mockTg,
+		ms:  mockMS,
 		log: log,
-		cfg: &cfg,
+		cfg: &config.Config{},
+		now: fixedNow(now),
 	}
 
-	usersToKick := []domain.User{
-		{TelegramID: 22222},
-		{TelegramID: 33333},
-		{TelegramID: 11111}, //group owner
+	kicks := []domain.Kick{
+		{ID: 1, ChatID: 12345, TelegramID: 22222, DueAt: now}, //already left
+		{ID: 2, ChatID: 12345, TelegramID: 33333, DueAt: now}, //membership unknown
+		{ID: 3, ChatID: 12345, TelegramID: 44444, DueAt: now}, //in the group before the tracking
+		{ID: 4, ChatID: 12345, TelegramID: 55555, DueAt: now}, //never joined
+		{ID: 5, ChatID: 12345, TelegramID: 66666, DueAt: now}, //telegram doesn't answer
 	}
 
-	mockTg.EXPECT().KickUser(cfg.BirthdayGroupID, int64(33333)).Return(errors.New("test")).Times(1)
-	mockTg.EXPECT().KickUser(cfg.BirthdayGroupID, int64(22222)).Return(errors.New("test")).Times(1)
-	mockTg.EXPECT().KickUser(cfg.BirthdayGroupID, int64(11111)).Return(nil).Times(0)
+	mockKR.EXPECT().GetDueKicks(now).Return(&kicks, nil)
+	mockTg.EXPECT().GetChatAdministrators(int64(12345)).Return(nil, nil)
+	mockMR.EXPECT().IsMember(int64(12345), int64(22222)).Return(false, true, nil)
+	mockMR.EXPECT().IsMember(int64(12345), int64(33333)).Return(false, false, errors.New("test"))
+	mockMR.EXPECT().IsMember(int64(12345), int64(44444)).Return(false, false, nil)
+	mockMR.EXPECT().IsMember(int64(12345), int64(55555)).Return(false, false, nil)
+	mockMR.EXPECT().IsMember(int64(12345), int64(66666)).Return(false, false, nil)
+	mockTg.EXPECT().IsChatMember(int64(12345), int64(44444)).Return(true, nil)
+	mockTg.EXPECT().IsChatMember(int64(12345), int64(55555)).Return(false, nil)
+	mockTg.EXPECT().IsChatMember(int64(12345), int64(66666)).Return(false, errors.New("test"))
+	mockMS.EXPECT().Kick(int64(12345), int64(44444)).Return(nil)
+	mockTg.EXPECT().SendMessage(gomock.Any(), gomock.Any()).Times(0)
+	mockMR.EXPECT().RecordLeave(int64(12345), int64(44444), now).Return(nil)
+	//kicks of unknown membership stay for the next call
+	mockKR.EXPECT().DeleteKick(&kicks[0]).Return(nil)
+	mockKR.EXPECT().DeleteKick(&kicks[2]).Return(nil)
+	mockKR.EXPECT().DeleteKick(&kicks[3]).Return(nil)
+	mockIR.EXPECT().GetExpiredInviteLinks(now).Return(&[]domain.InviteLink{}, nil)
+	mockGR.EXPECT().ReleaseGroups(now).Return(nil)
 
-	mockTg.EXPECT().SendMessage(int64(22222), "please, leave from group. We'll wait for next birthday")
-	mockTg.EXPECT().SendMessage(int64(33333), "please, leave from group. We'll wait for next birthday")
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	bs.KickDue(context.Background(), wg)
+	wg.Wait()
 
-	timeout := time.NewTimer(2 * time.Second)
-	defer timeout.Stop()
+	logSlice := strings.Split(logBuf.String(), "\n")
+	if len(logSlice) > 0 {
+		logSlice = logSlice[:len(logSlice)-1]
+	}
+	assert.Equal(t, 2, len(logSlice))
+	assert.Contains(t, logSlice[0], "kick is postponed")
+	assert.Contains(t, logSlice[1], "telegram_id=66666")
+}
 
-	done := make(chan struct{})
+func TestMemberJoined(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
 
-	go func() {
-		defer close(done)
-		bs.kickUsers(ctx, &usersToKick)
-	}()
+	mockMR := mock.NewMockMembershipRepo(ctrl)
 
-	select {
-	case <-done:
-		//test completed within the timeout
-	case <-timeout.C:
-		t.Fatal("test timed out")
+	bs := &BirthdayService{
+		mr:  mockMR,
+		log: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		cfg: &config.Config{BirthdayGroupIDs: []int64{12345, 54321}},
 	}
 
+	at := time.Date(2024, 5, 10, 9, 0, 0, 0, time.UTC)
+	mockMR.EXPECT().RecordJoin(int64(54321), int64(22222), at).Return(nil)
+	mockMR.EXPECT().RecordLeave(int64(12345), int64(22222), at).Return(errors.New("test"))
+
+	assert.NoError(t, bs.MemberJoined(54321, 22222, at))
+	//chats outside the pool aren't tracked
+	assert.NoError(t, bs.MemberJoined(777, 22222, at))
+	assert.NoError(t, bs.MemberLeft(777, 22222, at))
+	assert.Error(t, bs.MemberLeft(12345, 22222, at))
+}
+
+func TestKickDue_RevokesExpiredInviteLinks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockKR := mock.NewMockKickRepo(ctrl)
+	mockGR := mock.NewMockGroupRepo(ctrl)
+	mockIR := mock.NewMockInviteLinkRepo(ctrl)
+	mockTg := mock.NewMockTelegram(ctrl)
+
+	var logBuf bytes.Buffer
+	log := slog.New(
+		slog.NewTextHandler(&logBuf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	now := time.Date(2024, 5, 10, 20, 0, 0, 0, time.UTC)
+	bs := &BirthdayService{
+		kr:  mockKR,
+		gr:  mockGR,
+		ir:  mockIR,
+		tg:  mockTg,
+		log: log,
+		cfg: &config.Config{},
+		now: fixedNow(now),
+	}
+
+	links := []domain.InviteLink{
+		{ID: 1, ChatID: 12345, TelegramID: 22222, Link: "http://invite.com/1", ExpiresAt: now},
+		{ID: 2, ChatID: 12345, TelegramID: 33333, Link: "http://invite.com/2", ExpiresAt: now},
+	}
+
+	mockKR.EXPECT().GetDueKicks(now).Return(&[]domain.Kick{}, nil)
+	mockIR.EXPECT().GetExpiredInviteLinks(now).Return(&links, nil)
+	mockTg.EXPECT().RevokeInviteLink(int64(12345), "http://invite.com/1").Return(nil)
+	mockTg.EXPECT().RevokeInviteLink(int64(12345), "http://invite.com/2").Return(errors.New("test"))
+	//a link that can't be revoked isn't retried, it expires anyway
+	mockIR.EXPECT().DeleteInviteLink(&links[0]).Return(nil)
+	mockIR.EXPECT().DeleteInviteLink(&links[1]).Return(nil)
+	mockGR.EXPECT().ReleaseGroups(now).Return(nil)
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	bs.KickDue(context.Background(), wg)
+	wg.Wait()
+
 	logSlice := strings.Split(logBuf.String(), "\n")
 	if len(logSlice) > 0 {
 		logSlice = logSlice[:len(logSlice)-1]
 	}
-	assert.Equal(t, 2, len(logSlice))
+	assert.Equal(t, 1, len(logSlice))
+	assert.Contains(t, logSlice[0], "error revoke invite link")
+}
+
+func TestKickDue_CtxDoneLeavesPendingKicks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockKR := mock.NewMockKickRepo(ctrl)
+	mockTg := mock.NewMockTelegram(ctrl)
+	mockMS := mock.NewMockModeration(ctrl)
+
+	now := time.Date(2024, 5, 10, 20, 0, 0, 0, time.UTC)
+	bs := &BirthdayService{
+		kr:  mockKR,
+		tg:  mockTg,
+		ms:  mockMS,
+		log: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		cfg: &config.Config{},
+		now: fixedNow(now),
+	}
+
+	kicks := []domain.Kick{
+		{ID: 1, ChatID: 12345, TelegramID: 22222, DueAt: now},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	mockKR.EXPECT().GetDueKicks(now).Return(&kicks, nil)
+	mockMS.EXPECT().Kick(gomock.Any(), gomock.Any()).Times(0)
+	mockKR.EXPECT().DeleteKick(gomock.Any()).Times(0)
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	bs.KickDue(ctx, wg)
+	wg.Wait()
 }
 
 func TestBirthdayNotify_Success(t *testing.T) {
@@ -285,7 +584,14 @@ func TestBirthdayNotify_Success(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockUR := mock.NewMockUserRepo(ctrl)
+	mockJR := mock.NewMockJobRunRepo(ctrl)
+	mockKR := mock.NewMockKickRepo(ctrl)
+	mockGR := mock.NewMockGroupRepo(ctrl)
+	mockIR := mock.NewMockInviteLinkRepo(ctrl)
 	mockTg := mock.NewMockTelegram(ctrl)
+	mockWS := mock.NewMockWishlist(ctrl)
+	mockMS := mock.NewMockModeration(ctrl)
+	mockFS := mock.NewMockFund(ctrl)
 
 	var logBuf bytes.Buffer
 	log := slog.New(
@@ -294,13 +600,22 @@ func TestBirthdayNotify_Success(t *testing.T) {
 	cfg := config.Config{
 		BirthdayGroupID: 12345,
 		TimeToKick:      1 * time.Second,
+		NotifyHour:      8,
 	}
 
 	bs := &BirthdayService{
 		ur:  mockUR,
+		jr:  mockJR,
+		kr:  mockKR,
+		gr:  mockGR,
+		ir:  mockIR,
 		tg:  mockTg,
+		ws:  mockWS,
+		ms:  mockMS,
+		fs:  mockFS,
 		log: log,
 		cfg: &cfg,
+		now: fixedNow(time.Date(2024, 5, 10, 8, 0, 0, 0, time.UTC)),
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -317,14 +632,25 @@ func TestBirthdayNotify_Success(t *testing.T) {
 		{Username: "sub1", TelegramID: 33333},
 	}
 
-	mockUR.EXPECT().GetUsersWithBirthdayToday().Return(&birthdayUsers, nil)
+	mockUR.EXPECT().GetTimezones().Return([]string{"UTC"}, nil)
+	mockJR.EXPECT().ClaimJobRun(gomock.Any()).Return(true, nil)
+	mockUR.EXPECT().GetUsersWithBirthdayOn(gomock.Any(), "UTC").Return(&birthdayUsers, nil)
+	mockGR.EXPECT().AllocateGroup([]int64{cfg.BirthdayGroupID}, gomock.Any(), gomock.Any()).Return(cfg.BirthdayGroupID, false, nil)
 	mockUR.EXPECT().GetUsersSubscribedToUsers(&birthdayUsers).Return(&subscribers, nil)
+	mockIR.EXPECT().HasActiveInviteLink(cfg.BirthdayGroupID, int64(22222), bs.now()).Return(false, nil)
 	mockTg.EXPECT().SendMessage(cfg.BirthdayGroupID, "happy birthday @user1")
 
 	mockTg.EXPECT().SendMessage(gomock.Any(), gomock.Any()).AnyTimes().Times(2)
-	mockTg.EXPECT().GetInviteLink(gomock.Any(), gomock.Any()).AnyTimes().Times(1)
-	mockTg.EXPECT().UnBanUser(gomock.Any(), gomock.Any()).Return(nil).Times(2)
-	mockTg.EXPECT().KickUser(gomock.Any(), gomock.Any()).Return(nil).Times(2)
+	mockTg.EXPECT().CreateInviteLink(cfg.BirthdayGroupID, gomock.Any(), bs.now().Add(cfg.TimeToKick)).Return("http://invite.com", nil).Times(2)
+	mockIR.EXPECT().SaveInviteLink(gomock.Any()).Return(nil).Times(2)
+	mockWS.EXPECT().GetWishlists(gomock.Any()).Return(map[int64][]domain.WishlistItem{}, nil).AnyTimes()
+	mockTg.EXPECT().GetChatAdministrators(gomock.Any()).Return(nil, nil).AnyTimes()
+	mockMS.EXPECT().Unban(gomock.Any(), gomock.Any()).Return(nil).Times(2)
+	//the celebrant is in the group, funds open only with surprise planning
+	mockFS.EXPECT().OpenFund(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+	//without a fund claimed gifts are given at the celebration
+	mockWS.EXPECT().GiveClaimedItems([]int64{22222}).Return(nil)
+	mockKR.EXPECT().ScheduleKick(gomock.Any()).Return(nil).Times(2)
 
 	timeout := time.NewTimer(2 * time.Second)
 	defer timeout.Stop()
@@ -356,6 +682,7 @@ func TestBirthdayNotify_WithoutBirthday(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockUR := mock.NewMockUserRepo(ctrl)
+	mockJR := mock.NewMockJobRunRepo(ctrl)
 	mockTg := mock.NewMockTelegram(ctrl)
 
 	var logBuf bytes.Buffer
@@ -365,13 +692,16 @@ func TestBirthdayNotify_WithoutBirthday(t *testing.T) {
 	cfg := config.Config{
 		BirthdayGroupID: 12345,
 		TimeToKick:      1 * time.Second,
+		NotifyHour:      8,
 	}
 
 	bs := &BirthdayService{
 		ur:  mockUR,
+		jr:  mockJR,
 		tg:  mockTg,
 		log: log,
 		cfg: &cfg,
+		now: fixedNow(time.Date(2024, 5, 10, 8, 0, 0, 0, time.UTC)),
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -382,7 +712,9 @@ func TestBirthdayNotify_WithoutBirthday(t *testing.T) {
 
 	birthdayUsers := []domain.User{}
 
-	mockUR.EXPECT().GetUsersWithBirthdayToday().Return(&birthdayUsers, nil)
+	mockUR.EXPECT().GetTimezones().Return([]string{"UTC"}, nil)
+	mockJR.EXPECT().ClaimJobRun(gomock.Any()).Return(true, nil)
+	mockUR.EXPECT().GetUsersWithBirthdayOn(gomock.Any(), "UTC").Return(&birthdayUsers, nil)
 
 	timeout := time.NewTimer(2 * time.Second)
 	defer timeout.Stop()
@@ -414,6 +746,7 @@ func TestBirthdayNotify_WithoutSubscribers(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockUR := mock.NewMockUserRepo(ctrl)
+	mockJR := mock.NewMockJobRunRepo(ctrl)
 	mockTg := mock.NewMockTelegram(ctrl)
 
 	var logBuf bytes.Buffer
@@ -423,13 +756,16 @@ func TestBirthdayNotify_WithoutSubscribers(t *testing.T) {
 	cfg := config.Config{
 		BirthdayGroupID: 12345,
 		TimeToKick:      1 * time.Second,
+		NotifyHour:      8,
 	}
 
 	bs := &BirthdayService{
 		ur:  mockUR,
+		jr:  mockJR,
 		tg:  mockTg,
 		log: log,
 		cfg: &cfg,
+		now: fixedNow(time.Date(2024, 5, 10, 8, 0, 0, 0, time.UTC)),
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -444,7 +780,9 @@ func TestBirthdayNotify_WithoutSubscribers(t *testing.T) {
 
 	subscribers := []domain.User{}
 
-	mockUR.EXPECT().GetUsersWithBirthdayToday().Return(&birthdayUsers, nil)
+	mockUR.EXPECT().GetTimezones().Return([]string{"UTC"}, nil)
+	mockJR.EXPECT().ClaimJobRun(gomock.Any()).Return(true, nil)
+	mockUR.EXPECT().GetUsersWithBirthdayOn(gomock.Any(), "UTC").Return(&birthdayUsers, nil)
 	mockUR.EXPECT().GetUsersSubscribedToUsers(&birthdayUsers).Return(&subscribers, nil)
 
 	timeout := time.NewTimer(2 * time.Second)
@@ -472,11 +810,12 @@ func TestBirthdayNotify_WithoutSubscribers(t *testing.T) {
 	assert.Equal(t, 0, len(logSlice))
 }
 
-func TestBirthdayNotify_ErrGetUsersWithBirthdayToday(t *testing.T) {
+func TestBirthdayNotify_ErrGetUsersWithBirthdayOn(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
 	mockUR := mock.NewMockUserRepo(ctrl)
+	mockJR := mock.NewMockJobRunRepo(ctrl)
 	mockTg := mock.NewMockTelegram(ctrl)
 
 	var logBuf bytes.Buffer
@@ -486,13 +825,16 @@ func TestBirthdayNotify_ErrGetUsersWithBirthdayToday(t *testing.T) {
 	cfg := config.Config{
 		BirthdayGroupID: 12345,
 		TimeToKick:      1 * time.Second,
+		NotifyHour:      8,
 	}
 
 	bs := &BirthdayService{
 		ur:  mockUR,
+		jr:  mockJR,
 		tg:  mockTg,
 		log: log,
 		cfg: &cfg,
+		now: fixedNow(time.Date(2024, 5, 10, 8, 0, 0, 0, time.UTC)),
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -505,7 +847,10 @@ func TestBirthdayNotify_ErrGetUsersWithBirthdayToday(t *testing.T) {
 		{Username: "user1", TelegramID: 22222},
 	}
 
-	mockUR.EXPECT().GetUsersWithBirthdayToday().Return(&birthdayUsers, errors.New("test"))
+	mockUR.EXPECT().GetTimezones().Return([]string{"UTC"}, nil)
+	mockJR.EXPECT().ClaimJobRun(gomock.Any()).Return(true, nil)
+	mockUR.EXPECT().GetUsersWithBirthdayOn(gomock.Any(), "UTC").Return(&birthdayUsers, errors.New("test"))
+	mockJR.EXPECT().ReleaseJobRun(gomock.Any()).Return(nil)
 
 	timeout := time.NewTimer(2 * time.Second)
 	defer timeout.Stop()
@@ -537,6 +882,7 @@ func TestBirthdayNotify_ErrGetSubscribers(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockUR := mock.NewMockUserRepo(ctrl)
+	mockJR := mock.NewMockJobRunRepo(ctrl)
 	mockTg := mock.NewMockTelegram(ctrl)
 
 	var logBuf bytes.Buffer
@@ -546,13 +892,16 @@ func TestBirthdayNotify_ErrGetSubscribers(t *testing.T) {
 	cfg := config.Config{
 		BirthdayGroupID: 12345,
 		TimeToKick:      1 * time.Second,
+		NotifyHour:      8,
 	}
 
 	bs := &BirthdayService{
 		ur:  mockUR,
+		jr:  mockJR,
 		tg:  mockTg,
 		log: log,
 		cfg: &cfg,
+		now: fixedNow(time.Date(2024, 5, 10, 8, 0, 0, 0, time.UTC)),
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -569,8 +918,12 @@ func TestBirthdayNotify_ErrGetSubscribers(t *testing.T) {
 		{Username: "sub1", TelegramID: 33333},
 	}
 
-	mockUR.EXPECT().GetUsersWithBirthdayToday().Return(&birthdayUsers, nil)
+	mockUR.EXPECT().GetTimezones().Return([]string{"UTC"}, nil)
+	mockJR.EXPECT().ClaimJobRun(gomock.Any()).Return(true, nil)
+	mockUR.EXPECT().GetUsersWithBirthdayOn(gomock.Any(), "UTC").Return(&birthdayUsers, nil)
 	mockUR.EXPECT().GetUsersSubscribedToUsers(&birthdayUsers).Return(&subscribers, errors.New("test"))
+	//the date is celebrated again on the next call
+	mockJR.EXPECT().ReleaseJobRun(gomock.Any()).Return(nil)
 
 	timeout := time.NewTimer(2 * time.Second)
 	defer timeout.Stop()
@@ -596,3 +949,737 @@ func TestBirthdayNotify_ErrGetSubscribers(t *testing.T) {
 	}
 	assert.Equal(t, 1, len(logSlice))
 }
+
+func TestBirthdayNotify_PartialFailure(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUR := mock.NewMockUserRepo(ctrl)
+	mockJR := mock.NewMockJobRunRepo(ctrl)
+	mockKR := mock.NewMockKickRepo(ctrl)
+	mockGR := mock.NewMockGroupRepo(ctrl)
+	mockIR := mock.NewMockInviteLinkRepo(ctrl)
+	mockTg := mock.NewMockTelegram(ctrl)
+	mockWS := mock.NewMockWishlist(ctrl)
+	mockMS := mock.NewMockModeration(ctrl)
+
+	cfg := config.Config{
+		BirthdayGroupID: 12345,
+		TimeToKick:      12 * time.Hour,
+		NotifyHour:      8,
+	}
+
+	bs := &BirthdayService{
+		ur:  mockUR,
+		jr:  mockJR,
+		kr:  mockKR,
+		gr:  mockGR,
+		ir:  mockIR,
+		tg:  mockTg,
+		ws:  mockWS,
+		ms:  mockMS,
+		log: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		cfg: &cfg,
+		now: fixedNow(time.Date(2024, 5, 10, 8, 0, 0, 0, time.UTC)),
+	}
+
+	birthdayUsers := []domain.User{{Username: "user1", TelegramID: 22222}}
+	subscribers := []domain.User{{Username: "sub1", TelegramID: 33333}}
+
+	mockUR.EXPECT().GetTimezones().Return([]string{"UTC"}, nil)
+	mockJR.EXPECT().ClaimJobRun(gomock.Any()).Return(true, nil)
+	mockUR.EXPECT().GetUsersWithBirthdayOn(gomock.Any(), "UTC").Return(&birthdayUsers, nil)
+	mockUR.EXPECT().GetUsersSubscribedToUsers(&birthdayUsers).Return(&subscribers, nil)
+	mockIR.EXPECT().HasActiveInviteLink(cfg.BirthdayGroupID, int64(22222), bs.now()).Return(false, nil)
+	mockGR.EXPECT().AllocateGroup([]int64{cfg.BirthdayGroupID}, gomock.Any(), gomock.Any()).Return(cfg.BirthdayGroupID, false, nil)
+	mockTg.EXPECT().GetChatAdministrators(cfg.BirthdayGroupID).Return(nil, nil).Times(2)
+	mockWS.EXPECT().GetWishlists(gomock.Any()).Return(map[int64][]domain.WishlistItem{}, nil)
+	mockMS.EXPECT().Unban(cfg.BirthdayGroupID, gomock.Any()).Return(nil).Times(2)
+	mockTg.EXPECT().CreateInviteLink(cfg.BirthdayGroupID, "birthday @user1", gomock.Any()).Return("http://invite.com", nil)
+	mockIR.EXPECT().SaveInviteLink(gomock.Any()).Return(nil)
+	mockTg.EXPECT().SendMessage(int64(22222), gomock.Any())
+	mockTg.EXPECT().CreateInviteLink(cfg.BirthdayGroupID, "birthday @sub1", gomock.Any()).Return("", errors.New("test"))
+	mockTg.EXPECT().SendMessage(int64(33333), "to invite birthday group with users celebrating: @user1, contact support")
+	mockTg.EXPECT().SendMessage(cfg.BirthdayGroupID, "happy birthday @user1")
+	mockWS.EXPECT().GiveClaimedItems([]int64{22222}).Return(nil)
+	mockKR.EXPECT().ScheduleKick(gomock.Any()).Return(nil).Times(2)
+	//the subscriber without a link is invited on the next call
+	mockJR.EXPECT().ReleaseJobRun(gomock.Any()).Return(nil)
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	bs.BirthdayNotify(context.Background(), wg)
+	wg.Wait()
+}
+
+func TestBirthdayNotify_Retry(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUR := mock.NewMockUserRepo(ctrl)
+	mockJR := mock.NewMockJobRunRepo(ctrl)
+	mockKR := mock.NewMockKickRepo(ctrl)
+	mockGR := mock.NewMockGroupRepo(ctrl)
+	mockIR := mock.NewMockInviteLinkRepo(ctrl)
+	mockTg := mock.NewMockTelegram(ctrl)
+	mockWS := mock.NewMockWishlist(ctrl)
+	mockMS := mock.NewMockModeration(ctrl)
+
+	cfg := config.Config{
+		BirthdayGroupIDs: []int64{12345, 54321},
+		TimeToKick:       12 * time.Hour,
+		NotifyHour:       8,
+		CatchUpWindow:    24 * time.Hour,
+	}
+
+	bs := &BirthdayService{
+		ur:  mockUR,
+		jr:  mockJR,
+		kr:  mockKR,
+		gr:  mockGR,
+		ir:  mockIR,
+		tg:  mockTg,
+		ws:  mockWS,
+		ms:  mockMS,
+		log: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		cfg: &cfg,
+		now: fixedNow(time.Date(2024, 5, 10, 9, 0, 0, 0, time.UTC)),
+	}
+
+	birthdayUsers := []domain.User{{Username: "user1", TelegramID: 22222}}
+	subscribers := []domain.User{{Username: "sub1", TelegramID: 33333}}
+
+	mockUR.EXPECT().GetTimezones().Return([]string{"UTC"}, nil)
+	mockJR.EXPECT().ClaimJobRun(gomock.Any()).Return(true, nil)
+	mockUR.EXPECT().GetUsersWithBirthdayOn(gomock.Any(), "UTC").Return(&birthdayUsers, nil)
+	mockUR.EXPECT().GetUsersSubscribedToUsers(&birthdayUsers).Return(&subscribers, nil)
+	//the celebrant was invited to the second group of the pool by the failed call
+	mockIR.EXPECT().HasActiveInviteLink(int64(12345), int64(22222), bs.now()).Return(false, nil)
+	mockIR.EXPECT().HasActiveInviteLink(int64(54321), int64(22222), bs.now()).Return(true, nil).Times(2)
+	mockIR.EXPECT().HasActiveInviteLink(int64(54321), int64(33333), bs.now()).Return(false, nil)
+	mockGR.EXPECT().AllocateGroup(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+	mockTg.EXPECT().GetChatAdministrators(int64(54321)).Return(nil, nil).Times(2)
+	mockWS.EXPECT().GetWishlists(gomock.Any()).Return(map[int64][]domain.WishlistItem{}, nil)
+	mockMS.EXPECT().Unban(int64(54321), int64(33333)).Return(nil)
+	mockTg.EXPECT().CreateInviteLink(int64(54321), "birthday @sub1", gomock.Any()).Return("http://invite.com", nil)
+	mockIR.EXPECT().SaveInviteLink(gomock.Any()).Return(nil)
+	mockTg.EXPECT().SendMessage(int64(33333), "Join the group to congratulate the birthday for users: @user1. Link: http://invite.com")
+	//the group already got the greeting and the claimed gifts are given
+	mockTg.EXPECT().SendMessage(int64(54321), gomock.Any()).Times(0)
+	mockWS.EXPECT().GiveClaimedItems(gomock.Any()).Times(0)
+	mockKR.EXPECT().ScheduleKick(gomock.Any()).Return(nil).Times(2)
+	mockJR.EXPECT().ReleaseJobRun(gomock.Any()).Times(0)
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	bs.BirthdayNotify(context.Background(), wg)
+	wg.Wait()
+}
+
+func TestBirthdayNotify_Timezones(t *testing.T) {
+	tokyo, lErr := time.LoadLocation("Asia/Tokyo")
+	if lErr != nil {
+		t.Fatal(lErr)
+	}
+
+	tests := []struct {
+		name     string
+		now      time.Time
+		timezone string
+		date     time.Time
+	}{
+		{
+			name:     "notify hour in UTC",
+			now:      time.Date(2024, 5, 10, 8, 0, 0, 0, time.UTC),
+			timezone: "UTC",
+			date:     time.Date(2024, 5, 10, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "notify hour in Tokyo is the next day",
+			now:      time.Date(2024, 5, 10, 23, 0, 0, 0, time.UTC),
+			timezone: "Asia/Tokyo",
+			date:     time.Date(2024, 5, 11, 8, 0, 0, 0, tokyo),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockUR := mock.NewMockUserRepo(ctrl)
+			mockJR := mock.NewMockJobRunRepo(ctrl)
+			mockTg := mock.NewMockTelegram(ctrl)
+
+			var logBuf bytes.Buffer
+			log := slog.New(
+				slog.NewTextHandler(&logBuf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+			bs := &BirthdayService{
+				ur:  mockUR,
+				jr:  mockJR,
+				tg:  mockTg,
+				log: log,
+				cfg: &config.Config{NotifyHour: 8},
+				now: fixedNow(tt.now),
+			}
+
+			birthdayUsers := []domain.User{}
+
+			mockUR.EXPECT().GetTimezones().Return([]string{"UTC", "Asia/Tokyo", "Unknown/Zone"}, nil)
+			mockJR.EXPECT().ClaimJobRun(gomock.Any()).Return(true, nil).Times(1)
+			mockUR.EXPECT().GetUsersWithBirthdayOn(gomock.Any(), tt.timezone).DoAndReturn(
+				func(date time.Time, timezone string) (*[]domain.User, error) {
+					assert.True(t, date.Equal(tt.date))
+					assert.Equal(t, tt.date.Day(), date.Day())
+					return &birthdayUsers, nil
+				}).Times(1)
+
+			wg := &sync.WaitGroup{}
+			wg.Add(1)
+			bs.BirthdayNotify(context.Background(), wg)
+			wg.Wait()
+
+			logSlice := strings.Split(logBuf.String(), "\n")
+			if len(logSlice) > 0 {
+				logSlice = logSlice[:len(logSlice)-1]
+			}
+			assert.Equal(t, 1, len(logSlice))
+			assert.Contains(t, logSlice[0], "error load timezone")
+		})
+	}
+}
+
+func TestBirthdayNotify_WeekendShift(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUR := mock.NewMockUserRepo(ctrl)
+	mockJR := mock.NewMockJobRunRepo(ctrl)
+	mockKR := mock.NewMockKickRepo(ctrl)
+	mockGR := mock.NewMockGroupRepo(ctrl)
+	mockIR := mock.NewMockInviteLinkRepo(ctrl)
+	mockTg := mock.NewMockTelegram(ctrl)
+	mockWS := mock.NewMockWishlist(ctrl)
+	mockMS := mock.NewMockModeration(ctrl)
+	mockFS := mock.NewMockFund(ctrl)
+
+	cfg := config.Config{
+		BirthdayGroupID: 12345,
+		NotifyHour:      8,
+	}
+
+	bs := &BirthdayService{
+		ur:       mockUR,
+		jr:       mockJR,
+		kr:       mockKR,
+		gr:       mockGR,
+		ir:       mockIR,
+		tg:       mockTg,
+		ws:       mockWS,
+		ms:       mockMS,
+		fs:       mockFS,
+		log:      slog.New(slog.NewTextHandler(io.Discard, nil)),
+		cfg:      &cfg,
+		now:      fixedNow(time.Date(2024, 5, 17, 8, 0, 0, 0, time.UTC)), //friday
+		calendar: &domain.WorkCalendar{Shift: domain.ShiftPrevious},
+	}
+
+	birthdays := map[string][]domain.User{
+		"2024-05-17": {{Username: "friday", TelegramID: 22222}},
+		"2024-05-18": {{Username: "saturday", TelegramID: 44444}},
+	}
+	subscribers := []domain.User{
+		{Username: "sub1", TelegramID: 33333},
+	}
+
+	var queried []string
+	mockUR.EXPECT().GetTimezones().Return([]string{"UTC"}, nil)
+	mockJR.EXPECT().ClaimJobRun(gomock.Any()).Return(true, nil)
+	mockUR.EXPECT().GetUsersWithBirthdayOn(gomock.Any(), "UTC").DoAndReturn(func(date time.Time, timezone string) (*[]domain.User, error) {
+		queried = append(queried, date.Format(time.DateOnly))
+		users := birthdays[date.Format(time.DateOnly)]
+		return &users, nil
+	}).Times(3)
+	mockGR.EXPECT().AllocateGroup([]int64{cfg.BirthdayGroupID}, gomock.Any(), gomock.Any()).Return(cfg.BirthdayGroupID, false, nil)
+	mockUR.EXPECT().GetUsersSubscribedToUsers(gomock.Any()).Return(&subscribers, nil)
+
+	celebrants := "@friday, @saturday (birthday on 18.05)"
+	mockIR.EXPECT().HasActiveInviteLink(cfg.BirthdayGroupID, gomock.Any(), gomock.Any()).Return(false, nil).Times(2)
+	mockTg.EXPECT().CreateInviteLink(cfg.BirthdayGroupID, gomock.Any(), gomock.Any()).Return("http://invite.com", nil).Times(3)
+	mockIR.EXPECT().SaveInviteLink(gomock.Any()).Return(nil).Times(3)
+	mockWS.EXPECT().GetWishlists(gomock.Any()).Return(map[int64][]domain.WishlistItem{}, nil).AnyTimes()
+	mockTg.EXPECT().GetChatAdministrators(gomock.Any()).Return(nil, nil).AnyTimes()
+	mockMS.EXPECT().Unban(gomock.Any(), gomock.Any()).Return(nil).Times(3)
+	mockTg.EXPECT().SendMessage(cfg.BirthdayGroupID, "happy birthday "+celebrants)
+	mockTg.EXPECT().SendMessage(gomock.Any(), gomock.Any()).Times(3)
+	mockFS.EXPECT().OpenFund(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+	mockWS.EXPECT().GiveClaimedItems([]int64{22222, 44444}).Return(nil)
+	mockKR.EXPECT().ScheduleKick(gomock.Any()).Return(nil).Times(3)
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	bs.BirthdayNotify(context.Background(), wg)
+	wg.Wait()
+
+	assert.Equal(t, []string{"2024-05-17", "2024-05-18", "2024-05-19"}, queried)
+}
+
+func fixedNow(now time.Time) func() time.Time {
+	return func() time.Time {
+		return now
+	}
+}
+
+func TestBirthdayNotify_CatchUp(t *testing.T) {
+	tests := []struct {
+		name    string
+		now     time.Time
+		window  time.Duration
+		claimed map[string]bool
+		due     []string
+	}{
+		{
+			name:   "restart mid-day runs today",
+			now:    time.Date(2024, 5, 10, 14, 0, 0, 0, time.UTC),
+			window: 24 * time.Hour,
+			due:    []string{"2024-05-10"},
+		},
+		{
+			name:    "today already processed",
+			now:     time.Date(2024, 5, 10, 14, 0, 0, 0, time.UTC),
+			window:  24 * time.Hour,
+			claimed: map[string]bool{"2024-05-10": true},
+		},
+		{
+			name:   "down at notify hour yesterday",
+			now:    time.Date(2024, 5, 10, 7, 0, 0, 0, time.UTC),
+			window: 24 * time.Hour,
+			due:    []string{"2024-05-09"},
+		},
+		{
+			name:   "several days within window oldest first",
+			now:    time.Date(2024, 5, 10, 9, 0, 0, 0, time.UTC),
+			window: 72 * time.Hour,
+			due:    []string{"2024-05-08", "2024-05-09", "2024-05-10"},
+		},
+		{
+			name: "without window only notify hour",
+			now:  time.Date(2024, 5, 10, 14, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockUR := mock.NewMockUserRepo(ctrl)
+			mockJR := mock.NewMockJobRunRepo(ctrl)
+
+			bs := &BirthdayService{
+				ur:  mockUR,
+				jr:  mockJR,
+				log: slog.New(slog.NewTextHandler(io.Discard, nil)),
+				cfg: &config.Config{NotifyHour: 8, CatchUpWindow: tt.window},
+				now: fixedNow(tt.now),
+			}
+
+			var claims, queried []string
+			mockUR.EXPECT().GetTimezones().Return([]string{"UTC"}, nil)
+			mockJR.EXPECT().ClaimJobRun(gomock.Any()).DoAndReturn(func(run *domain.JobRun) (bool, error) {
+				assert.Equal(t, domain.JobBirthdayNotify, run.Job)
+				assert.Equal(t, "UTC", run.Timezone)
+				claims = append(claims, run.Date.Format(time.DateOnly))
+				return !tt.claimed[run.Date.Format(time.DateOnly)], nil
+			}).AnyTimes()
+			mockUR.EXPECT().GetUsersWithBirthdayOn(gomock.Any(), "UTC").DoAndReturn(func(date time.Time, timezone string) (*[]domain.User, error) {
+				queried = append(queried, date.Format(time.DateOnly))
+				return &[]domain.User{}, nil
+			}).AnyTimes()
+
+			wg := &sync.WaitGroup{}
+			wg.Add(1)
+			bs.BirthdayNotify(context.Background(), wg)
+			wg.Wait()
+
+			assert.Equal(t, tt.due, queried)
+			for date := range tt.claimed {
+				assert.Contains(t, claims, date)
+			}
+		})
+	}
+}
+
+func TestRemindUpcoming(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUR := mock.NewMockUserRepo(ctrl)
+	mockJR := mock.NewMockJobRunRepo(ctrl)
+	mockTg := mock.NewMockTelegram(ctrl)
+	mockWS := mock.NewMockWishlist(ctrl)
+	mockCS := mock.NewMockCard(ctrl)
+
+	now := time.Date(2024, 5, 10, 8, 0, 0, 0, time.UTC)
+	bs := &BirthdayService{
+		ur:  mockUR,
+		jr:  mockJR,
+		tg:  mockTg,
+		ws:  mockWS,
+		cs:  mockCS,
+		log: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		cfg: &config.Config{NotifyHour: 8, ReminderDays: []int{7, 1}, TimeToKick: 12 * time.Hour, GreetingCard: true},
+		now: fixedNow(now),
+	}
+
+	celebrant := domain.User{ID: 1, Username: "user1", TelegramID: 22222}
+	subscribers := []domain.User{
+		{TelegramID: 33333},                         //default reminder days
+		{TelegramID: 44444, ReminderDays: []int{3}}, //own reminder days
+		{TelegramID: 55555, ReminderDays: []int{}},  //reminders off
+	}
+
+	mockUR.EXPECT().GetTimezones().Return([]string{"UTC"}, nil)
+	mockJR.EXPECT().ClaimJobRun(gomock.Any()).DoAndReturn(func(run *domain.JobRun) (bool, error) {
+		assert.Equal(t, domain.JobBirthdayReminder, run.Job)
+		return true, nil
+	})
+	mockUR.EXPECT().GetUsersWithBirthdayIn(gomock.Any(), gomock.Any(), "UTC").DoAndReturn(
+		func(date time.Time, days int, timezone string) (*[]domain.User, error) {
+			if days == 7 || days == 3 {
+				return &[]domain.User{celebrant}, nil
+			}
+			return &[]domain.User{}, nil
+		}).Times(domain.MaxReminderDays)
+	mockUR.EXPECT().GetUsersSubscribedToUsers(&[]domain.User{celebrant}).Return(&subscribers, nil).Times(2)
+	mockWS.EXPECT().GetWishlists(&[]domain.User{celebrant}).Return(map[int64][]domain.WishlistItem{
+		22222: {{ID: 1, TelegramID: 22222, Title: "book"}},
+	}, nil).Times(2)
+	mockTg.EXPECT().SendMessage(int64(33333), "reminder: @user1 has birthday in 7 days, on 17.05\nwishlist of @user1:\n#1 book\nsend /wishlist claim \"number\" to buy a gift").Times(1)
+	mockTg.EXPECT().SendMessage(int64(44444), "reminder: @user1 has birthday in 3 days, on 13.05\nwishlist of @user1:\n#1 book\nsend /wishlist claim \"number\" to buy a gift").Times(1)
+	//reminded subscribers greet in the card of the celebration
+	for _, reminder := range []struct {
+		subscriber  domain.User
+		celebrateAt time.Time
+	}{
+		{subscribers[0], time.Date(2024, 5, 17, 8, 0, 0, 0, time.UTC)},
+		{subscribers[1], time.Date(2024, 5, 13, 8, 0, 0, 0, time.UTC)},
+	} {
+		mockCS.EXPECT().RequestGreetings(int64(0), celebrant, &[]domain.User{reminder.subscriber}, reminder.celebrateAt, reminder.celebrateAt.Add(12*time.Hour))
+	}
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	bs.RemindUpcoming(context.Background(), wg)
+	wg.Wait()
+}
+
+func TestRemindUpcoming_AlreadyProcessed(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUR := mock.NewMockUserRepo(ctrl)
+	mockJR := mock.NewMockJobRunRepo(ctrl)
+
+	bs := &BirthdayService{
+		ur:  mockUR,
+		jr:  mockJR,
+		log: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		cfg: &config.Config{NotifyHour: 8, ReminderDays: []int{7, 1}},
+		now: fixedNow(time.Date(2024, 5, 10, 8, 0, 0, 0, time.UTC)),
+	}
+
+	mockUR.EXPECT().GetTimezones().Return([]string{"UTC"}, nil)
+	mockJR.EXPECT().ClaimJobRun(gomock.Any()).Return(false, nil)
+	mockUR.EXPECT().GetUsersWithBirthdayIn(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	bs.RemindUpcoming(context.Background(), wg)
+	wg.Wait()
+}
+
+func TestSurpriseLeadDays(t *testing.T) {
+	tests := []struct {
+		name     string
+		lead     time.Duration
+		reveal   int
+		expected int
+	}{
+		{"planning the day before", 24 * time.Hour, 12, 1},
+		{"planning at notify hour", 4 * time.Hour, 12, 0},
+		{"planning after notify hour", time.Hour, 12, 0},
+		{"planning before notify hour", 6 * time.Hour, 12, 1},
+		{"planning days ahead", 72 * time.Hour, 8, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bs := &BirthdayService{cfg: &config.Config{
+				NotifyHour: 8,
+				Surprise:   config.Surprise{PlanningLead: tt.lead, RevealHour: tt.reveal},
+			}}
+			assert.Equal(t, tt.expected, bs.surpriseLeadDays())
+		})
+	}
+}
+
+func TestBirthdayNotify_Surprise(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUR := mock.NewMockUserRepo(ctrl)
+	mockJR := mock.NewMockJobRunRepo(ctrl)
+	mockCR := mock.NewMockCelebrationRepo(ctrl)
+
+	bs := &BirthdayService{
+		ur:  mockUR,
+		jr:  mockJR,
+		cr:  mockCR,
+		log: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		cfg: &config.Config{
+			NotifyHour: 8,
+			Surprise:   config.Surprise{Enabled: true, PlanningLead: 24 * time.Hour, RevealHour: 12},
+		},
+		now: fixedNow(time.Date(2024, 5, 10, 8, 0, 0, 0, time.UTC)),
+	}
+
+	birthdayUsers := []domain.User{{Username: "user1", TelegramID: 22222}}
+
+	mockUR.EXPECT().GetTimezones().Return([]string{"UTC"}, nil)
+	mockJR.EXPECT().ClaimJobRun(gomock.Any()).Return(true, nil)
+	mockUR.EXPECT().GetUsersWithBirthdayOn(gomock.Any(), "UTC").DoAndReturn(func(date time.Time, timezone string) (*[]domain.User, error) {
+		assert.Equal(t, "2024-05-11", date.Format(time.DateOnly))
+		return &birthdayUsers, nil
+	})
+	mockCR.EXPECT().ScheduleCelebration(gomock.Any()).DoAndReturn(func(celebration *domain.Celebration) error {
+		assert.Equal(t, int64(22222), celebration.TelegramID)
+		assert.Equal(t, time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC), celebration.PlanAt)
+		assert.Equal(t, time.Date(2024, 5, 11, 12, 0, 0, 0, time.UTC), celebration.RevealAt)
+		return nil
+	})
+	mockUR.EXPECT().GetUsersSubscribedToUsers(gomock.Any()).Times(0)
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	bs.BirthdayNotify(context.Background(), wg)
+	wg.Wait()
+}
+
+func TestCelebrationDue(t *testing.T) {
+	revealAt := time.Date(2024, 5, 11, 12, 0, 0, 0, time.UTC)
+	celebrant := domain.User{Username: "user1", TelegramID: 22222}
+	subscribers := []domain.User{
+		{Username: "sub1", TelegramID: 33333},
+		celebrant,
+	}
+
+	tests := []struct {
+		name    string
+		now     time.Time
+		planned bool
+		replan  bool
+		invited []int64
+		message string
+		kicks   int
+	}{
+		{
+			name:    "planning invites subscribers",
+			now:     revealAt.Add(-24 * time.Hour),
+			invited: []int64{33333},
+			message: "surprise for @user1, they join the group on 11.05 at 12:00",
+		},
+		{
+			name:   "planning again skips invited subscribers",
+			now:    revealAt.Add(-23 * time.Hour),
+			replan: true,
+		},
+		{
+			name:    "reveal invites celebrant",
+			now:     revealAt,
+			planned: true,
+			invited: []int64{22222},
+			message: "happy birthday @user1",
+			kicks:   2,
+		},
+		{
+			name:    "late reveal ends with its links",
+			now:     revealAt.Add(2 * time.Hour),
+			planned: true,
+			invited: []int64{22222},
+			message: "happy birthday @user1",
+			kicks:   2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockUR := mock.NewMockUserRepo(ctrl)
+			mockKR := mock.NewMockKickRepo(ctrl)
+			mockCR := mock.NewMockCelebrationRepo(ctrl)
+			mockGR := mock.NewMockGroupRepo(ctrl)
+			mockIR := mock.NewMockInviteLinkRepo(ctrl)
+			mockTg := mock.NewMockTelegram(ctrl)
+			mockWS := mock.NewMockWishlist(ctrl)
+			mockMS := mock.NewMockModeration(ctrl)
+			mockFS := mock.NewMockFund(ctrl)
+			mockCS := mock.NewMockCard(ctrl)
+
+			cfg := config.Config{BirthdayGroupIDs: []int64{12345, 54321}, TimeToKick: 12 * time.Hour, GreetingCard: true}
+			bs := &BirthdayService{
+				ur:  mockUR,
+				kr:  mockKR,
+				cr:  mockCR,
+				gr:  mockGR,
+				ir:  mockIR,
+				tg:  mockTg,
+				ws:  mockWS,
+				ms:  mockMS,
+				fs:  mockFS,
+				cs:  mockCS,
+				log: slog.New(slog.NewTextHandler(io.Discard, nil)),
+				cfg: &cfg,
+				now: fixedNow(tt.now),
+			}
+
+			celebrations := []domain.Celebration{{
+				ID:         1,
+				TelegramID: celebrant.TelegramID,
+				Date:       time.Date(2024, 5, 11, 0, 0, 0, 0, time.UTC),
+				Timezone:   "UTC",
+				PlanAt:     revealAt.Add(-24 * time.Hour),
+				RevealAt:   revealAt,
+				Planned:    tt.planned,
+			}}
+			if tt.planned || tt.replan {
+				celebrations[0].ChatID = 54321
+			}
+
+			mockCR.EXPECT().GetDueCelebrations(tt.now).Return(&celebrations, nil)
+			mockUR.EXPECT().GetUserByTelegramID(&domain.User{TelegramID: celebrant.TelegramID}).Return(&celebrant, nil)
+			mockUR.EXPECT().GetUsersSubscribedToUsers(&[]domain.User{celebrant}).Return(&subscribers, nil)
+			mockTg.EXPECT().CreateInviteLink(int64(54321), gomock.Any(), revealAt.Add(cfg.TimeToKick)).Return("http://invite.com", nil).Times(len(tt.invited))
+			mockIR.EXPECT().SaveInviteLink(gomock.Any()).Return(nil).Times(len(tt.invited))
+			mockWS.EXPECT().GetWishlists(gomock.Any()).Return(map[int64][]domain.WishlistItem{}, nil).AnyTimes()
+			mockTg.EXPECT().GetChatAdministrators(int64(54321)).Return(nil, nil).AnyTimes()
+			for _, telegramID := range tt.invited {
+				mockMS.EXPECT().Unban(int64(54321), telegramID).Return(nil)
+				mockTg.EXPECT().SendMessage(telegramID, "Join the group to congratulate the birthday for users: @user1. Link: http://invite.com")
+			}
+			if tt.message != "" {
+				mockTg.EXPECT().SendMessage(int64(54321), tt.message)
+			}
+			mockKR.EXPECT().ScheduleKick(gomock.Any()).DoAndReturn(func(kick *domain.Kick) error {
+				assert.Equal(t, revealAt.Add(cfg.TimeToKick), kick.DueAt)
+				return nil
+			}).Times(tt.kicks)
+			if tt.planned {
+				mockGR.EXPECT().AllocateGroup(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+				mockCR.EXPECT().MarkCelebrationPlanned(gomock.Any()).Times(0)
+				mockCR.EXPECT().DeleteCelebration(&celebrations[0]).Return(nil)
+			} else {
+				if !tt.replan {
+					//the group stays allocated until the kicks after the reveal
+					mockGR.EXPECT().AllocateGroup(cfg.BirthdayGroupIDs, tt.now, revealAt.Add(cfg.TimeToKick)).Return(int64(54321), false, nil)
+				}
+				mockIR.EXPECT().HasActiveInviteLink(int64(54321), int64(33333), tt.now).Return(tt.replan, nil)
+				mockCR.EXPECT().MarkCelebrationPlanned(gomock.Any()).DoAndReturn(func(celebration *domain.Celebration) error {
+					assert.Equal(t, int64(54321), celebration.ChatID)
+					return nil
+				})
+				mockCR.EXPECT().DeleteCelebration(gomock.Any()).Times(0)
+				//planners pledge until the reveal
+				mockFS.EXPECT().OpenFund(int64(54321), "@user1", &[]domain.User{celebrant}, &[]domain.User{subscribers[0]}, revealAt, revealAt.Add(cfg.TimeToKick))
+				//planners greet until the reveal
+				mockCS.EXPECT().RequestGreetings(int64(54321), celebrant, &[]domain.User{subscribers[0]}, revealAt, revealAt.Add(cfg.TimeToKick))
+			}
+			mockCS.EXPECT().DeliverCards()
+
+			wg := &sync.WaitGroup{}
+			wg.Add(1)
+			bs.CelebrationDue(context.Background(), wg)
+			wg.Wait()
+		})
+	}
+}
+
+func TestAllocateGroup(t *testing.T) {
+	tests := []struct {
+		name     string
+		pool     []int64
+		chatID   int64
+		shared   bool
+		err      error
+		expected int64
+		logged   string
+	}{
+		{name: "free group", pool: []int64{1, 2}, chatID: 2, expected: 2},
+		{name: "exhausted pool is shared", pool: []int64{1, 2}, chatID: 1, shared: true, expected: 1, logged: "pool of birthday groups is exhausted"},
+		{name: "single group without pool", chatID: 12345, shared: true, expected: 12345},
+		{name: "allocation error", pool: []int64{1, 2}, err: errors.New("test"), expected: 1, logged: "AllocateGroup error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockGR := mock.NewMockGroupRepo(ctrl)
+
+			var logBuf bytes.Buffer
+			bs := &BirthdayService{
+				gr:  mockGR,
+				log: slog.New(slog.NewTextHandler(&logBuf, nil)),
+				cfg: &config.Config{BirthdayGroupID: 12345, BirthdayGroupIDs: tt.pool},
+				now: fixedNow(time.Date(2024, 5, 10, 8, 0, 0, 0, time.UTC)),
+			}
+
+			pool := tt.pool
+			if pool == nil {
+				pool = []int64{12345}
+			}
+			mockGR.EXPECT().AllocateGroup(pool, gomock.Any(), gomock.Any()).Return(tt.chatID, tt.shared, tt.err)
+
+			assert.Equal(t, tt.expected, bs.allocateGroup(time.Date(2024, 5, 10, 20, 0, 0, 0, time.UTC)))
+			if tt.logged == "" {
+				assert.Empty(t, logBuf.String())
+			} else {
+				assert.Contains(t, logBuf.String(), tt.logged)
+			}
+		})
+	}
+}
+
+func TestIsExpectedMember(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockIR := mock.NewMockInviteLinkRepo(ctrl)
+
+	now := time.Date(2024, 5, 10, 8, 0, 0, 0, time.UTC)
+	bs := &BirthdayService{
+		ir:  mockIR,
+		log: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		cfg: &config.Config{},
+		now: fixedNow(now),
+	}
+
+	mockIR.EXPECT().HasActiveInviteLink(int64(12345), int64(22222), now).Return(true, nil)
+	mockIR.EXPECT().HasActiveInviteLink(int64(12345), int64(33333), now).Return(false, nil)
+	mockIR.EXPECT().HasActiveInviteLink(int64(12345), int64(44444), now).Return(false, errors.New("test"))
+
+	expected, ieErr := bs.IsExpectedMember(12345, 22222)
+	assert.NoError(t, ieErr)
+	assert.True(t, expected)
+
+	expected, ieErr = bs.IsExpectedMember(12345, 33333)
+	assert.NoError(t, ieErr)
+	assert.False(t, expected)
+
+	_, ieErr = bs.IsExpectedMember(12345, 44444)
+	assert.Error(t, ieErr)
+}
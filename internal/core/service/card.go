@@ -0,0 +1,162 @@
+package service
+
+import (
+	"birthdayapp/internal/config"
+	"birthdayapp/internal/core/domain"
+	"birthdayapp/internal/core/port"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxGreeting limits the length of a greeting in characters
+const maxGreeting = 500
+
+type CardService struct {
+	log *slog.Logger
+	cfg *config.Config
+	gr  port.GreetingRepo
+	ur  port.UserRepo
+	tg  port.Telegram
+	now func() time.Time
+}
+
+func NewCardService(log *slog.Logger, gr port.GreetingRepo, ur port.UserRepo, tg port.Telegram, cfg *config.Config) *CardService {
+	return &CardService{
+		log: log,
+		cfg: cfg,
+		gr:  gr,
+		ur:  ur,
+		tg:  tg,
+		now: time.Now,
+	}
+}
+
+// RequestGreetings asks planners subscribed to the celebrant for a greeting, the card is delivered at deliverAt
+// to the celebrant and to the group of chatID unless it's 0, late greetings are forwarded to the celebrant until expiresAt
+func (cs *CardService) RequestGreetings(chatID int64, celebrant domain.User, planners *[]domain.User, deliverAt time.Time, expiresAt time.Time) {
+	op := "cardService.RequestGreetings"
+	cs.log.With(slog.String("op", op))
+
+	subscribers, gsuErr := cs.ur.GetUsersSubscribedToUsers(&[]domain.User{celebrant})
+	if gsuErr != nil {
+		cs.log.Error("GetUsersSubscribedToUsers error: ", "telegram_id", celebrant.TelegramID, "error", gsuErr.Error())
+		return
+	}
+
+	for _, subscriber := range *subscribers {
+		if !slices.ContainsFunc(*planners, func(planner domain.User) bool { return planner.TelegramID == subscriber.TelegramID }) {
+			continue
+		}
+		greeting := &domain.Greeting{
+			ChatID:      chatID,
+			CelebrantID: celebrant.TelegramID,
+			TelegramID:  subscriber.TelegramID,
+			DeliverAt:   deliverAt,
+			ExpiresAt:   expiresAt,
+		}
+		saved, sgrErr := cs.gr.SaveGreetingRequest(greeting)
+		if sgrErr != nil {
+			cs.log.Error("SaveGreetingRequest error: ", "telegram_id", subscriber.TelegramID, "error", sgrErr.Error())
+			continue
+		}
+		//the subscriber was asked already
+		if !saved {
+			continue
+		}
+		cs.tg.SendMessage(subscriber.TelegramID, fmt.Sprintf("reply with a short greeting for @%s, it goes to the greeting card delivered on %s, send /greet \"@%s\" \"greeting\" to change it",
+			celebrant.Username, deliverAt.Format("02.01 at 15:04"), celebrant.Username))
+	}
+}
+
+// GetOpenGreetings returns greetings the user may still write or change
+func (cs *CardService) GetOpenGreetings(telegramID int64) (*[]domain.Greeting, error) {
+	greetings, gogErr := cs.gr.GetOpenGreetings(telegramID, cs.now())
+	if gogErr != nil {
+		return nil, fmt.Errorf("GetOpenGreetings: %w", gogErr)
+	}
+	return greetings, nil
+}
+
+// Greet writes or changes the greeting of the user for the celebrant until the card is delivered,
+// greetings of delivered cards go to the celebrant separately
+func (cs *CardService) Greet(telegramID int64, celebrantID int64, text string) (*domain.Greeting, error) {
+	greetings, gogErr := cs.gr.GetOpenGreetings(telegramID, cs.now())
+	if gogErr != nil {
+		return nil, fmt.Errorf("GetOpenGreetings: %w", gogErr)
+	}
+	i := slices.IndexFunc(*greetings, func(greeting domain.Greeting) bool { return greeting.CelebrantID == celebrantID })
+	if i < 0 {
+		return nil, fmt.Errorf("greeting of telegram_id %d for telegram_id %d: %w", telegramID, celebrantID, domain.ErrNotFound)
+	}
+
+	text = strings.TrimSpace(text)
+	if text == "" || utf8.RuneCountInString(text) > maxGreeting {
+		return nil, fmt.Errorf("greeting text: %w", domain.ErrValidation)
+	}
+
+	greeting := (*greetings)[i]
+	if sgtErr := cs.gr.SaveGreetingText(greeting.ID, text); sgtErr != nil {
+		return nil, fmt.Errorf("SaveGreetingText: %w", sgtErr)
+	}
+	greeting.Text = text
+
+	if greeting.Delivered {
+		cs.tg.SendMessage(greeting.CelebrantID, fmt.Sprintf("late greeting from @%s: %s", greeting.Username, greeting.Text))
+	}
+	return &greeting, nil
+}
+
+// DeliverCards posts due cards to their groups and sends them to the celebrants,
+// subscribers who missed the deadline are told they can still greet until the celebration is over
+func (cs *CardService) DeliverCards() {
+	op := "cardService.DeliverCards"
+	cs.log.With(slog.String("op", op))
+
+	now := cs.now()
+	greetings, gdgErr := cs.gr.GetDueGreetings(now)
+	if gdgErr != nil {
+		cs.log.Error("GetDueGreetings error: ", "error", gdgErr.Error())
+		return
+	}
+
+	//greetings come ordered by card
+	var card []domain.Greeting
+	for i, greeting := range *greetings {
+		card = append(card, greeting)
+		if i+1 < len(*greetings) && (*greetings)[i+1].CelebrantID == greeting.CelebrantID && (*greetings)[i+1].DeliverAt.Equal(greeting.DeliverAt) {
+			continue
+		}
+		cs.deliver(card)
+		card = nil
+	}
+
+	if degErr := cs.gr.DeleteExpiredGreetings(now); degErr != nil {
+		cs.log.Error("DeleteExpiredGreetings error: ", "error", degErr.Error())
+	}
+}
+
+func (cs *CardService) deliver(card []domain.Greeting) {
+	celebrant := card[0]
+	if message := domain.GreetingCard(card); message != "" {
+		//cards requested with reminders have no group
+		if celebrant.ChatID != 0 {
+			cs.tg.SendMessage(celebrant.ChatID, message)
+		}
+		cs.tg.SendMessage(celebrant.CelebrantID, message)
+	}
+
+	for _, greeting := range card {
+		if greeting.Text == "" {
+			cs.tg.SendMessage(greeting.TelegramID, fmt.Sprintf("the greeting card for @%s is delivered, reply until %s and your greeting is sent separately",
+				greeting.CelebrantUsername, greeting.ExpiresAt.Format("02.01 at 15:04")))
+		}
+	}
+
+	if mgdErr := cs.gr.MarkGreetingsDelivered(celebrant.CelebrantID, celebrant.DeliverAt); mgdErr != nil {
+		cs.log.Error("MarkGreetingsDelivered error: ", "telegram_id", celebrant.CelebrantID, "error", mgdErr.Error())
+	}
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"birthdayapp/internal/config"
+	"birthdayapp/internal/core/domain"
+	"birthdayapp/internal/core/port/mock"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCardService_RequestGreetings(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	mockGR := mock.NewMockGreetingRepo(ctrl)
+	mockUR := mock.NewMockUserRepo(ctrl)
+	mockTg := mock.NewMockTelegram(ctrl)
+	cs := &CardService{
+		log: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		cfg: &config.Config{},
+		gr:  mockGR,
+		ur:  mockUR,
+		tg:  mockTg,
+		now: fixedNow(now),
+	}
+
+	celebrant := domain.User{Username: "user1", TelegramID: 11111}
+	deliverAt := now.Add(24 * time.Hour)
+	expiresAt := deliverAt.Add(12 * time.Hour)
+
+	//user3 plans another celebrant of the same group and isn't asked
+	mockUR.EXPECT().GetUsersSubscribedToUsers(&[]domain.User{celebrant}).Return(&[]domain.User{{Username: "user2", TelegramID: 22222}, {Username: "user4", TelegramID: 44444}}, nil)
+	mockGR.EXPECT().SaveGreetingRequest(&domain.Greeting{ChatID: -100, CelebrantID: 11111, TelegramID: 22222, DeliverAt: deliverAt, ExpiresAt: expiresAt}).Return(true, nil)
+	mockTg.EXPECT().SendMessage(int64(22222), "reply with a short greeting for @user1, it goes to the greeting card delivered on 02.05 at 12:00, send /greet \"@user1\" \"greeting\" to change it")
+
+	cs.RequestGreetings(-100, celebrant, &[]domain.User{{TelegramID: 22222}, {TelegramID: 33333}}, deliverAt, expiresAt)
+}
+
+func TestCardService_Greet(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		text      string
+		greetings []domain.Greeting
+		save      bool
+		late      bool
+		err       error
+	}{
+		{name: "greeting to the card", text: " happy birthday ", greetings: []domain.Greeting{{ID: 1, CelebrantID: 11111, Username: "user2"}, {ID: 2, CelebrantID: 33333}}, save: true},
+		{name: "greeting to the chosen card", text: "happy birthday", greetings: []domain.Greeting{{ID: 2, CelebrantID: 33333}, {ID: 1, CelebrantID: 11111, Username: "user2"}}, save: true},
+		{name: "changed greeting", text: "happy birthday", greetings: []domain.Greeting{{ID: 1, CelebrantID: 11111, Username: "user2", Text: "hb"}}, save: true},
+		{name: "late greeting", text: "happy birthday", greetings: []domain.Greeting{{ID: 1, CelebrantID: 11111, Username: "user2", Delivered: true}}, save: true, late: true},
+		{name: "no requests", text: "happy birthday", greetings: []domain.Greeting{}, err: domain.ErrNotFound},
+		{name: "no request for the celebrant", text: "happy birthday", greetings: []domain.Greeting{{ID: 2, CelebrantID: 33333}}, err: domain.ErrNotFound},
+		{name: "empty greeting", text: " ", greetings: []domain.Greeting{{ID: 1, CelebrantID: 11111}}, err: domain.ErrValidation},
+		{name: "long greeting", text: strings.Repeat("a", maxGreeting+1), greetings: []domain.Greeting{{ID: 1, CelebrantID: 11111}}, err: domain.ErrValidation},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockGR := mock.NewMockGreetingRepo(ctrl)
+			mockTg := mock.NewMockTelegram(ctrl)
+			cs := &CardService{
+				log: slog.New(slog.NewTextHandler(io.Discard, nil)),
+				cfg: &config.Config{},
+				gr:  mockGR,
+				tg:  mockTg,
+				now: fixedNow(now),
+			}
+
+			mockGR.EXPECT().GetOpenGreetings(int64(22222), now).Return(&tt.greetings, nil)
+			if tt.save {
+				mockGR.EXPECT().SaveGreetingText(1, "happy birthday").Return(nil)
+			}
+			if tt.late {
+				mockTg.EXPECT().SendMessage(int64(11111), "late greeting from @user2: happy birthday")
+			}
+
+			greeting, err := cs.Greet(22222, 11111, tt.text)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, "happy birthday", greeting.Text)
+		})
+	}
+}
+
+func TestCardService_DeliverCards(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	mockGR := mock.NewMockGreetingRepo(ctrl)
+	mockTg := mock.NewMockTelegram(ctrl)
+	cs := &CardService{
+		log: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		cfg: &config.Config{},
+		gr:  mockGR,
+		tg:  mockTg,
+		now: fixedNow(now),
+	}
+
+	expiresAt := now.Add(12 * time.Hour)
+	mockGR.EXPECT().GetDueGreetings(now).Return(&[]domain.Greeting{
+		{ID: 1, ChatID: -100, CelebrantID: 11111, CelebrantUsername: "user1", TelegramID: 22222, Username: "user2", Text: "happy birthday", DeliverAt: now, ExpiresAt: expiresAt},
+		{ID: 2, ChatID: -100, CelebrantID: 11111, CelebrantUsername: "user1", TelegramID: 33333, Username: "user3", DeliverAt: now, ExpiresAt: expiresAt},
+		{ID: 3, ChatID: -100, CelebrantID: 44444, CelebrantUsername: "user4", TelegramID: 22222, Username: "user2", DeliverAt: now, ExpiresAt: expiresAt},
+		{ID: 4, CelebrantID: 55555, CelebrantUsername: "user5", TelegramID: 33333, Username: "user3", Text: "congrats", DeliverAt: now, ExpiresAt: expiresAt},
+	}, nil)
+
+	card := "greeting card for @user1:\n@user2: happy birthday"
+	mockTg.EXPECT().SendMessage(int64(-100), card)
+	mockTg.EXPECT().SendMessage(int64(11111), card)
+	mockTg.EXPECT().SendMessage(int64(33333), "the greeting card for @user1 is delivered, reply until 02.05 at 00:00 and your greeting is sent separately")
+	mockGR.EXPECT().MarkGreetingsDelivered(int64(11111), now).Return(nil)
+	//no one greeted user4, the card isn't posted
+	mockTg.EXPECT().SendMessage(int64(22222), "the greeting card for @user4 is delivered, reply until 02.05 at 00:00 and your greeting is sent separately")
+	mockGR.EXPECT().MarkGreetingsDelivered(int64(44444), now).Return(nil)
+	//the card requested with reminders goes to the celebrant only
+	mockTg.EXPECT().SendMessage(int64(55555), "greeting card for @user5:\n@user3: congrats")
+	mockGR.EXPECT().MarkGreetingsDelivered(int64(55555), now).Return(nil)
+	mockGR.EXPECT().DeleteExpiredGreetings(now).Return(nil)
+
+	cs.DeliverCards()
+}
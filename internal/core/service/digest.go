@@ -0,0 +1,154 @@
+package service
+
+import (
+	"birthdayapp/internal/config"
+	"birthdayapp/internal/core/domain"
+	"birthdayapp/internal/core/port"
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+type DigestService struct {
+	log *slog.Logger
+	cfg *config.Config
+	ur  port.UserRepo
+	jr  port.JobRunRepo
+	tg  port.Telegram
+	now func() time.Time
+}
+
+func NewDigestService(log *slog.Logger, ur port.UserRepo, jr port.JobRunRepo, tg port.Telegram, cfg *config.Config) *DigestService {
+	return &DigestService{
+		log: log,
+		cfg: cfg,
+		ur:  ur,
+		jr:  jr,
+		tg:  tg,
+		now: time.Now,
+	}
+}
+
+// WeeklyDigest sends every active user one message with the coming birthdays among their subscriptions
+func (ds *DigestService) WeeklyDigest(ctx context.Context, wg *sync.WaitGroup) {
+	defer wg.Done()
+	op := "digestService.WeeklyDigest"
+	ds.log.With(slog.String("op", op))
+
+	now := ds.now()
+	if !ds.claim(domain.JobWeeklyDigest, now) {
+		return
+	}
+
+	users, gaErr := ds.ur.GetAllUsers()
+	if gaErr != nil {
+		ds.log.Error("GetAllUsers error: ", "error", gaErr.Error())
+		return
+	}
+
+	for _, user := range *users {
+		if ctx.Err() != nil {
+			return
+		}
+		if !user.Active || user.DigestOptOut {
+			continue
+		}
+
+		subscriptions, gsErr := ds.ur.GetSubscriptionsByTelegramID(&user)
+		if gsErr != nil {
+			ds.log.Error("GetSubscriptionsByTelegramID error: ", "telegram_id", user.TelegramID, "error", gsErr.Error())
+			continue
+		}
+
+		from := now.In(ds.userLocation(user))
+		upcoming := domain.UpcomingBirthdays(*subscriptions, from, ds.cfg.Digest.WeekDays, ds.cfg.LeapDayPolicy)
+		if len(upcoming) == 0 {
+			continue
+		}
+		ds.tg.SendMessage(user.TelegramID, formatDigest("birthdays of the coming week:", upcoming))
+	}
+}
+
+// MonthlyDigest posts birthdays of the current month to the team chat
+func (ds *DigestService) MonthlyDigest(ctx context.Context, wg *sync.WaitGroup) {
+	defer wg.Done()
+	op := "digestService.MonthlyDigest"
+	ds.log.With(slog.String("op", op))
+
+	if ds.cfg.Digest.TeamChatID == 0 {
+		return
+	}
+
+	now := ds.now()
+	if !ds.claim(domain.JobMonthlyDigest, now) {
+		return
+	}
+
+	users, gaErr := ds.ur.GetAllUsers()
+	if gaErr != nil {
+		ds.log.Error("GetAllUsers error: ", "error", gaErr.Error())
+		return
+	}
+
+	var active []domain.User
+	for _, user := range *users {
+		if user.Active {
+			active = append(active, user)
+		}
+	}
+
+	local := now.In(ds.scheduleLocation())
+	monthStart := time.Date(local.Year(), local.Month(), 1, 0, 0, 0, 0, local.Location())
+	monthDays := monthStart.AddDate(0, 1, -1).Day()
+	upcoming := domain.UpcomingBirthdays(active, monthStart, monthDays, ds.cfg.LeapDayPolicy)
+
+	if len(upcoming) == 0 {
+		ds.tg.SendMessage(ds.cfg.Digest.TeamChatID, fmt.Sprintf("no birthdays in %s", local.Month()))
+		return
+	}
+	ds.tg.SendMessage(ds.cfg.Digest.TeamChatID, formatDigest(fmt.Sprintf("birthdays in %s:", local.Month()), upcoming))
+}
+
+// claim records the digest of the day in the run log so a restart doesn't send it twice
+func (ds *DigestService) claim(job string, now time.Time) bool {
+	loc := ds.scheduleLocation()
+	run := &domain.JobRun{Job: job, Date: now.In(loc), Timezone: loc.String(), StartedAt: now}
+	claimed, cjErr := ds.jr.ClaimJobRun(run)
+	if cjErr != nil {
+		ds.log.Error("ClaimJobRun error: ", "job", job, "error", cjErr.Error())
+		return false
+	}
+	return claimed
+}
+
+func (ds *DigestService) scheduleLocation() *time.Location {
+	loc, lErr := time.LoadLocation(ds.cfg.Schedule.Timezone)
+	if lErr != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+func (ds *DigestService) userLocation(user domain.User) *time.Location {
+	timezone := user.Timezone
+	if timezone == "" {
+		timezone = ds.cfg.DefaultTimezone
+	}
+	loc, lErr := time.LoadLocation(timezone)
+	if lErr != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+func formatDigest(title string, upcoming []domain.UpcomingBirthday) string {
+	var digest strings.Builder
+	digest.WriteString(title)
+	for _, birthday := range upcoming {
+		digest.WriteString(fmt.Sprintf("\n%s @%s", birthday.Date.Format("02.01"), birthday.User.Username))
+	}
+	return digest.String()
+}
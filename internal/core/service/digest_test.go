@@ -0,0 +1,144 @@
+package service
+
+import (
+	"birthdayapp/internal/config"
+	"birthdayapp/internal/core/domain"
+	"birthdayapp/internal/core/port/mock"
+	"context"
+	"github.com/golang/mock/gomock"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWeeklyDigest(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUR := mock.NewMockUserRepo(ctrl)
+	mockJR := mock.NewMockJobRunRepo(ctrl)
+	mockTg := mock.NewMockTelegram(ctrl)
+
+	cfg := &config.Config{
+		DefaultTimezone: "UTC",
+		Digest:          config.Digest{WeekDays: 7},
+		Schedule:        config.Schedule{Timezone: "UTC"},
+	}
+	ds := &DigestService{
+		log: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		cfg: cfg,
+		ur:  mockUR,
+		jr:  mockJR,
+		tg:  mockTg,
+		now: fixedNow(time.Date(2024, 5, 13, 9, 0, 0, 0, time.UTC)), //monday
+	}
+
+	users := []domain.User{
+		{TelegramID: 1, Active: true},
+		{TelegramID: 2, Active: true, DigestOptOut: true},
+		{TelegramID: 3, Active: false},
+		{TelegramID: 4, Active: true},
+	}
+	subscriptions := []domain.User{
+		{Username: "next_year", Birthday: time.Date(1990, 5, 12, 0, 0, 0, 0, time.UTC)},
+		{Username: "sunday", Birthday: time.Date(1990, 5, 19, 0, 0, 0, 0, time.UTC)},
+		{Username: "today", Birthday: time.Date(1991, 5, 13, 0, 0, 0, 0, time.UTC)},
+	}
+
+	mockJR.EXPECT().ClaimJobRun(gomock.Any()).Return(true, nil)
+	mockUR.EXPECT().GetAllUsers().Return(&users, nil)
+	mockUR.EXPECT().GetSubscriptionsByTelegramID(&users[0]).Return(&subscriptions, nil)
+	mockUR.EXPECT().GetSubscriptionsByTelegramID(&users[3]).Return(&[]domain.User{}, nil)
+	mockTg.EXPECT().SendMessage(int64(1), "birthdays of the coming week:\n13.05 @today\n19.05 @sunday").Times(1)
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	ds.WeeklyDigest(context.Background(), wg)
+	wg.Wait()
+}
+
+func TestWeeklyDigest_AlreadySent(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUR := mock.NewMockUserRepo(ctrl)
+	mockJR := mock.NewMockJobRunRepo(ctrl)
+
+	ds := &DigestService{
+		log: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		cfg: &config.Config{Schedule: config.Schedule{Timezone: "UTC"}},
+		ur:  mockUR,
+		jr:  mockJR,
+		now: fixedNow(time.Date(2024, 5, 13, 9, 0, 0, 0, time.UTC)),
+	}
+
+	mockJR.EXPECT().ClaimJobRun(gomock.Any()).Return(false, nil)
+	mockUR.EXPECT().GetAllUsers().Times(0)
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	ds.WeeklyDigest(context.Background(), wg)
+	wg.Wait()
+}
+
+func TestMonthlyDigest(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUR := mock.NewMockUserRepo(ctrl)
+	mockJR := mock.NewMockJobRunRepo(ctrl)
+	mockTg := mock.NewMockTelegram(ctrl)
+
+	cfg := &config.Config{
+		LeapDayPolicy: domain.LeapDayFeb28,
+		Digest:        config.Digest{TeamChatID: 777},
+		Schedule:      config.Schedule{Timezone: "UTC"},
+	}
+	ds := &DigestService{
+		log: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		cfg: cfg,
+		ur:  mockUR,
+		jr:  mockJR,
+		tg:  mockTg,
+		now: fixedNow(time.Date(2023, 2, 1, 9, 0, 0, 0, time.UTC)),
+	}
+
+	users := []domain.User{
+		{Username: "leap", Active: true, Birthday: time.Date(2000, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{Username: "first", Active: true, Birthday: time.Date(1990, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{Username: "march", Active: true, Birthday: time.Date(1990, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{Username: "inactive", Active: false, Birthday: time.Date(1990, 2, 10, 0, 0, 0, 0, time.UTC)},
+	}
+
+	mockJR.EXPECT().ClaimJobRun(gomock.Any()).Return(true, nil)
+	mockUR.EXPECT().GetAllUsers().Return(&users, nil)
+	mockTg.EXPECT().SendMessage(int64(777), "birthdays in February:\n01.02 @first\n28.02 @leap").Times(1)
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	ds.MonthlyDigest(context.Background(), wg)
+	wg.Wait()
+}
+
+func TestMonthlyDigest_WithoutTeamChat(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockJR := mock.NewMockJobRunRepo(ctrl)
+
+	ds := &DigestService{
+		log: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		cfg: &config.Config{},
+		jr:  mockJR,
+		now: time.Now,
+	}
+
+	mockJR.EXPECT().ClaimJobRun(gomock.Any()).Times(0)
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	ds.MonthlyDigest(context.Background(), wg)
+	wg.Wait()
+}
@@ -0,0 +1,202 @@
+package service
+
+import (
+	"birthdayapp/internal/config"
+	"birthdayapp/internal/core/domain"
+	"birthdayapp/internal/core/port"
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+)
+
+type FundService struct {
+	log *slog.Logger
+	cfg *config.Config
+	fr  port.FundRepo
+	ws  port.Wishlist
+	tg  port.Telegram
+	now func() time.Time
+}
+
+func NewFundService(log *slog.Logger, fr port.FundRepo, ws port.Wishlist, tg port.Telegram, cfg *config.Config) *FundService {
+	return &FundService{
+		log: log,
+		cfg: cfg,
+		fr:  fr,
+		ws:  ws,
+		tg:  tg,
+		now: time.Now,
+	}
+}
+
+// OpenFund starts the gift fund of a celebration in its group, members pledge until pledgesUntil,
+// opening it again only adds members and the group is told once, the zone of pledgesUntil is the zone of the celebration
+func (fs *FundService) OpenFund(chatID int64, celebrants string, celebrantUsers *[]domain.User, members *[]domain.User, pledgesUntil time.Time, endsAt time.Time) {
+	op := "fundService.OpenFund"
+	fs.log.With(slog.String("op", op))
+
+	celebrantIDs := make([]int64, 0, len(*celebrantUsers))
+	for _, celebrant := range *celebrantUsers {
+		celebrantIDs = append(celebrantIDs, celebrant.TelegramID)
+	}
+	memberIDs := make([]int64, 0, len(*members))
+	for _, member := range *members {
+		memberIDs = append(memberIDs, member.TelegramID)
+	}
+
+	fund, created, cfErr := fs.fr.CreateFund(&domain.Fund{ChatID: chatID, Celebrants: celebrants, CelebrantIDs: celebrantIDs, PledgesUntil: pledgesUntil, EndsAt: endsAt,
+		Timezone: pledgesUntil.Location().String()}, memberIDs)
+	if cfErr != nil {
+		fs.log.Error("CreateFund error: ", "chat_id", chatID, "error", cfErr.Error())
+		return
+	}
+	if !created {
+		return
+	}
+
+	fs.tg.SendMessage(chatID, fmt.Sprintf("gift fund #%d for %s is open, send the bot /pledge \"amount\" until %s, the first one who pledges organizes the fund",
+		fund.ID, celebrants, pledgesUntil.Format("02.01 at 15:04")))
+}
+
+// GetMemberFunds returns open funds the user takes part in
+func (fs *FundService) GetMemberFunds(telegramID int64) (*[]domain.Fund, error) {
+	funds, gmfErr := fs.fr.GetMemberFunds(telegramID, fs.now())
+	if gmfErr != nil {
+		return nil, fmt.Errorf("GetMemberFunds: %w", gmfErr)
+	}
+	return funds, nil
+}
+
+// Pledge sets the amount the member gives to the fund, the first member who pledges becomes the organizer
+func (fs *FundService) Pledge(telegramID int64, fundID int, amount int64) (*domain.Fund, error) {
+	if amount <= 0 {
+		return nil, fmt.Errorf("amount %d: %w", amount, domain.ErrValidation)
+	}
+
+	fund, contributions, gfErr := fs.getFund(fundID)
+	if gfErr != nil {
+		return nil, gfErr
+	}
+	i := slices.IndexFunc(*contributions, func(contribution domain.Contribution) bool { return contribution.TelegramID == telegramID })
+	if i < 0 {
+		return nil, fmt.Errorf("telegram_id %d in fund %d: %w", telegramID, fundID, domain.ErrNotFound)
+	}
+	if fund.Closed || !fs.now().Before(fund.PledgesUntil) {
+		return nil, fmt.Errorf("pledges of fund %d: %w", fundID, domain.ErrClosed)
+	}
+	if (*contributions)[i].Paid {
+		return nil, fmt.Errorf("paid contribution of telegram_id %d: %w", telegramID, domain.ErrForbidden)
+	}
+
+	if pErr := fs.fr.Pledge(fundID, telegramID, amount); pErr != nil {
+		return nil, fmt.Errorf("Pledge: %w", pErr)
+	}
+	if fund.OrganizerID != 0 {
+		return fund, nil
+	}
+
+	if soErr := fs.fr.SetOrganizer(fundID, telegramID); soErr != nil {
+		return nil, fmt.Errorf("SetOrganizer: %w", soErr)
+	}
+	//another member may have pledged first
+	fund, gfErr = fs.fr.GetFund(fundID)
+	if gfErr != nil {
+		return nil, fmt.Errorf("GetFund: %w", gfErr)
+	}
+	return fund, nil
+}
+
+// GetFund returns the fund with its ledger to the organizer or an admin
+func (fs *FundService) GetFund(telegramID int64, fundID int) (*domain.Fund, *[]domain.Contribution, error) {
+	fund, contributions, gfErr := fs.getFund(fundID)
+	if gfErr != nil {
+		return nil, nil, gfErr
+	}
+	if !fs.manages(fund, telegramID) {
+		return nil, nil, fmt.Errorf("fund %d of telegram_id %d: %w", fundID, telegramID, domain.ErrForbidden)
+	}
+	return fund, contributions, nil
+}
+
+// MarkPaid marks the pledge of the member paid, only the organizer or an admin marks pledges,
+// pledges of closed funds are marked too since money often comes after the celebration
+func (fs *FundService) MarkPaid(organizerID int64, fundID int, telegramID int64) error {
+	fund, gfErr := fs.fr.GetFund(fundID)
+	if gfErr != nil {
+		return fmt.Errorf("GetFund: %w", gfErr)
+	}
+	if !fs.manages(fund, organizerID) {
+		return fmt.Errorf("fund %d of telegram_id %d: %w", fundID, organizerID, domain.ErrForbidden)
+	}
+
+	if mpErr := fs.fr.MarkPaid(fundID, telegramID); mpErr != nil {
+		return fmt.Errorf("MarkPaid: %w", mpErr)
+	}
+	fs.tg.SendMessage(telegramID, fmt.Sprintf("your pledge to the gift fund #%d for %s is marked paid", fund.ID, fund.Celebrants))
+	return nil
+}
+
+// FundDue closes funds of ended celebrations and sends their members the closing summary,
+// funds without pledges are closed silently, items claimed from the wishlists of the celebrants are given
+func (fs *FundService) FundDue(ctx context.Context, wg *sync.WaitGroup) {
+	defer wg.Done()
+	op := "fundService.FundDue"
+	fs.log.With(slog.String("op", op))
+
+	funds, gefErr := fs.fr.GetEndedFunds(fs.now())
+	if gefErr != nil {
+		fs.log.Error("GetEndedFunds error: ", "error", gefErr.Error())
+		return
+	}
+
+	for _, fund := range *funds {
+		if ctx.Err() != nil {
+			//ended funds stay for the next start
+			return
+		}
+		contributions, gcErr := fs.fr.GetContributions(fund.ID)
+		if gcErr != nil {
+			fs.log.Error("GetContributions error: ", "fund_id", fund.ID, "error", gcErr.Error())
+			continue
+		}
+
+		if fund.OrganizerID != 0 {
+			summary := fmt.Sprintf("the celebration is over, %s", domain.FundSummary(&fund, *contributions))
+			for _, contribution := range *contributions {
+				if contribution.TelegramID == fund.OrganizerID {
+					fs.tg.SendMessage(contribution.TelegramID, fmt.Sprintf("%s\nsend /paid \"@username\" #%d for pledges paid later", summary, fund.ID))
+					continue
+				}
+				fs.tg.SendMessage(contribution.TelegramID, summary)
+			}
+		}
+		celebrantIDs, gfcErr := fs.fr.GetFundCelebrants(fund.ID)
+		if gfcErr != nil {
+			fs.log.Error("GetFundCelebrants error: ", "fund_id", fund.ID, "error", gfcErr.Error())
+		} else if gciErr := fs.ws.GiveClaimedItems(celebrantIDs); gciErr != nil {
+			fs.log.Error("GiveClaimedItems error: ", "fund_id", fund.ID, "error", gciErr.Error())
+		}
+		if cfErr := fs.fr.CloseFund(fund.ID); cfErr != nil {
+			fs.log.Error("CloseFund error: ", "fund_id", fund.ID, "error", cfErr.Error())
+		}
+	}
+}
+
+func (fs *FundService) getFund(fundID int) (*domain.Fund, *[]domain.Contribution, error) {
+	fund, gfErr := fs.fr.GetFund(fundID)
+	if gfErr != nil {
+		return nil, nil, fmt.Errorf("GetFund: %w", gfErr)
+	}
+	contributions, gcErr := fs.fr.GetContributions(fundID)
+	if gcErr != nil {
+		return nil, nil, fmt.Errorf("GetContributions: %w", gcErr)
+	}
+	return fund, contributions, nil
+}
+
+func (fs *FundService) manages(fund *domain.Fund, telegramID int64) bool {
+	return fund.OrganizerID == telegramID || slices.Contains(fs.cfg.Admins, telegramID)
+}
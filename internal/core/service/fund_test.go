@@ -0,0 +1,203 @@
+package service
+
+import (
+	"birthdayapp/internal/config"
+	"birthdayapp/internal/core/domain"
+	"birthdayapp/internal/core/port/mock"
+	"context"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFundService_OpenFund(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	pledgesUntil := time.Date(2024, 5, 11, 12, 0, 0, 0, time.UTC)
+	endsAt := pledgesUntil.Add(12 * time.Hour)
+	celebrants := []domain.User{{TelegramID: 22222}}
+	members := []domain.User{{TelegramID: 33333}, {TelegramID: 44444}}
+
+	tests := []struct {
+		name    string
+		created bool
+	}{
+		{name: "new fund is announced", created: true},
+		{name: "reopened fund is not announced again", created: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockFR := mock.NewMockFundRepo(ctrl)
+			mockTg := mock.NewMockTelegram(ctrl)
+			fs := &FundService{
+				log: slog.New(slog.NewTextHandler(io.Discard, nil)),
+				cfg: &config.Config{Admins: []int64{99999}},
+				fr:  mockFR,
+				tg:  mockTg,
+				now: fixedNow(now),
+			}
+
+			mockFR.EXPECT().CreateFund(&domain.Fund{ChatID: 12345, Celebrants: "@user1", CelebrantIDs: []int64{22222}, PledgesUntil: pledgesUntil, EndsAt: endsAt, Timezone: "UTC"}, []int64{33333, 44444}).
+				DoAndReturn(func(fund *domain.Fund, members []int64) (*domain.Fund, bool, error) {
+					fund.ID = 3
+					return fund, tt.created, nil
+				})
+			if tt.created {
+				mockTg.EXPECT().SendMessage(int64(12345), "gift fund #3 for @user1 is open, send the bot /pledge \"amount\" until 11.05 at 12:00, the first one who pledges organizes the fund")
+			}
+
+			fs.OpenFund(12345, "@user1", &celebrants, &members, pledgesUntil, endsAt)
+		})
+	}
+}
+
+func TestFundService_Pledge(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	open := domain.Fund{ID: 3, Celebrants: "@user1", PledgesUntil: now.Add(time.Hour), EndsAt: now.Add(2 * time.Hour)}
+	contributions := []domain.Contribution{
+		{FundID: 3, TelegramID: 33333},
+		{FundID: 3, TelegramID: 44444, Amount: 500, Paid: true},
+	}
+
+	tests := []struct {
+		name       string
+		telegramID int64
+		amount     int64
+		fund       domain.Fund
+		err        error
+		pledged    bool
+		organizer  bool
+	}{
+		{name: "first pledge organizes the fund", telegramID: 33333, amount: 500, fund: open, pledged: true, organizer: true},
+		{name: "organized fund", telegramID: 33333, amount: 500, fund: domain.Fund{ID: 3, OrganizerID: 44444, PledgesUntil: open.PledgesUntil}, pledged: true},
+		{name: "not a member", telegramID: 55555, amount: 500, fund: open, err: domain.ErrNotFound},
+		{name: "planning is over", telegramID: 33333, amount: 500, fund: domain.Fund{ID: 3, PledgesUntil: now}, err: domain.ErrClosed},
+		{name: "paid pledge", telegramID: 44444, amount: 700, fund: open, err: domain.ErrForbidden},
+		{name: "negative amount", telegramID: 33333, amount: -1, fund: open, err: domain.ErrValidation},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockFR := mock.NewMockFundRepo(ctrl)
+			fs := &FundService{
+				log: slog.New(slog.NewTextHandler(io.Discard, nil)),
+				cfg: &config.Config{Admins: []int64{99999}},
+				fr:  mockFR,
+				now: fixedNow(now),
+			}
+
+			fund := tt.fund
+			mockFR.EXPECT().GetFund(3).Return(&fund, nil).AnyTimes()
+			mockFR.EXPECT().GetContributions(3).Return(&contributions, nil).AnyTimes()
+			if tt.pledged {
+				mockFR.EXPECT().Pledge(3, tt.telegramID, tt.amount).Return(nil)
+			}
+			if tt.organizer {
+				mockFR.EXPECT().SetOrganizer(3, tt.telegramID).DoAndReturn(func(fundID int, telegramID int64) error {
+					fund.OrganizerID = telegramID
+					return nil
+				})
+			}
+
+			pledgedFund, err := fs.Pledge(tt.telegramID, 3, tt.amount)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				return
+			}
+			assert.NoError(t, err)
+			if tt.organizer {
+				assert.Equal(t, tt.telegramID, pledgedFund.OrganizerID)
+			}
+		})
+	}
+}
+
+func TestFundService_MarkPaid(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+
+	mockFR := mock.NewMockFundRepo(ctrl)
+	mockTg := mock.NewMockTelegram(ctrl)
+	fs := &FundService{
+		log: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		cfg: &config.Config{Admins: []int64{99999}},
+		fr:  mockFR,
+		tg:  mockTg,
+		now: fixedNow(now),
+	}
+
+	fund := domain.Fund{ID: 3, Celebrants: "@user1", OrganizerID: 33333}
+	closed := domain.Fund{ID: 4, Celebrants: "@user2", OrganizerID: 33333, Closed: true}
+	mockFR.EXPECT().GetFund(3).Return(&fund, nil).Times(3)
+	mockFR.EXPECT().GetFund(4).Return(&closed, nil)
+	mockFR.EXPECT().MarkPaid(3, int64(44444)).Return(nil).Times(2)
+	mockFR.EXPECT().MarkPaid(4, int64(44444)).Return(nil)
+	mockTg.EXPECT().SendMessage(int64(44444), "your pledge to the gift fund #3 for @user1 is marked paid").Times(2)
+	mockTg.EXPECT().SendMessage(int64(44444), "your pledge to the gift fund #4 for @user2 is marked paid")
+
+	assert.NoError(t, fs.MarkPaid(33333, 3, 44444))
+	//admins manage every fund
+	assert.NoError(t, fs.MarkPaid(99999, 3, 44444))
+	assert.ErrorIs(t, fs.MarkPaid(44444, 3, 44444), domain.ErrForbidden)
+	//money paid after the celebration
+	assert.NoError(t, fs.MarkPaid(33333, 4, 44444))
+}
+
+func TestFundService_FundDue(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+
+	mockFR := mock.NewMockFundRepo(ctrl)
+	mockWS := mock.NewMockWishlist(ctrl)
+	mockTg := mock.NewMockTelegram(ctrl)
+	fs := &FundService{
+		log: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		cfg: &config.Config{Admins: []int64{99999}},
+		fr:  mockFR,
+		ws:  mockWS,
+		tg:  mockTg,
+		now: fixedNow(now),
+	}
+
+	funds := []domain.Fund{
+		{ID: 3, Celebrants: "@user1", OrganizerID: 33333, EndsAt: now},
+		{ID: 4, Celebrants: "@user2", EndsAt: now}, //nobody pledged
+	}
+	contributions := []domain.Contribution{
+		{FundID: 3, TelegramID: 33333, Username: "sub1", Amount: 500, Paid: true},
+		{FundID: 3, TelegramID: 44444, Username: "sub2", Amount: 300},
+	}
+	summary := "the celebration is over, gift fund #3 for @user1: pledged 800, paid 500\npaid: @sub1 500\nnot paid: @sub2 300"
+
+	mockFR.EXPECT().GetEndedFunds(now).Return(&funds, nil)
+	mockFR.EXPECT().GetContributions(3).Return(&contributions, nil)
+	mockFR.EXPECT().GetContributions(4).Return(&[]domain.Contribution{{FundID: 4, TelegramID: 55555}}, nil)
+	mockTg.EXPECT().SendMessage(int64(33333), summary+"\nsend /paid \"@username\" #3 for pledges paid later")
+	mockTg.EXPECT().SendMessage(int64(44444), summary)
+	//gifts claimed for the celebrants are given
+	mockFR.EXPECT().GetFundCelebrants(3).Return([]int64{22222}, nil)
+	mockFR.EXPECT().GetFundCelebrants(4).Return([]int64{11111}, nil)
+	mockWS.EXPECT().GiveClaimedItems([]int64{22222}).Return(nil)
+	mockWS.EXPECT().GiveClaimedItems([]int64{11111}).Return(nil)
+	mockFR.EXPECT().CloseFund(3).Return(nil)
+	mockFR.EXPECT().CloseFund(4).Return(nil)
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	fs.FundDue(context.Background(), wg)
+	wg.Wait()
+}
@@ -0,0 +1,188 @@
+package service
+
+import (
+	"birthdayapp/internal/config"
+	"birthdayapp/internal/core/domain"
+	"birthdayapp/internal/core/port"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+type ModerationService struct {
+	log *slog.Logger
+	cfg *config.Config
+	mr  port.ModerationRepo
+	tg  port.Telegram
+	now func() time.Time
+}
+
+func NewModerationService(log *slog.Logger, mr port.ModerationRepo, tg port.Telegram, cfg *config.Config) *ModerationService {
+	return &ModerationService{
+		log: log,
+		cfg: cfg,
+		mr:  mr,
+		tg:  tg,
+		now: time.Now,
+	}
+}
+
+// Kick removes the user from the chat, a failed kick is queued for retries
+func (ms *ModerationService) Kick(chatID int64, telegramID int64) error {
+	return ms.moderate(&domain.ModerationAction{Action: domain.ActionKick, ChatID: chatID, TelegramID: telegramID})
+}
+
+// Unban lets the user join the chat again, a failed unban is queued for retries
+func (ms *ModerationService) Unban(chatID int64, telegramID int64) error {
+	return ms.moderate(&domain.ModerationAction{Action: domain.ActionUnban, ChatID: chatID, TelegramID: telegramID})
+}
+
+// QueueUnban queues the unban for the moderation retries without running it now
+func (ms *ModerationService) QueueUnban(chatID int64, telegramID int64, reason string) error {
+	action := &domain.ModerationAction{
+		Action:        domain.ActionUnban,
+		ChatID:        chatID,
+		TelegramID:    telegramID,
+		NextAttemptAt: ms.now().Add(ms.cfg.Moderation.Backoff),
+		LastError:     reason,
+	}
+	if saErr := ms.mr.SaveAction(action); saErr != nil {
+		return fmt.Errorf("SaveAction: %w", saErr)
+	}
+	return nil
+}
+
+// moderate runs the action right away, it replaces the queued action of the user so a late retry doesn't undo it
+func (ms *ModerationService) moderate(action *domain.ModerationAction) error {
+	if rErr := ms.run(action); rErr != nil {
+		ms.fail(action, rErr)
+		return rErr
+	}
+	if daErr := ms.mr.DeleteActions(action.ChatID, action.TelegramID); daErr != nil {
+		ms.log.Error("DeleteActions error: ", "telegram_id", action.TelegramID, "error", daErr.Error())
+	}
+	return nil
+}
+
+// ModerationDue retries queued kicks and unbans whose next attempt has come
+func (ms *ModerationService) ModerationDue(ctx context.Context, wg *sync.WaitGroup) {
+	defer wg.Done()
+	op := "moderationService.ModerationDue"
+	ms.log.With(slog.String("op", op))
+
+	actions, gdaErr := ms.mr.GetDueActions(ms.now())
+	if gdaErr != nil {
+		ms.log.Error("GetDueActions error: ", "error", gdaErr.Error())
+		return
+	}
+
+	//protection is checked again, an admin promoted after the action was queued must not be kicked or unbanned
+	protected := make(map[int64]map[int64]bool)
+	for _, action := range *actions {
+		if ctx.Err() != nil {
+			//queued actions stay for the next start
+			return
+		}
+		if _, ok := protected[action.ChatID]; !ok {
+			chatProtected, pmErr := protectedMembers(ms.tg, ms.cfg.ProtectedMembers, action.ChatID)
+			if pmErr != nil {
+				ms.log.Error("protected members are unknown: ", "chat_id", action.ChatID, "error", pmErr)
+			}
+			protected[action.ChatID] = chatProtected
+		}
+		chatProtected := protected[action.ChatID]
+		if chatProtected == nil {
+			ms.fail(&action, errors.New("protected members are unknown"))
+			continue
+		}
+		if chatProtected[action.TelegramID] {
+			ms.log.Info("queued action of protected member is dropped", "action", action.Action, "chat_id", action.ChatID,
+				"telegram_id", action.TelegramID)
+			if daErr := ms.mr.DeleteActions(action.ChatID, action.TelegramID); daErr != nil {
+				ms.log.Error("DeleteActions error: ", "telegram_id", action.TelegramID, "error", daErr.Error())
+			}
+			continue
+		}
+		if rErr := ms.run(&action); rErr != nil {
+			ms.fail(&action, rErr)
+			continue
+		}
+		ms.log.Info("moderation action succeeded on retry", "action", action.Action, "chat_id", action.ChatID,
+			"telegram_id", action.TelegramID, "attempts", action.Attempts)
+		if daErr := ms.mr.DeleteActions(action.ChatID, action.TelegramID); daErr != nil {
+			ms.log.Error("DeleteActions error: ", "telegram_id", action.TelegramID, "error", daErr.Error())
+		}
+	}
+}
+
+func (ms *ModerationService) GetDeadActions() (*[]domain.ModerationAction, error) {
+	actions, gdaErr := ms.mr.GetDeadActions()
+	if gdaErr != nil {
+		return nil, fmt.Errorf("GetDeadActions: %w", gdaErr)
+	}
+	return actions, nil
+}
+
+// RetryDeadActions queues the dead action with the id again, every dead action when id is 0
+func (ms *ModerationService) RetryDeadActions(id int) (int64, error) {
+	retried, rdaErr := ms.mr.RetryDeadActions(id, ms.now())
+	if rdaErr != nil {
+		return 0, fmt.Errorf("RetryDeadActions: %w", rdaErr)
+	}
+	if retried == 0 && id != 0 {
+		return 0, fmt.Errorf("dead action %d: %w", id, domain.ErrNotFound)
+	}
+	return retried, nil
+}
+
+func (ms *ModerationService) run(action *domain.ModerationAction) error {
+	switch action.Action {
+	case domain.ActionKick:
+		return ms.tg.KickUser(action.ChatID, action.TelegramID)
+	case domain.ActionUnban:
+		return ms.tg.UnBanUser(action.ChatID, action.TelegramID)
+	default:
+		return fmt.Errorf("unknown moderation action %q: %w", action.Action, domain.ErrValidation)
+	}
+}
+
+// fail schedules the next attempt after the delay telegram asked for or with exponential backoff,
+// an action failed after every retry is dead until an admin retries it
+func (ms *ModerationService) fail(action *domain.ModerationAction, err error) {
+	action.Attempts++
+	action.LastError = err.Error()
+
+	delay := ms.backoff(action.Attempts)
+	var retryAfter domain.RetryAfterError
+	if errors.As(err, &retryAfter) {
+		delay = retryAfter.After
+	}
+	action.NextAttemptAt = ms.now().Add(delay)
+
+	if action.Attempts > ms.cfg.Moderation.Retries {
+		action.Dead = true
+		ms.log.Warn("moderation action failed every retry", "action", action.Action, "chat_id", action.ChatID,
+			"telegram_id", action.TelegramID, "error", err)
+		if action.Action == domain.ActionKick {
+			ms.tg.SendMessage(action.TelegramID, "please, leave from group. We'll wait for next birthday")
+		}
+	}
+
+	if saErr := ms.mr.SaveAction(action); saErr != nil {
+		ms.log.Error("SaveAction error: ", "telegram_id", action.TelegramID, "error", saErr.Error())
+	}
+}
+
+func (ms *ModerationService) backoff(attempts int) time.Duration {
+	backoff := ms.cfg.Moderation.Backoff
+	for i := 1; i < attempts; i++ {
+		backoff *= 2
+		if ms.cfg.Moderation.MaxBackoff > 0 && backoff >= ms.cfg.Moderation.MaxBackoff {
+			return ms.cfg.Moderation.MaxBackoff
+		}
+	}
+	return backoff
+}
@@ -0,0 +1,232 @@
+package service
+
+import (
+	"birthdayapp/internal/config"
+	"birthdayapp/internal/core/domain"
+	"birthdayapp/internal/core/port/mock"
+	"context"
+	"errors"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestModerationService_KickSuccess(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	now := time.Date(2024, 5, 10, 20, 0, 0, 0, time.UTC)
+
+	mockMR := mock.NewMockModerationRepo(ctrl)
+	mockTg := mock.NewMockTelegram(ctrl)
+	ms := &ModerationService{
+		log: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		cfg: &config.Config{Moderation: config.Moderation{Retries: 2, Backoff: time.Minute, MaxBackoff: 3 * time.Minute}},
+		mr:  mockMR,
+		tg:  mockTg,
+		now: fixedNow(now),
+	}
+
+	mockTg.EXPECT().KickUser(int64(12345), int64(22222)).Return(nil)
+	//a queued unban of the user must not undo the kick
+	mockMR.EXPECT().DeleteActions(int64(12345), int64(22222)).Return(nil)
+	mockMR.EXPECT().SaveAction(gomock.Any()).Times(0)
+
+	assert.NoError(t, ms.Kick(12345, 22222))
+}
+
+func TestModerationService_UnbanErr(t *testing.T) {
+	tests := []struct {
+		name  string
+		err   error
+		delay time.Duration
+	}{
+		{name: "backoff", err: errors.New("test"), delay: time.Minute},
+		{name: "retry after", err: domain.RetryAfterError{After: 30 * time.Second, Err: errors.New("test")}, delay: 30 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			now := time.Date(2024, 5, 10, 20, 0, 0, 0, time.UTC)
+
+			mockMR := mock.NewMockModerationRepo(ctrl)
+			mockTg := mock.NewMockTelegram(ctrl)
+			ms := &ModerationService{
+				log: slog.New(slog.NewTextHandler(io.Discard, nil)),
+				cfg: &config.Config{Moderation: config.Moderation{Retries: 2, Backoff: time.Minute, MaxBackoff: 3 * time.Minute}},
+				mr:  mockMR,
+				tg:  mockTg,
+				now: fixedNow(now),
+			}
+
+			mockTg.EXPECT().UnBanUser(int64(12345), int64(22222)).Return(tt.err)
+			mockMR.EXPECT().SaveAction(&domain.ModerationAction{
+				Action:        domain.ActionUnban,
+				ChatID:        12345,
+				TelegramID:    22222,
+				Attempts:      1,
+				NextAttemptAt: now.Add(tt.delay),
+				LastError:     tt.err.Error(),
+			}).Return(nil)
+			mockMR.EXPECT().DeleteActions(gomock.Any(), gomock.Any()).Times(0)
+
+			assert.ErrorIs(t, ms.Unban(12345, 22222), tt.err)
+		})
+	}
+}
+
+func TestModerationService_ModerationDue(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	now := time.Date(2024, 5, 10, 20, 0, 0, 0, time.UTC)
+
+	mockMR := mock.NewMockModerationRepo(ctrl)
+	mockTg := mock.NewMockTelegram(ctrl)
+	ms := &ModerationService{
+		log: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		cfg: &config.Config{
+			ProtectedMembers: []int64{44444},
+			Moderation:       config.Moderation{Retries: 2, Backoff: time.Minute, MaxBackoff: 3 * time.Minute},
+		},
+		mr:  mockMR,
+		tg:  mockTg,
+		now: fixedNow(now),
+	}
+
+	actions := []domain.ModerationAction{
+		{ID: 1, Action: domain.ActionUnban, ChatID: 12345, TelegramID: 11111, Attempts: 1},
+		{ID: 2, Action: domain.ActionUnban, ChatID: 12345, TelegramID: 22222, Attempts: 1},
+		{ID: 3, Action: domain.ActionKick, ChatID: 12345, TelegramID: 33333, Attempts: 2},
+		{ID: 4, Action: domain.ActionUnban, ChatID: 12345, TelegramID: 44444, Attempts: 1}, //protected member
+		{ID: 5, Action: domain.ActionKick, ChatID: 12345, TelegramID: 55555, Attempts: 1},  //administrator
+	}
+
+	mockMR.EXPECT().GetDueActions(now).Return(&actions, nil)
+	//administrators are looked up once per chat
+	mockTg.EXPECT().GetChatAdministrators(int64(12345)).Return([]int64{55555}, nil).Times(1)
+	mockTg.EXPECT().UnBanUser(int64(12345), int64(11111)).Return(nil)
+	mockMR.EXPECT().DeleteActions(int64(12345), int64(11111)).Return(nil)
+
+	//the backoff doubles with every failed attempt
+	mockTg.EXPECT().UnBanUser(int64(12345), int64(22222)).Return(errors.New("test"))
+	mockMR.EXPECT().SaveAction(&domain.ModerationAction{
+		ID: 2, Action: domain.ActionUnban, ChatID: 12345, TelegramID: 22222,
+		Attempts: 2, NextAttemptAt: now.Add(2 * time.Minute), LastError: "test",
+	}).Return(nil)
+
+	//the kick is dead after the retries, the user is asked to leave
+	mockTg.EXPECT().KickUser(int64(12345), int64(33333)).Return(errors.New("test"))
+	mockTg.EXPECT().SendMessage(int64(33333), "please, leave from group. We'll wait for next birthday")
+	mockMR.EXPECT().SaveAction(&domain.ModerationAction{
+		ID: 3, Action: domain.ActionKick, ChatID: 12345, TelegramID: 33333,
+		Attempts: 3, NextAttemptAt: now.Add(3 * time.Minute), LastError: "test", Dead: true,
+	}).Return(nil)
+
+	//actions queued before the users were protected are dropped
+	mockTg.EXPECT().UnBanUser(int64(12345), int64(44444)).Times(0)
+	mockMR.EXPECT().DeleteActions(int64(12345), int64(44444)).Return(nil)
+	mockTg.EXPECT().KickUser(int64(12345), int64(55555)).Times(0)
+	mockMR.EXPECT().DeleteActions(int64(12345), int64(55555)).Return(nil)
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	ms.ModerationDue(context.Background(), wg)
+	wg.Wait()
+}
+
+func TestModerationService_ModerationDue_UnknownAdministrators(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	now := time.Date(2024, 5, 10, 20, 0, 0, 0, time.UTC)
+
+	mockMR := mock.NewMockModerationRepo(ctrl)
+	mockTg := mock.NewMockTelegram(ctrl)
+	ms := &ModerationService{
+		log: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		cfg: &config.Config{Moderation: config.Moderation{Retries: 2, Backoff: time.Minute, MaxBackoff: 3 * time.Minute}},
+		mr:  mockMR,
+		tg:  mockTg,
+		now: fixedNow(now),
+	}
+
+	actions := []domain.ModerationAction{
+		{ID: 1, Action: domain.ActionUnban, ChatID: 12345, TelegramID: 11111},
+	}
+
+	mockMR.EXPECT().GetDueActions(now).Return(&actions, nil)
+	mockTg.EXPECT().GetChatAdministrators(int64(12345)).Return(nil, errors.New("test"))
+	//the user may be an administrator, the unban waits for the next retry
+	mockTg.EXPECT().UnBanUser(gomock.Any(), gomock.Any()).Times(0)
+	mockMR.EXPECT().SaveAction(&domain.ModerationAction{
+		ID: 1, Action: domain.ActionUnban, ChatID: 12345, TelegramID: 11111,
+		Attempts: 1, NextAttemptAt: now.Add(time.Minute), LastError: "protected members are unknown",
+	}).Return(nil)
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	ms.ModerationDue(context.Background(), wg)
+	wg.Wait()
+}
+
+func TestModerationService_RetryDeadActions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	now := time.Date(2024, 5, 10, 20, 0, 0, 0, time.UTC)
+
+	mockMR := mock.NewMockModerationRepo(ctrl)
+	ms := &ModerationService{
+		log: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		cfg: &config.Config{Moderation: config.Moderation{Retries: 2, Backoff: time.Minute, MaxBackoff: 3 * time.Minute}},
+		mr:  mockMR,
+		now: fixedNow(now),
+	}
+
+	mockMR.EXPECT().RetryDeadActions(0, now).Return(int64(3), nil)
+	mockMR.EXPECT().RetryDeadActions(7, now).Return(int64(0), nil)
+
+	retried, err := ms.RetryDeadActions(0)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), retried)
+
+	_, err = ms.RetryDeadActions(7)
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
+
+func TestModerationService_QueueUnban(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	now := time.Date(2024, 5, 10, 20, 0, 0, 0, time.UTC)
+
+	mockMR := mock.NewMockModerationRepo(ctrl)
+	mockTg := mock.NewMockTelegram(ctrl)
+	ms := &ModerationService{
+		log: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		cfg: &config.Config{Moderation: config.Moderation{Retries: 2, Backoff: time.Minute, MaxBackoff: 3 * time.Minute}},
+		mr:  mockMR,
+		tg:  mockTg,
+		now: fixedNow(now),
+	}
+
+	//the unban isn't tried now
+	mockTg.EXPECT().UnBanUser(gomock.Any(), gomock.Any()).Times(0)
+	mockMR.EXPECT().SaveAction(&domain.ModerationAction{
+		Action:        domain.ActionUnban,
+		ChatID:        12345,
+		TelegramID:    22222,
+		NextAttemptAt: now.Add(time.Minute),
+		LastError:     "administrators are unknown",
+	}).Return(nil)
+
+	assert.NoError(t, ms.QueueUnban(12345, 22222, "administrators are unknown"))
+}
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"birthdayapp/internal/config"
+	"birthdayapp/internal/core/domain"
+	"birthdayapp/internal/core/port"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// telegram limits of a poll
+const (
+	minPollOptions  = 2
+	maxPollOptions  = 10
+	maxPollOption   = 100
+	maxPollQuestion = 300
+)
+
+type PollService struct {
+	log *slog.Logger
+	cfg *config.Config
+	pr  port.PollRepo
+	fr  port.FundRepo
+	tg  port.Telegram
+	now func() time.Time
+}
+
+func NewPollService(log *slog.Logger, pr port.PollRepo, fr port.FundRepo, tg port.Telegram, cfg *config.Config) *PollService {
+	return &PollService{
+		log: log,
+		cfg: cfg,
+		pr:  pr,
+		fr:  fr,
+		tg:  tg,
+		now: time.Now,
+	}
+}
+
+// StartPoll posts the gift poll of the fund open in the chat, only the organizer or an admin starts it,
+// the poll runs while the celebrants are kept out of the group and its winner is announced poll_lead before the reveal
+func (ps *PollService) StartPoll(telegramID int64, chatID int64, options []string) (*domain.Poll, error) {
+	if len(options) < minPollOptions || len(options) > maxPollOptions {
+		return nil, fmt.Errorf("%d poll options: %w", len(options), domain.ErrValidation)
+	}
+	pollOptions := make([]domain.PollOption, 0, len(options))
+	texts := make([]string, 0, len(options))
+	for i, option := range options {
+		option = strings.TrimSpace(option)
+		if option == "" || utf8.RuneCountInString(option) > maxPollOption {
+			return nil, fmt.Errorf("poll option: %w", domain.ErrValidation)
+		}
+		pollOptions = append(pollOptions, domain.PollOption{ID: i, Text: option})
+		texts = append(texts, option)
+	}
+
+	now := ps.now()
+	funds, gmfErr := ps.fr.GetMemberFunds(telegramID, now)
+	if gmfErr != nil {
+		return nil, fmt.Errorf("GetMemberFunds: %w", gmfErr)
+	}
+	//a shared group may hold several funds, the one the user organizes goes first
+	i := slices.IndexFunc(*funds, func(fund domain.Fund) bool { return fund.ChatID == chatID && ps.manages(&fund, telegramID) })
+	if i < 0 {
+		if slices.ContainsFunc(*funds, func(fund domain.Fund) bool { return fund.ChatID == chatID }) {
+			return nil, fmt.Errorf("fund in chat %d of telegram_id %d: %w", chatID, telegramID, domain.ErrForbidden)
+		}
+		return nil, fmt.Errorf("fund in chat %d of telegram_id %d: %w", chatID, telegramID, domain.ErrNotFound)
+	}
+	fund := (*funds)[i]
+	//funds open with surprise planning, the celebrants join when pledges close
+	endsAt := fund.PledgesUntil.Add(-ps.cfg.Surprise.PollLead)
+	if !now.Before(endsAt) {
+		return nil, fmt.Errorf("planning of fund %d: %w", fund.ID, domain.ErrClosed)
+	}
+	loc, lErr := time.LoadLocation(fund.Timezone)
+	if lErr != nil {
+		loc = time.UTC
+	}
+
+	if _, gfpErr := ps.pr.GetFundPoll(fund.ID); gfpErr == nil {
+		return nil, fmt.Errorf("poll of fund %d: %w", fund.ID, domain.ErrAlreadyExist)
+	} else if !errors.Is(gfpErr, domain.ErrNotFound) {
+		return nil, fmt.Errorf("GetFundPoll: %w", gfpErr)
+	}
+
+	question := fmt.Sprintf("gift for %s, the winner is announced on %s", fund.Celebrants, endsAt.In(loc).Format("02.01 at 15:04"))
+	if runes := []rune(question); len(runes) > maxPollQuestion {
+		question = string(runes[:maxPollQuestion])
+	}
+	pollID, messageID, spErr := ps.tg.SendPoll(chatID, question, texts)
+	if spErr != nil {
+		return nil, fmt.Errorf("SendPoll: %w", spErr)
+	}
+
+	poll, cpErr := ps.pr.CreatePoll(&domain.Poll{
+		FundID:     fund.ID,
+		ChatID:     chatID,
+		MessageID:  messageID,
+		PollID:     pollID,
+		Celebrants: fund.Celebrants,
+		Options:    pollOptions,
+		EndsAt:     endsAt,
+	})
+	if cpErr != nil {
+		return nil, fmt.Errorf("CreatePoll: %w", cpErr)
+	}
+	return poll, nil
+}
+
+// Vote stores the answer of the user from a poll_answer update, no options means the vote is retracted
+func (ps *PollService) Vote(pollID string, telegramID int64, optionIDs []int) error {
+	poll, gpErr := ps.pr.GetPoll(pollID)
+	if gpErr != nil {
+		return fmt.Errorf("GetPoll: %w", gpErr)
+	}
+	if poll.Closed {
+		return fmt.Errorf("poll %d: %w", poll.ID, domain.ErrClosed)
+	}
+
+	if len(optionIDs) == 0 {
+		if dvErr := ps.pr.DeleteVote(poll.ID, telegramID); dvErr != nil {
+			return fmt.Errorf("DeleteVote: %w", dvErr)
+		}
+		return nil
+	}
+	//gift polls take a single answer
+	if svErr := ps.pr.SaveVote(poll.ID, telegramID, optionIDs[0]); svErr != nil {
+		return fmt.Errorf("SaveVote: %w", svErr)
+	}
+	return nil
+}
+
+// PollDue stops polls whose deadline has passed and announces their winners in the group
+func (ps *PollService) PollDue(ctx context.Context, wg *sync.WaitGroup) {
+	defer wg.Done()
+	op := "pollService.PollDue"
+	ps.log.With(slog.String("op", op))
+
+	polls, gepErr := ps.pr.GetEndedPolls(ps.now())
+	if gepErr != nil {
+		ps.log.Error("GetEndedPolls error: ", "error", gepErr.Error())
+		return
+	}
+
+	for _, poll := range *polls {
+		if ctx.Err() != nil {
+			//ended polls stay for the next start
+			return
+		}
+		results, gprErr := ps.pr.GetPollResults(poll.ID)
+		if gprErr != nil {
+			ps.log.Error("GetPollResults error: ", "poll_id", poll.ID, "error", gprErr.Error())
+			continue
+		}
+
+		//the poll is closed first, an unclosed poll is announced again on the next call
+		if cpErr := ps.pr.ClosePoll(poll.ID); cpErr != nil {
+			ps.log.Error("ClosePoll error: ", "poll_id", poll.ID, "error", cpErr.Error())
+			continue
+		}
+
+		//the poll message may be deleted from the group, the winner is announced anyway
+		if spErr := ps.tg.StopPoll(poll.ChatID, poll.MessageID); spErr != nil {
+			ps.log.Error("StopPoll error: ", "poll_id", poll.ID, "error", spErr.Error())
+		}
+		ps.tg.SendMessage(poll.ChatID, domain.PollResult(&poll, *results))
+	}
+}
+
+func (ps *PollService) manages(fund *domain.Fund, telegramID int64) bool {
+	return fund.OrganizerID == telegramID || slices.Contains(ps.cfg.Admins, telegramID)
+}
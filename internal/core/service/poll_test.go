@@ -0,0 +1,159 @@
+package service
+
+import (
+	"birthdayapp/internal/config"
+	"birthdayapp/internal/core/domain"
+	"birthdayapp/internal/core/port/mock"
+	"context"
+	"errors"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPollService_StartPoll(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	pledgesUntil := time.Date(2024, 5, 11, 12, 0, 0, 0, time.UTC)
+	fund := domain.Fund{ID: 3, ChatID: 12345, Celebrants: "@user1", OrganizerID: 33333, PledgesUntil: pledgesUntil, Timezone: "Europe/Moscow"}
+	//the winner is announced before the reveal, in the zone of the celebration
+	endsAt := pledgesUntil.Add(-3 * time.Hour)
+
+	tests := []struct {
+		name       string
+		telegramID int64
+		options    []string
+		funds      []domain.Fund
+		pollErr    error
+		posted     bool
+		err        error
+	}{
+		{name: "organizer starts the poll", telegramID: 33333, options: []string{"book", " pen "}, funds: []domain.Fund{fund}, pollErr: domain.ErrNotFound, posted: true},
+		{name: "not the organizer", telegramID: 44444, options: []string{"book", "pen"}, funds: []domain.Fund{fund}, err: domain.ErrForbidden},
+		{name: "no fund in the chat", telegramID: 33333, options: []string{"book", "pen"}, funds: []domain.Fund{{ID: 4, ChatID: 54321, OrganizerID: 33333}}, err: domain.ErrNotFound},
+		{name: "pledges are closed", telegramID: 33333, options: []string{"book", "pen"}, funds: []domain.Fund{{ID: 3, ChatID: 12345, OrganizerID: 33333, PledgesUntil: now}}, err: domain.ErrClosed},
+		{name: "reveal is too close", telegramID: 33333, options: []string{"book", "pen"}, funds: []domain.Fund{{ID: 3, ChatID: 12345, OrganizerID: 33333, PledgesUntil: now.Add(2 * time.Hour)}}, err: domain.ErrClosed},
+		{name: "poll already started", telegramID: 33333, options: []string{"book", "pen"}, funds: []domain.Fund{fund}, err: domain.ErrAlreadyExist},
+		{name: "single option", telegramID: 33333, options: []string{"book"}, err: domain.ErrValidation},
+		{name: "empty option", telegramID: 33333, options: []string{"book", " "}, err: domain.ErrValidation},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockPR := mock.NewMockPollRepo(ctrl)
+			mockFR := mock.NewMockFundRepo(ctrl)
+			mockTg := mock.NewMockTelegram(ctrl)
+			ps := &PollService{
+				log: slog.New(slog.NewTextHandler(io.Discard, nil)),
+				cfg: &config.Config{Admins: []int64{99999}, Surprise: config.Surprise{PollLead: 3 * time.Hour}},
+				pr:  mockPR,
+				fr:  mockFR,
+				tg:  mockTg,
+				now: fixedNow(now),
+			}
+
+			if tt.funds != nil {
+				mockFR.EXPECT().GetMemberFunds(tt.telegramID, now).Return(&tt.funds, nil)
+			}
+			if tt.pollErr != nil || errors.Is(tt.err, domain.ErrAlreadyExist) {
+				mockPR.EXPECT().GetFundPoll(3).Return(&domain.Poll{}, tt.pollErr)
+			}
+			if tt.posted {
+				mockTg.EXPECT().SendPoll(int64(12345), "gift for @user1, the winner is announced on 11.05 at 12:00", []string{"book", "pen"}).Return("poll", 7, nil)
+				mockPR.EXPECT().CreatePoll(&domain.Poll{
+					FundID:     3,
+					ChatID:     12345,
+					MessageID:  7,
+					PollID:     "poll",
+					Celebrants: "@user1",
+					Options:    []domain.PollOption{{ID: 0, Text: "book"}, {ID: 1, Text: "pen"}},
+					EndsAt:     endsAt,
+				}).DoAndReturn(func(poll *domain.Poll) (*domain.Poll, error) {
+					poll.ID = 1
+					return poll, nil
+				})
+			}
+
+			poll, err := ps.StartPoll(tt.telegramID, 12345, tt.options)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, 1, poll.ID)
+		})
+	}
+}
+
+func TestPollService_Vote(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockPR := mock.NewMockPollRepo(ctrl)
+	ps := &PollService{
+		log: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		cfg: &config.Config{},
+		pr:  mockPR,
+		now: time.Now,
+	}
+
+	mockPR.EXPECT().GetPoll("poll").Return(&domain.Poll{ID: 1}, nil).Times(2)
+	mockPR.EXPECT().SaveVote(1, int64(33333), 2).Return(nil)
+	mockPR.EXPECT().DeleteVote(1, int64(33333)).Return(nil)
+	mockPR.EXPECT().GetPoll("closed").Return(&domain.Poll{ID: 2, Closed: true}, nil)
+
+	assert.NoError(t, ps.Vote("poll", 33333, []int{2}))
+	//retracted vote
+	assert.NoError(t, ps.Vote("poll", 33333, nil))
+	assert.ErrorIs(t, ps.Vote("closed", 33333, []int{0}), domain.ErrClosed)
+}
+
+func TestPollService_PollDue(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	now := time.Date(2024, 5, 11, 12, 0, 0, 0, time.UTC)
+
+	mockPR := mock.NewMockPollRepo(ctrl)
+	mockTg := mock.NewMockTelegram(ctrl)
+	ps := &PollService{
+		log: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		cfg: &config.Config{Admins: []int64{99999}},
+		pr:  mockPR,
+		tg:  mockTg,
+		now: fixedNow(now),
+	}
+
+	mockPR.EXPECT().GetEndedPolls(now).Return(&[]domain.Poll{
+		{ID: 1, ChatID: 12345, MessageID: 7, Celebrants: "@user1"},
+		{ID: 2, ChatID: 54321, MessageID: 8, Celebrants: "@user2"},
+		{ID: 3, ChatID: 11111, MessageID: 9, Celebrants: "@user3"},
+	}, nil)
+	mockPR.EXPECT().GetPollResults(1).Return(&[]domain.PollOption{{ID: 0, Text: "book", Votes: 1}, {ID: 1, Text: "pen", Votes: 2}}, nil)
+	gomock.InOrder(
+		mockPR.EXPECT().ClosePoll(1).Return(nil),
+		mockTg.EXPECT().StopPoll(int64(12345), 7).Return(nil),
+		mockTg.EXPECT().SendMessage(int64(12345), "the gift poll for @user1 is over, \"pen\" wins with 2 votes"),
+	)
+	//a deleted poll message doesn't keep the winner from being announced
+	mockPR.EXPECT().GetPollResults(2).Return(&[]domain.PollOption{{ID: 0, Text: "book"}, {ID: 1, Text: "pen"}}, nil)
+	mockPR.EXPECT().ClosePoll(2).Return(nil)
+	mockTg.EXPECT().StopPoll(int64(54321), 8).Return(errors.New("message to stop not found"))
+	mockTg.EXPECT().SendMessage(int64(54321), "the gift poll for @user2 is over without votes")
+	//an unclosed poll isn't announced, it would be announced again on the next call
+	mockPR.EXPECT().GetPollResults(3).Return(&[]domain.PollOption{{ID: 0, Text: "book", Votes: 1}}, nil)
+	mockPR.EXPECT().ClosePoll(3).Return(errors.New("test"))
+	mockTg.EXPECT().StopPoll(int64(11111), gomock.Any()).Times(0)
+	mockTg.EXPECT().SendMessage(int64(11111), gomock.Any()).Times(0)
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	ps.PollDue(context.Background(), wg)
+	wg.Wait()
+}
@@ -1,38 +1,243 @@
 package service
 
 import (
+	"birthdayapp/internal/config"
 	"birthdayapp/internal/core/domain"
 	"birthdayapp/internal/core/port"
+	"context"
 	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
 )
 
 type UserService struct {
+	log    *slog.Logger
+	cfg    *config.Config
 	ur     port.UserRepo
 	extAPI port.ExternalAPI
+	syncMu sync.Mutex
 }
 
-func NewUserService(ur port.UserRepo, extAPI port.ExternalAPI) *UserService {
+func NewUserService(log *slog.Logger, ur port.UserRepo, extAPI port.ExternalAPI, cfg *config.Config) *UserService {
 	return &UserService{
+		log:    log,
+		cfg:    cfg,
 		ur:     ur,
 		extAPI: extAPI,
 	}
 }
 
-func (us *UserService) UpdateUsers() error {
+// SyncUsers syncs users with the external source, retrying with exponential backoff while the source fails
+func (us *UserService) SyncUsers(ctx context.Context, wg *sync.WaitGroup) {
+	defer wg.Done()
+	op := "userService.SyncUsers"
+	log := us.log.With(slog.String("op", op))
 
-	users, guErr := us.extAPI.GetUsers()
-	if guErr != nil {
-		return guErr
+	if !us.syncMu.TryLock() {
+		log.Warn("previous users sync is still running, skip")
+		return
+	}
+	defer us.syncMu.Unlock()
+
+	backoff := us.cfg.UserSync.Backoff
+	for attempt := 1; ; attempt++ {
+		sErr := us.syncOnce(log)
+		if sErr == nil {
+			return
+		}
+		if attempt > us.cfg.UserSync.Retries {
+			log.Error("users sync failed", "attempts", attempt, "error", sErr)
+			return
+		}
+		log.Warn("users sync failed, retrying", "attempt", attempt, "retry_in", backoff, "error", sErr)
+
+		retry := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			retry.Stop()
+			return
+		case <-retry.C:
+		}
+
+		backoff *= 2
+		if us.cfg.UserSync.MaxBackoff > 0 && backoff > us.cfg.UserSync.MaxBackoff {
+			backoff = us.cfg.UserSync.MaxBackoff
+		}
 	}
+}
 
-	iuErr := us.ur.InsertUsers(users)
-	if iuErr != nil && !errors.Is(iuErr, domain.ErrAlreadyExist) {
-		return iuErr
+func (us *UserService) syncOnce(log *slog.Logger) error {
+	if !us.cfg.UserSync.DryRun {
+		summary, uErr := us.UpdateUsers()
+		if uErr != nil {
+			return uErr
+		}
+		for _, sErr := range summary.Errors {
+			log.Warn("user not synced", "error", sErr)
+		}
+		log.Info("users synced", "added", summary.Added, "updated", summary.Updated, "deactivated", summary.Deactivated, "errors", len(summary.Errors))
+		return nil
 	}
 
+	diff, sourceErrs, duErr := us.diffUsers()
+	if duErr != nil {
+		return duErr
+	}
+	for _, user := range diff.ToAdd {
+		log.Info("dry-run: add user", "username", user.Username, "telegram_id", user.TelegramID, "birthday", user.Birthday.Format(time.DateOnly))
+	}
+	for _, user := range diff.ToUpdate {
+		log.Info("dry-run: update user", "username", user.Username, "telegram_id", user.TelegramID, "birthday", user.Birthday.Format(time.DateOnly),
+			"username_source", user.Sources.Username, "birthday_source", user.Sources.Birthday)
+	}
+	for _, user := range diff.ToDeactivate {
+		log.Info("dry-run: deactivate user", "username", user.Username, "telegram_id", user.TelegramID)
+	}
+	for _, sErr := range sourceErrs {
+		log.Warn("dry-run: user rejected", "error", sErr)
+	}
+	log.Info("dry-run: users diff", "add", len(diff.ToAdd), "update", len(diff.ToUpdate), "deactivate", len(diff.ToDeactivate), "errors", len(sourceErrs))
 	return nil
 }
 
+// UpdateUsers reconciles the users table with the external source and reports what was changed
+func (us *UserService) UpdateUsers() (*domain.SyncSummary, error) {
+
+	diff, sourceErrs, duErr := us.diffUsers()
+	if duErr != nil {
+		return nil, duErr
+	}
+
+	summary := &domain.SyncSummary{Errors: sourceErrs}
+
+	for _, user := range diff.ToDeactivate {
+		if dErr := us.ur.DeactivateUserByTelegramID(&user); dErr != nil {
+			summary.Errors = append(summary.Errors, fmt.Errorf("deactivate user %s: %w", user.Username, dErr))
+			continue
+		}
+		summary.Deactivated++
+	}
+
+	for _, user := range diff.ToUpdate {
+		if _, uErr := us.ur.UpdateUserByTelegramID(&user); uErr != nil {
+			summary.Errors = append(summary.Errors, fmt.Errorf("update user %s: %w", user.Username, uErr))
+			continue
+		}
+		summary.Updated++
+	}
+
+	for _, user := range diff.ToAdd {
+		if _, iErr := us.ur.InsertUser(&user); iErr != nil {
+			summary.Errors = append(summary.Errors, fmt.Errorf("add user %s: %w", user.Username, iErr))
+			continue
+		}
+		summary.Added++
+	}
+
+	return summary, nil
+}
+
+// diffUsers compares the external source with the users table,
+// users are not deactivated while the source reports rejected records
+func (us *UserService) diffUsers() (*domain.UserSyncDiff, []error, error) {
+
+	sourceUsers, guErr := us.extAPI.GetUsers()
+	var rowErrs domain.RowErrors
+	if guErr != nil && !errors.As(guErr, &rowErrs) {
+		return nil, nil, guErr
+	}
+
+	storedUsers, gaErr := us.ur.GetAllUsers()
+	if gaErr != nil {
+		return nil, nil, gaErr
+	}
+
+	stored := make(map[int64]domain.User, len(*storedUsers))
+	for _, user := range *storedUsers {
+		stored[user.TelegramID] = user
+	}
+
+	var sourceErrs []error
+	for _, rowErr := range rowErrs {
+		sourceErrs = append(sourceErrs, rowErr)
+	}
+
+	diff := &domain.UserSyncDiff{}
+	seen := make(map[int64]bool, len(*sourceUsers))
+
+	for _, sourceUser := range *sourceUsers {
+		if seen[sourceUser.TelegramID] {
+			sourceErrs = append(sourceErrs, fmt.Errorf("duplicate telegram_id %d in source: %w", sourceUser.TelegramID, domain.ErrValidation))
+			continue
+		}
+		seen[sourceUser.TelegramID] = true
+
+		storedUser, ok := stored[sourceUser.TelegramID]
+		if !ok {
+			sourceUser.Active = true
+			diff.ToAdd = append(diff.ToAdd, sourceUser)
+			continue
+		}
+
+		//fields entered with /register are kept when self is more trusted than the source
+		if us.keepSelfField(storedUser.Sources.Username, sourceUser.Sources.Username) {
+			sourceUser.Username = storedUser.Username
+			sourceUser.Sources.Username = storedUser.Sources.Username
+		}
+		if us.keepSelfField(storedUser.Sources.Birthday, sourceUser.Sources.Birthday) {
+			sourceUser.Birthday = storedUser.Birthday
+			sourceUser.Sources.Birthday = storedUser.Sources.Birthday
+		}
+		if us.keepSelfField(storedUser.Sources.TelegramID, sourceUser.Sources.TelegramID) {
+			sourceUser.Sources.TelegramID = storedUser.Sources.TelegramID
+		}
+
+		if storedUser.Username == sourceUser.Username && sameDate(storedUser.Birthday, sourceUser.Birthday) &&
+			storedUser.Sources == sourceUser.Sources && storedUser.Active {
+			continue
+		}
+		storedUser.Username = sourceUser.Username
+		storedUser.Birthday = sourceUser.Birthday
+		storedUser.Sources = sourceUser.Sources
+		storedUser.Active = true
+		diff.ToUpdate = append(diff.ToUpdate, storedUser)
+	}
+
+	if len(sourceErrs) != 0 {
+		return diff, sourceErrs, nil
+	}
+
+	//self-registered users are not expected in the external source
+	for _, storedUser := range *storedUsers {
+		if storedUser.Active && !seen[storedUser.TelegramID] && !storedUser.IsSelfRegistered() {
+			diff.ToDeactivate = append(diff.ToDeactivate, storedUser)
+		}
+	}
+
+	return diff, nil, nil
+}
+
+func (us *UserService) keepSelfField(storedSource, sourceSource string) bool {
+	return storedSource == domain.SourceSelf && sourceRank(us.cfg.SourcePrecedence, domain.SourceSelf) < sourceRank(us.cfg.SourcePrecedence, sourceSource)
+}
+
+// sourceRank is the position in source_precedence, unlisted sources are the least trusted
+func sourceRank(precedence []string, name string) int {
+	for i, trusted := range precedence {
+		if trusted == name {
+			return i
+		}
+	}
+	return len(precedence)
+}
+
+func sameDate(a, b time.Time) bool {
+	return a.Year() == b.Year() && a.Month() == b.Month() && a.Day() == b.Day()
+}
+
 func (us *UserService) GetUsers(user *domain.User) (*[]domain.User, error) {
 	users, guErr := us.ur.GetUsersToSubscribeByTelegramID(user)
 	if guErr != nil {
@@ -48,11 +253,130 @@ func (us *UserService) ChangeNotify(user *domain.User) error {
 	return nil
 }
 
+// ChangeTimezone sets the IANA timezone the user is celebrated in, empty resets it to the default
+func (us *UserService) ChangeTimezone(user *domain.User) error {
+	if user.Timezone != "" {
+		if _, lErr := time.LoadLocation(user.Timezone); lErr != nil || user.Timezone == "Local" {
+			return fmt.Errorf("timezone %q: %w", user.Timezone, domain.ErrValidation)
+		}
+	}
+	_, ctErr := us.ur.ChangeTimezoneByTelegramID(user)
+	if ctErr != nil {
+		return ctErr
+	}
+	return nil
+}
+
+// ChangeReminderDays sets days before subscribed birthdays to remind, nil resets them to the default
+func (us *UserService) ChangeReminderDays(user *domain.User) error {
+	if user.ReminderDays != nil {
+		days := make([]int, 0, len(user.ReminderDays))
+		seen := make(map[int]bool)
+		for _, day := range user.ReminderDays {
+			if day < 1 || day > domain.MaxReminderDays {
+				return fmt.Errorf("reminder day %d must be from 1 to %d: %w", day, domain.MaxReminderDays, domain.ErrValidation)
+			}
+			if !seen[day] {
+				seen[day] = true
+				days = append(days, day)
+			}
+		}
+		sort.Sort(sort.Reverse(sort.IntSlice(days)))
+		user.ReminderDays = days
+	}
+	_, crErr := us.ur.ChangeReminderDaysByTelegramID(user)
+	if crErr != nil {
+		return crErr
+	}
+	return nil
+}
+
+func (us *UserService) ChangeDigestOptOut(user *domain.User) error {
+	_, cdErr := us.ur.ChangeDigestOptOutByTelegramID(user)
+	if cdErr != nil {
+		return cdErr
+	}
+	return nil
+}
+
 func (us *UserService) GetTelegramIDByUsername(username string) (int64, error) {
 	user := &domain.User{Username: username}
 	uUser, guErr := us.ur.GetUserByUsername(user)
 	if guErr != nil {
 		return 0, guErr
 	}
+	if !uUser.Active {
+		return 0, fmt.Errorf("username %s: %w", username, domain.ErrNotFound)
+	}
 	return uUser.TelegramID, nil
 }
+
+// Register creates a user from /register, the user stays inactive until an admin approves when approval is required
+func (us *UserService) Register(user *domain.User) (*domain.User, error) {
+	if user.Username == "" {
+		return nil, fmt.Errorf("username is empty: %w", domain.ErrValidation)
+	}
+	if vErr := validateBirthday(user.Birthday, time.Now()); vErr != nil {
+		return nil, vErr
+	}
+	user.Sources = domain.UserSources{TelegramID: domain.SourceSelf, Username: domain.SourceSelf, Birthday: domain.SourceSelf}
+	user.Active = !us.cfg.Registration.RequireApproval
+
+	storedUser, guErr := us.ur.GetUserByTelegramID(user)
+	switch {
+	case guErr == nil && storedUser.Active:
+		return nil, fmt.Errorf("telegram_id %d: %w", user.TelegramID, domain.ErrAlreadyExist)
+	case guErr == nil && storedUser.IsSelfRegistered():
+		return nil, fmt.Errorf("telegram_id %d: %w", user.TelegramID, domain.ErrNotApproved)
+	case guErr == nil:
+		//deactivated by users sync, registers again
+		user.ID = storedUser.ID
+		user.NotifyBirthday = storedUser.NotifyBirthday
+		return us.ur.UpdateUserByTelegramID(user)
+	case !errors.Is(guErr, domain.ErrNotFound):
+		return nil, guErr
+	}
+
+	return us.ur.InsertUser(user)
+}
+
+func (us *UserService) ApproveRegistration(telegramID int64) (*domain.User, error) {
+	user, gpErr := us.getPendingUser(telegramID)
+	if gpErr != nil {
+		return nil, gpErr
+	}
+	user.Active = true
+	return us.ur.UpdateUserByTelegramID(user)
+}
+
+func (us *UserService) DeclineRegistration(telegramID int64) (*domain.User, error) {
+	user, gpErr := us.getPendingUser(telegramID)
+	if gpErr != nil {
+		return nil, gpErr
+	}
+	if dErr := us.ur.DeleteUserByTelegramID(user); dErr != nil {
+		return nil, dErr
+	}
+	return user, nil
+}
+
+func (us *UserService) getPendingUser(telegramID int64) (*domain.User, error) {
+	user, guErr := us.ur.GetUserByTelegramID(&domain.User{TelegramID: telegramID})
+	if guErr != nil {
+		return nil, guErr
+	}
+	if user.Active || !user.IsSelfRegistered() {
+		return nil, fmt.Errorf("registration of telegram_id %d: %w", telegramID, domain.ErrNotFound)
+	}
+	return user, nil
+}
+
+func validateBirthday(birthday time.Time, now time.Time) error {
+	if birthday.After(now) {
+		return fmt.Errorf("birthday %s is in the future: %w", birthday.Format(time.DateOnly), domain.ErrValidation)
+	}
+	if birthday.Year() < 1900 {
+		return fmt.Errorf("birthday %s is too early: %w", birthday.Format(time.DateOnly), domain.ErrValidation)
+	}
+	return nil
+}
@@ -0,0 +1,388 @@
+package service
+
+import (
+	"birthdayapp/internal/config"
+	"birthdayapp/internal/core/domain"
+	"birthdayapp/internal/core/port/mock"
+	"bytes"
+	"context"
+	"errors"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestUpdateUsers_Reconcile(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUR := mock.NewMockUserRepo(ctrl)
+	mockExtAPI := mock.NewMockExternalAPI(ctrl)
+
+	us := NewUserService(slog.New(slog.NewTextHandler(io.Discard, nil)), mockUR, mockExtAPI, &config.Config{})
+
+	birthday := time.Date(2000, 6, 21, 0, 0, 0, 0, time.UTC)
+
+	sourceUsers := []domain.User{
+		{Username: "same", TelegramID: 111, Birthday: birthday},
+		{Username: "renamed", TelegramID: 222, Birthday: birthday},
+		{Username: "moved", TelegramID: 333, Birthday: birthday.AddDate(0, 0, 1)},
+		{Username: "returned", TelegramID: 444, Birthday: birthday},
+		{Username: "new", TelegramID: 555, Birthday: birthday},
+	}
+	storedUsers := []domain.User{
+		{ID: 1, Username: "same", TelegramID: 111, Birthday: birthday, Active: true},
+		{ID: 2, Username: "old", TelegramID: 222, Birthday: birthday, Active: true},
+		{ID: 3, Username: "moved", TelegramID: 333, Birthday: birthday, Active: true},
+		{ID: 4, Username: "returned", TelegramID: 444, Birthday: birthday, Active: false},
+		{ID: 6, Username: "gone", TelegramID: 666, Birthday: birthday, Active: true},
+	}
+
+	mockExtAPI.EXPECT().GetUsers().Return(&sourceUsers, nil)
+	mockUR.EXPECT().GetAllUsers().Return(&storedUsers, nil)
+
+	mockUR.EXPECT().DeactivateUserByTelegramID(&domain.User{ID: 6, Username: "gone", TelegramID: 666, Birthday: birthday, Active: true}).Return(nil)
+	mockUR.EXPECT().UpdateUserByTelegramID(&domain.User{ID: 2, Username: "renamed", TelegramID: 222, Birthday: birthday, Active: true}).Return(nil, nil)
+	mockUR.EXPECT().UpdateUserByTelegramID(&domain.User{ID: 3, Username: "moved", TelegramID: 333, Birthday: birthday.AddDate(0, 0, 1), Active: true}).Return(nil, nil)
+	mockUR.EXPECT().UpdateUserByTelegramID(&domain.User{ID: 4, Username: "returned", TelegramID: 444, Birthday: birthday, Active: true}).Return(nil, errors.New("test"))
+	mockUR.EXPECT().InsertUser(&domain.User{Username: "new", TelegramID: 555, Birthday: birthday, Active: true}).Return(nil, nil)
+
+	summary, err := us.UpdateUsers()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, summary.Added)
+	assert.Equal(t, 2, summary.Updated)
+	assert.Equal(t, 1, summary.Deactivated)
+	assert.Len(t, summary.Errors, 1)
+	assert.Contains(t, summary.Errors[0].Error(), "update user returned")
+}
+
+func TestUpdateUsers_RowErrorsSkipDeactivation(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUR := mock.NewMockUserRepo(ctrl)
+	mockExtAPI := mock.NewMockExternalAPI(ctrl)
+
+	us := NewUserService(slog.New(slog.NewTextHandler(io.Discard, nil)), mockUR, mockExtAPI, &config.Config{})
+
+	birthday := time.Date(2000, 6, 21, 0, 0, 0, 0, time.UTC)
+
+	sourceUsers := []domain.User{
+		{Username: "new", TelegramID: 555, Birthday: birthday},
+	}
+	storedUsers := []domain.User{
+		{ID: 1, Username: "invalid in source", TelegramID: 111, Birthday: birthday, Active: true},
+	}
+	rowErrs := domain.RowErrors{{Row: 2, Err: domain.ErrValidation}}
+
+	mockExtAPI.EXPECT().GetUsers().Return(&sourceUsers, rowErrs)
+	mockUR.EXPECT().GetAllUsers().Return(&storedUsers, nil)
+	mockUR.EXPECT().InsertUser(gomock.Any()).Return(nil, nil)
+	mockUR.EXPECT().DeactivateUserByTelegramID(gomock.Any()).Times(0)
+
+	summary, err := us.UpdateUsers()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, summary.Added)
+	assert.Equal(t, 0, summary.Deactivated)
+	assert.Len(t, summary.Errors, 1)
+	assert.ErrorIs(t, summary.Errors[0], domain.ErrValidation)
+}
+
+func TestUpdateUsers_ErrGetUsers(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUR := mock.NewMockUserRepo(ctrl)
+	mockExtAPI := mock.NewMockExternalAPI(ctrl)
+
+	us := NewUserService(slog.New(slog.NewTextHandler(io.Discard, nil)), mockUR, mockExtAPI, &config.Config{})
+
+	mockExtAPI.EXPECT().GetUsers().Return(nil, errors.New("test"))
+
+	summary, err := us.UpdateUsers()
+	assert.Error(t, err)
+	assert.Nil(t, summary)
+}
+
+func TestSyncUsers_RetryWithBackoff(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUR := mock.NewMockUserRepo(ctrl)
+	mockExtAPI := mock.NewMockExternalAPI(ctrl)
+
+	var logBuf bytes.Buffer
+	log := slog.New(
+		slog.NewTextHandler(&logBuf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	cfg := &config.Config{
+		UserSync: config.UserSync{
+			Retries:    3,
+			Backoff:    10 * time.Millisecond,
+			MaxBackoff: 15 * time.Millisecond,
+		},
+	}
+
+	us := NewUserService(log, mockUR, mockExtAPI, cfg)
+
+	sourceUsers := []domain.User{}
+	storedUsers := []domain.User{}
+
+	gomock.InOrder(
+		mockExtAPI.EXPECT().GetUsers().Return(nil, errors.New("test")).Times(2),
+		mockExtAPI.EXPECT().GetUsers().Return(&sourceUsers, nil),
+	)
+	mockUR.EXPECT().GetAllUsers().Return(&storedUsers, nil)
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	us.SyncUsers(context.Background(), wg)
+
+	logSlice := strings.Split(logBuf.String(), "\n")
+	if len(logSlice) > 0 {
+		logSlice = logSlice[:len(logSlice)-1]
+	}
+	assert.Equal(t, 3, len(logSlice))
+	assert.Contains(t, logSlice[0], "retrying")
+	assert.Contains(t, logSlice[1], "retry_in=15ms")
+	assert.Contains(t, logSlice[2], "users synced")
+}
+
+func TestSyncUsers_RetriesExhausted(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUR := mock.NewMockUserRepo(ctrl)
+	mockExtAPI := mock.NewMockExternalAPI(ctrl)
+
+	var logBuf bytes.Buffer
+	log := slog.New(
+		slog.NewTextHandler(&logBuf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	cfg := &config.Config{
+		UserSync: config.UserSync{
+			Retries: 1,
+			Backoff: time.Millisecond,
+		},
+	}
+
+	us := NewUserService(log, mockUR, mockExtAPI, cfg)
+
+	mockExtAPI.EXPECT().GetUsers().Return(nil, errors.New("test")).Times(2)
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	us.SyncUsers(context.Background(), wg)
+
+	assert.Contains(t, logBuf.String(), "users sync failed\"")
+}
+
+func TestSyncUsers_DryRun(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUR := mock.NewMockUserRepo(ctrl)
+	mockExtAPI := mock.NewMockExternalAPI(ctrl)
+
+	var logBuf bytes.Buffer
+	log := slog.New(
+		slog.NewTextHandler(&logBuf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	cfg := &config.Config{
+		UserSync: config.UserSync{DryRun: true},
+	}
+
+	us := NewUserService(log, mockUR, mockExtAPI, cfg)
+
+	birthday := time.Date(2000, 6, 21, 0, 0, 0, 0, time.UTC)
+	sourceUsers := []domain.User{
+		{Username: "new", TelegramID: 555, Birthday: birthday},
+	}
+	storedUsers := []domain.User{
+		{ID: 1, Username: "gone", TelegramID: 111, Birthday: birthday, Active: true},
+	}
+
+	mockExtAPI.EXPECT().GetUsers().Return(&sourceUsers, nil)
+	mockUR.EXPECT().GetAllUsers().Return(&storedUsers, nil)
+	mockUR.EXPECT().InsertUser(gomock.Any()).Times(0)
+	mockUR.EXPECT().DeactivateUserByTelegramID(gomock.Any()).Times(0)
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	us.SyncUsers(context.Background(), wg)
+
+	assert.Contains(t, logBuf.String(), "dry-run: add user")
+	assert.Contains(t, logBuf.String(), "dry-run: deactivate user")
+	assert.Contains(t, logBuf.String(), "add=1 update=0 deactivate=1")
+}
+
+func TestUpdateUsers_KeepSelfRegistered(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUR := mock.NewMockUserRepo(ctrl)
+	mockExtAPI := mock.NewMockExternalAPI(ctrl)
+
+	cfg := &config.Config{SourcePrecedence: []string{domain.SourceSelf, "hr"}}
+	us := NewUserService(slog.New(slog.NewTextHandler(io.Discard, nil)), mockUR, mockExtAPI, cfg)
+
+	selfBirthday := time.Date(2000, 6, 21, 0, 0, 0, 0, time.UTC)
+	hrBirthday := time.Date(2000, 6, 22, 0, 0, 0, 0, time.UTC)
+	self := domain.UserSources{TelegramID: domain.SourceSelf, Username: domain.SourceSelf, Birthday: domain.SourceSelf}
+	hr := domain.UserSources{TelegramID: "hr", Username: "hr", Birthday: "hr"}
+
+	sourceUsers := []domain.User{
+		{Username: "hr_name", TelegramID: 111, Birthday: hrBirthday, Sources: hr},
+	}
+	storedUsers := []domain.User{
+		{ID: 1, Username: "self_name", TelegramID: 111, Birthday: selfBirthday, Active: true, Sources: self},
+		{ID: 2, Username: "self_only", TelegramID: 222, Birthday: selfBirthday, Active: true, Sources: self},
+	}
+
+	mockExtAPI.EXPECT().GetUsers().Return(&sourceUsers, nil)
+	mockUR.EXPECT().GetAllUsers().Return(&storedUsers, nil)
+	mockUR.EXPECT().DeactivateUserByTelegramID(gomock.Any()).Times(0)
+	mockUR.EXPECT().UpdateUserByTelegramID(gomock.Any()).Times(0)
+
+	summary, err := us.UpdateUsers()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, summary.Updated)
+	assert.Equal(t, 0, summary.Deactivated)
+}
+
+func TestRegister(t *testing.T) {
+	birthday := time.Date(2000, 6, 21, 0, 0, 0, 0, time.UTC)
+	self := domain.UserSources{TelegramID: domain.SourceSelf, Username: domain.SourceSelf, Birthday: domain.SourceSelf}
+
+	tests := []struct {
+		name            string
+		requireApproval bool
+		stored          *domain.User
+		expectInsert    bool
+		expectUpdate    bool
+		expectErr       error
+	}{
+		{name: "new user", expectInsert: true},
+		{name: "new user with approval", requireApproval: true, expectInsert: true},
+		{name: "already registered", stored: &domain.User{TelegramID: 111, Active: true}, expectErr: domain.ErrAlreadyExist},
+		{name: "waiting for approval", stored: &domain.User{TelegramID: 111, Sources: self}, expectErr: domain.ErrNotApproved},
+		{name: "deactivated by sync", stored: &domain.User{ID: 1, TelegramID: 111, Sources: domain.UserSources{TelegramID: "hr"}}, expectUpdate: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockUR := mock.NewMockUserRepo(ctrl)
+			cfg := &config.Config{Registration: config.Registration{Enabled: true, RequireApproval: tt.requireApproval}}
+			us := NewUserService(slog.New(slog.NewTextHandler(io.Discard, nil)), mockUR, nil, cfg)
+
+			if tt.stored != nil {
+				mockUR.EXPECT().GetUserByTelegramID(gomock.Any()).Return(tt.stored, nil)
+			} else {
+				mockUR.EXPECT().GetUserByTelegramID(gomock.Any()).Return(nil, domain.ErrNotFound)
+			}
+			expected := &domain.User{Username: "user1", TelegramID: 111, Birthday: birthday, Active: !tt.requireApproval, Sources: self}
+			if tt.expectInsert {
+				mockUR.EXPECT().InsertUser(expected).Return(expected, nil)
+			}
+			if tt.expectUpdate {
+				expected.ID = tt.stored.ID
+				mockUR.EXPECT().UpdateUserByTelegramID(expected).Return(expected, nil)
+			}
+
+			user, err := us.Register(&domain.User{Username: "user1", TelegramID: 111, Birthday: birthday})
+			if tt.expectErr != nil {
+				assert.ErrorIs(t, err, tt.expectErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, !tt.requireApproval, user.Active)
+		})
+	}
+}
+
+func TestRegister_BirthdayInFuture(t *testing.T) {
+	us := NewUserService(slog.New(slog.NewTextHandler(io.Discard, nil)), nil, nil, &config.Config{})
+
+	_, err := us.Register(&domain.User{Username: "user1", TelegramID: 111, Birthday: time.Now().AddDate(1, 0, 0)})
+	assert.ErrorIs(t, err, domain.ErrValidation)
+}
+
+func TestChangeTimezone(t *testing.T) {
+	tests := []struct {
+		name      string
+		timezone  string
+		expectErr error
+	}{
+		{name: "iana timezone", timezone: "Europe/Moscow"},
+		{name: "reset to default", timezone: ""},
+		{name: "unknown timezone", timezone: "Mars/Olympus", expectErr: domain.ErrValidation},
+		{name: "server local timezone", timezone: "Local", expectErr: domain.ErrValidation},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockUR := mock.NewMockUserRepo(ctrl)
+			us := NewUserService(slog.New(slog.NewTextHandler(io.Discard, nil)), mockUR, nil, &config.Config{})
+
+			user := &domain.User{TelegramID: 111, Timezone: tt.timezone}
+			if tt.expectErr == nil {
+				mockUR.EXPECT().ChangeTimezoneByTelegramID(user).Return(user, nil)
+			}
+
+			err := us.ChangeTimezone(user)
+			if tt.expectErr != nil {
+				assert.ErrorIs(t, err, tt.expectErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestChangeReminderDays(t *testing.T) {
+	tests := []struct {
+		name      string
+		days      []int
+		expected  []int
+		expectErr error
+	}{
+		{name: "sorted without duplicates", days: []int{1, 7, 1}, expected: []int{7, 1}},
+		{name: "turn off", days: []int{}, expected: []int{}},
+		{name: "reset to default", days: nil, expected: nil},
+		{name: "zero day", days: []int{0}, expectErr: domain.ErrValidation},
+		{name: "too far", days: []int{domain.MaxReminderDays + 1}, expectErr: domain.ErrValidation},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockUR := mock.NewMockUserRepo(ctrl)
+			us := NewUserService(slog.New(slog.NewTextHandler(io.Discard, nil)), mockUR, nil, &config.Config{})
+
+			user := &domain.User{TelegramID: 111, ReminderDays: tt.days}
+			if tt.expectErr == nil {
+				mockUR.EXPECT().ChangeReminderDaysByTelegramID(&domain.User{TelegramID: 111, ReminderDays: tt.expected}).Return(user, nil)
+			}
+
+			err := us.ChangeReminderDays(user)
+			if tt.expectErr != nil {
+				assert.ErrorIs(t, err, tt.expectErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
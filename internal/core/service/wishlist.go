@@ -0,0 +1,138 @@
+package service
+
+import (
+	"birthdayapp/internal/core/domain"
+	"birthdayapp/internal/core/port"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxWishlistTitle limits the length of a wishlist item in characters
+const maxWishlistTitle = 200
+
+type WishlistService struct {
+	log *slog.Logger
+	wr  port.WishlistRepo
+	ur  port.UserRepo
+}
+
+func NewWishlistService(log *slog.Logger, wr port.WishlistRepo, ur port.UserRepo) *WishlistService {
+	return &WishlistService{
+		log: log,
+		wr:  wr,
+		ur:  ur,
+	}
+}
+
+func (ws *WishlistService) AddItem(telegramID int64, title string) (*domain.WishlistItem, error) {
+	title = strings.TrimSpace(title)
+	if title == "" || utf8.RuneCountInString(title) > maxWishlistTitle {
+		return nil, fmt.Errorf("wishlist item title: %w", domain.ErrValidation)
+	}
+
+	item, aiErr := ws.wr.AddItem(&domain.WishlistItem{TelegramID: telegramID, Title: title})
+	if aiErr != nil {
+		return nil, fmt.Errorf("AddItem: %w", aiErr)
+	}
+	return item, nil
+}
+
+func (ws *WishlistService) RemoveItem(telegramID int64, id int) error {
+	if diErr := ws.wr.DeleteItem(id, telegramID); diErr != nil {
+		return fmt.Errorf("DeleteItem: %w", diErr)
+	}
+	return nil
+}
+
+// GetItems returns the wishlist of the owner to the owner or a subscriber, the owner never sees claims
+func (ws *WishlistService) GetItems(viewerID int64, ownerID int64) (*[]domain.WishlistItem, error) {
+	if viewerID != ownerID {
+		subscribed, isErr := ws.isSubscribed(viewerID, ownerID)
+		if isErr != nil {
+			return nil, isErr
+		}
+		if !subscribed {
+			return nil, fmt.Errorf("wishlist of telegram_id %d: %w", ownerID, domain.ErrForbidden)
+		}
+	}
+
+	items, giErr := ws.wr.GetItems([]int64{ownerID})
+	if giErr != nil {
+		return nil, fmt.Errorf("GetItems: %w", giErr)
+	}
+	if viewerID == ownerID {
+		for i := range *items {
+			(*items)[i].ClaimedBy = 0
+		}
+	}
+	return items, nil
+}
+
+// Claim marks the item bought by the subscriber, so other subscribers don't buy the same gift
+func (ws *WishlistService) Claim(telegramID int64, id int) (*domain.WishlistItem, error) {
+	item, giErr := ws.wr.GetItem(id)
+	if giErr != nil {
+		return nil, fmt.Errorf("GetItem: %w", giErr)
+	}
+	if item.TelegramID == telegramID {
+		return nil, fmt.Errorf("own wishlist item %d: %w", id, domain.ErrForbidden)
+	}
+	subscribed, isErr := ws.isSubscribed(telegramID, item.TelegramID)
+	if isErr != nil {
+		return nil, isErr
+	}
+	if !subscribed {
+		return nil, fmt.Errorf("wishlist of telegram_id %d: %w", item.TelegramID, domain.ErrForbidden)
+	}
+
+	if ciErr := ws.wr.ClaimItem(id, telegramID); ciErr != nil {
+		return nil, fmt.Errorf("ClaimItem: %w", ciErr)
+	}
+	item.ClaimedBy = telegramID
+	return item, nil
+}
+
+func (ws *WishlistService) Unclaim(telegramID int64, id int) error {
+	if uiErr := ws.wr.UnclaimItem(id, telegramID); uiErr != nil {
+		return fmt.Errorf("UnclaimItem: %w", uiErr)
+	}
+	return nil
+}
+
+// GetWishlists returns wishlists of the celebrants by their telegram ids
+func (ws *WishlistService) GetWishlists(celebrants *[]domain.User) (map[int64][]domain.WishlistItem, error) {
+	telegramIDs := make([]int64, 0, len(*celebrants))
+	for _, celebrant := range *celebrants {
+		telegramIDs = append(telegramIDs, celebrant.TelegramID)
+	}
+
+	items, giErr := ws.wr.GetItems(telegramIDs)
+	if giErr != nil {
+		return nil, fmt.Errorf("GetItems: %w", giErr)
+	}
+
+	wishlists := make(map[int64][]domain.WishlistItem)
+	for _, item := range *items {
+		wishlists[item.TelegramID] = append(wishlists[item.TelegramID], item)
+	}
+	return wishlists, nil
+}
+
+// GiveClaimedItems drops claimed items of the celebrants once their gifts are given
+func (ws *WishlistService) GiveClaimedItems(celebrantIDs []int64) error {
+	if dciErr := ws.wr.DeleteClaimedItems(celebrantIDs); dciErr != nil {
+		return fmt.Errorf("DeleteClaimedItems: %w", dciErr)
+	}
+	return nil
+}
+
+func (ws *WishlistService) isSubscribed(subscriberID int64, telegramID int64) (bool, error) {
+	subscriptions, gsErr := ws.ur.GetSubscriptionsByTelegramID(&domain.User{TelegramID: subscriberID})
+	if gsErr != nil {
+		return false, fmt.Errorf("GetSubscriptionsByTelegramID: %w", gsErr)
+	}
+	return slices.ContainsFunc(*subscriptions, func(user domain.User) bool { return user.TelegramID == telegramID }), nil
+}
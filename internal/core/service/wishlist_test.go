@@ -0,0 +1,137 @@
+package service
+
+import (
+	"birthdayapp/internal/core/domain"
+	"birthdayapp/internal/core/port/mock"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestWishlistService_AddItem(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockWR := mock.NewMockWishlistRepo(ctrl)
+	ws := &WishlistService{
+		log: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		wr:  mockWR,
+	}
+
+	mockWR.EXPECT().AddItem(&domain.WishlistItem{TelegramID: 22222, Title: "book"}).Return(&domain.WishlistItem{ID: 1, TelegramID: 22222, Title: "book"}, nil)
+
+	item, err := ws.AddItem(22222, "  book ")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, item.ID)
+
+	_, err = ws.AddItem(22222, " ")
+	assert.ErrorIs(t, err, domain.ErrValidation)
+	_, err = ws.AddItem(22222, strings.Repeat("a", maxWishlistTitle+1))
+	assert.ErrorIs(t, err, domain.ErrValidation)
+}
+
+func TestWishlistService_GetItems(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockWR := mock.NewMockWishlistRepo(ctrl)
+	mockUR := mock.NewMockUserRepo(ctrl)
+	ws := &WishlistService{
+		log: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		wr:  mockWR,
+		ur:  mockUR,
+	}
+
+	items := func() *[]domain.WishlistItem {
+		return &[]domain.WishlistItem{{ID: 1, TelegramID: 22222, Title: "book", ClaimedBy: 33333}}
+	}
+	mockWR.EXPECT().GetItems([]int64{22222}).DoAndReturn(func(telegramIDs []int64) (*[]domain.WishlistItem, error) {
+		return items(), nil
+	}).Times(2)
+	mockUR.EXPECT().GetSubscriptionsByTelegramID(&domain.User{TelegramID: 33333}).Return(&[]domain.User{{TelegramID: 22222}}, nil)
+	mockUR.EXPECT().GetSubscriptionsByTelegramID(&domain.User{TelegramID: 44444}).Return(&[]domain.User{}, nil)
+
+	//the owner never sees who is buying what
+	own, err := ws.GetItems(22222, 22222)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), (*own)[0].ClaimedBy)
+
+	subscribed, err := ws.GetItems(33333, 22222)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(33333), (*subscribed)[0].ClaimedBy)
+
+	_, err = ws.GetItems(44444, 22222)
+	assert.ErrorIs(t, err, domain.ErrForbidden)
+}
+
+func TestWishlistService_Claim(t *testing.T) {
+	tests := []struct {
+		name          string
+		telegramID    int64
+		subscriptions []domain.User
+		claimErr      error
+		err           error
+	}{
+		{name: "subscriber claims", telegramID: 33333, subscriptions: []domain.User{{TelegramID: 22222}}},
+		{name: "already claimed", telegramID: 33333, subscriptions: []domain.User{{TelegramID: 22222}}, claimErr: domain.ErrAlreadyExist, err: domain.ErrAlreadyExist},
+		{name: "not a subscriber", telegramID: 44444, subscriptions: []domain.User{}, err: domain.ErrForbidden},
+		{name: "own item", telegramID: 22222, err: domain.ErrForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockWR := mock.NewMockWishlistRepo(ctrl)
+			mockUR := mock.NewMockUserRepo(ctrl)
+			ws := &WishlistService{
+				log: slog.New(slog.NewTextHandler(io.Discard, nil)),
+				wr:  mockWR,
+				ur:  mockUR,
+			}
+
+			mockWR.EXPECT().GetItem(1).Return(&domain.WishlistItem{ID: 1, TelegramID: 22222, Title: "book"}, nil)
+			if tt.subscriptions != nil {
+				mockUR.EXPECT().GetSubscriptionsByTelegramID(&domain.User{TelegramID: tt.telegramID}).Return(&tt.subscriptions, nil)
+			}
+			if tt.err == nil || tt.claimErr != nil {
+				mockWR.EXPECT().ClaimItem(1, tt.telegramID).Return(tt.claimErr)
+			}
+
+			item, err := ws.Claim(tt.telegramID, 1)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.telegramID, item.ClaimedBy)
+		})
+	}
+}
+
+func TestWishlistService_GetWishlists(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockWR := mock.NewMockWishlistRepo(ctrl)
+	ws := &WishlistService{
+		log: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		wr:  mockWR,
+	}
+
+	celebrants := []domain.User{{TelegramID: 22222}, {TelegramID: 33333}}
+	mockWR.EXPECT().GetItems([]int64{22222, 33333}).Return(&[]domain.WishlistItem{
+		{ID: 1, TelegramID: 22222, Title: "book"},
+		{ID: 3, TelegramID: 22222, Title: "pen"},
+		{ID: 2, TelegramID: 33333, Title: "mug"},
+	}, nil)
+
+	wishlists, err := ws.GetWishlists(&celebrants)
+	assert.NoError(t, err)
+	assert.Len(t, wishlists[22222], 2)
+	assert.Len(t, wishlists[33333], 1)
+}
@@ -0,0 +1,232 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule returns the first activation time strictly after t, zero time if there is none
+type Schedule interface {
+	Next(t time.Time) time.Time
+}
+
+// field is a set of allowed values of one cron field
+type field struct {
+	allowed []bool
+	any     bool
+}
+
+// Cron is a standard five-field cron expression: minute hour day-of-month month day-of-week,
+// evaluated on the wall clock of its location
+type Cron struct {
+	expr   string
+	loc    *time.Location
+	minute field
+	hour   field
+	dom    field
+	month  field
+	dow    field
+}
+
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var monthNames = map[string]int{
+	"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+	"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+}
+
+var dowNames = map[string]int{
+	"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+}
+
+// maxSearchYears bounds Next for expressions like "0 0 30 2 *" that never match
+const maxSearchYears = 8
+
+// ParseCron parses a five-field expression or a descriptor like @daily, nil loc means UTC
+func ParseCron(expr string, loc *time.Location) (*Cron, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	spec := strings.TrimSpace(expr)
+	if descriptor, ok := cronDescriptors[strings.ToLower(spec)]; ok {
+		spec = descriptor
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron %q: expected 5 fields, got %d", expr, len(fields))
+	}
+
+	cron := &Cron{expr: expr, loc: loc}
+	var pfErr error
+	if cron.minute, pfErr = parseField(fields[0], 0, 59, nil); pfErr != nil {
+		return nil, fmt.Errorf("cron %q minute: %w", expr, pfErr)
+	}
+	if cron.hour, pfErr = parseField(fields[1], 0, 23, nil); pfErr != nil {
+		return nil, fmt.Errorf("cron %q hour: %w", expr, pfErr)
+	}
+	if cron.dom, pfErr = parseField(fields[2], 1, 31, nil); pfErr != nil {
+		return nil, fmt.Errorf("cron %q day of month: %w", expr, pfErr)
+	}
+	if cron.month, pfErr = parseField(fields[3], 1, 12, monthNames); pfErr != nil {
+		return nil, fmt.Errorf("cron %q month: %w", expr, pfErr)
+	}
+	//7 is sunday too
+	if cron.dow, pfErr = parseField(fields[4], 0, 7, dowNames); pfErr != nil {
+		return nil, fmt.Errorf("cron %q day of week: %w", expr, pfErr)
+	}
+	if cron.dow.allowed[7] {
+		cron.dow.allowed[0] = true
+	}
+
+	return cron, nil
+}
+
+// MustParseCron is ParseCron for expressions known to be valid
+func MustParseCron(expr string, loc *time.Location) *Cron {
+	cron, pcErr := ParseCron(expr, loc)
+	if pcErr != nil {
+		panic(pcErr)
+	}
+	return cron
+}
+
+func (c *Cron) String() string {
+	return c.expr
+}
+
+// Next walks the wall clock of the cron location. A wall time skipped by a DST jump runs once
+// at the end of the gap, a wall time repeated by a DST fall back runs once at its first occurrence
+func (c *Cron) Next(t time.Time) time.Time {
+	local := t.In(c.loc)
+	year, month, day := local.Date()
+	start := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+
+	for date := start; date.Year() <= year+maxSearchYears; date = date.AddDate(0, 0, 1) {
+		if !c.month.allowed[int(date.Month())] || !c.dayMatches(date) {
+			continue
+		}
+		for hour := 0; hour < 24; hour++ {
+			if !c.hour.allowed[hour] {
+				continue
+			}
+			for minute := 0; minute < 60; minute++ {
+				if !c.minute.allowed[minute] {
+					continue
+				}
+				next := c.wallTime(date.Year(), date.Month(), date.Day(), hour, minute)
+				if next.After(t) {
+					return next
+				}
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// dayMatches follows cron rules: when both day fields are restricted either one is enough
+func (c *Cron) dayMatches(date time.Time) bool {
+	domMatch := c.dom.allowed[date.Day()]
+	dowMatch := c.dow.allowed[int(date.Weekday())]
+	if c.dom.any || c.dow.any {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+// wallTime returns the instant of the wall time, a time inside a DST gap becomes the end of the gap
+func (c *Cron) wallTime(year int, month time.Month, day, hour, minute int) time.Time {
+	wall := time.Date(year, month, day, hour, minute, 0, 0, c.loc)
+	if wall.Hour() == hour && wall.Minute() == minute {
+		return wall
+	}
+	//depending on the zone rules the normalized time lands before or after the gap
+	start, end := wall.ZoneBounds()
+	if wall.Hour()*60+wall.Minute() < hour*60+minute {
+		return end
+	}
+	return start
+}
+
+func parseField(expr string, min, max int, names map[string]int) (field, error) {
+	f := field{allowed: make([]bool, max+1), any: expr == "*" || expr == "?"}
+
+	for _, part := range strings.Split(expr, ",") {
+		rangeExpr, stepExpr, hasStep := strings.Cut(part, "/")
+
+		step := 1
+		if hasStep {
+			var aErr error
+			step, aErr = strconv.Atoi(stepExpr)
+			if aErr != nil || step <= 0 {
+				return field{}, fmt.Errorf("invalid step %q", stepExpr)
+			}
+		}
+
+		low, high := min, max
+		switch {
+		case rangeExpr == "*" || rangeExpr == "?":
+		case strings.Contains(rangeExpr, "-"):
+			lowExpr, highExpr, _ := strings.Cut(rangeExpr, "-")
+			var pvErr error
+			if low, pvErr = parseValue(lowExpr, min, max, names); pvErr != nil {
+				return field{}, pvErr
+			}
+			if high, pvErr = parseValue(highExpr, min, max, names); pvErr != nil {
+				return field{}, pvErr
+			}
+			if low > high {
+				return field{}, fmt.Errorf("invalid range %q", rangeExpr)
+			}
+		default:
+			var pvErr error
+			if low, pvErr = parseValue(rangeExpr, min, max, names); pvErr != nil {
+				return field{}, pvErr
+			}
+			//"5/15" means from 5 to max every 15
+			if !hasStep {
+				high = low
+			}
+		}
+
+		for value := low; value <= high; value += step {
+			f.allowed[value] = true
+		}
+	}
+	return f, nil
+}
+
+func parseValue(expr string, min, max int, names map[string]int) (int, error) {
+	if value, ok := names[strings.ToLower(expr)]; ok {
+		return value, nil
+	}
+	value, aErr := strconv.Atoi(expr)
+	if aErr != nil {
+		return 0, fmt.Errorf("invalid value %q", expr)
+	}
+	if value < min || value > max {
+		return 0, fmt.Errorf("value %d out of range %d-%d", value, min, max)
+	}
+	return value, nil
+}
+
+// Every runs at fixed intervals counted from the previous activation
+type Every time.Duration
+
+func (e Every) Next(t time.Time) time.Time {
+	if e <= 0 {
+		return time.Time{}
+	}
+	return t.Add(time.Duration(e))
+}
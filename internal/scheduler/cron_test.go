@@ -0,0 +1,162 @@
+package scheduler
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestParseCron_Invalid(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+	} {
+		_, err := ParseCron(expr, time.UTC)
+		assert.Error(t, err, expr)
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	tests := []struct {
+		name     string
+		expr     string
+		from     time.Time
+		expected time.Time
+	}{
+		{
+			name:     "hourly",
+			expr:     "0 * * * *",
+			from:     time.Date(2024, 5, 10, 8, 0, 0, 0, time.UTC),
+			expected: time.Date(2024, 5, 10, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "daily at 8 before",
+			expr:     "0 8 * * *",
+			from:     time.Date(2024, 5, 10, 7, 59, 59, 0, time.UTC),
+			expected: time.Date(2024, 5, 10, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "daily at 8 after",
+			expr:     "0 8 * * *",
+			from:     time.Date(2024, 5, 10, 8, 0, 0, 0, time.UTC),
+			expected: time.Date(2024, 5, 11, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "step and list",
+			expr:     "*/20 9,18 * * *",
+			from:     time.Date(2024, 5, 10, 9, 45, 0, 0, time.UTC),
+			expected: time.Date(2024, 5, 10, 18, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "weekdays by name",
+			expr:     "30 9 * * mon-fri",
+			from:     time.Date(2024, 5, 10, 10, 0, 0, 0, time.UTC), //friday
+			expected: time.Date(2024, 5, 13, 9, 30, 0, 0, time.UTC),
+		},
+		{
+			name:     "sunday as 7",
+			expr:     "0 0 * * 7",
+			from:     time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC),
+			expected: time.Date(2024, 5, 12, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "day of month or day of week",
+			expr:     "0 0 15 * sat",
+			from:     time.Date(2024, 5, 12, 0, 0, 0, 0, time.UTC),
+			expected: time.Date(2024, 5, 15, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "leap day",
+			expr:     "0 0 29 feb *",
+			from:     time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+			expected: time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "descriptor",
+			expr:     "@daily",
+			from:     time.Date(2024, 12, 31, 23, 0, 0, 0, time.UTC),
+			expected: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cron, err := ParseCron(tt.expr, time.UTC)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, cron.Next(tt.from))
+		})
+	}
+}
+
+func TestCronNext_Never(t *testing.T) {
+	cron := MustParseCron("0 0 30 2 *", time.UTC)
+	assert.True(t, cron.Next(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)).IsZero())
+}
+
+func TestCronNext_Location(t *testing.T) {
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	require.NoError(t, err)
+
+	cron := MustParseCron("0 8 * * *", moscow)
+	next := cron.Next(time.Date(2024, 5, 10, 6, 0, 0, 0, time.UTC))
+	assert.Equal(t, time.Date(2024, 5, 11, 5, 0, 0, 0, time.UTC), next.UTC())
+}
+
+func TestCronNext_DST(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	t.Run("spring forward runs skipped time once at the end of the gap", func(t *testing.T) {
+		cron := MustParseCron("30 2 * * *", newYork)
+		next := cron.Next(time.Date(2024, 3, 9, 12, 0, 0, 0, newYork))
+		assert.Equal(t, time.Date(2024, 3, 10, 7, 0, 0, 0, time.UTC), next.UTC()) //03:00 EDT
+		next = cron.Next(next)
+		assert.Equal(t, time.Date(2024, 3, 11, 2, 30, 0, 0, newYork), next)
+	})
+
+	t.Run("spring forward keeps daily interval in local time", func(t *testing.T) {
+		cron := MustParseCron("0 8 * * *", newYork)
+		first := cron.Next(time.Date(2024, 3, 9, 7, 0, 0, 0, newYork))
+		second := cron.Next(first)
+		assert.Equal(t, 23*time.Hour, second.Sub(first))
+		assert.Equal(t, 8, second.Hour())
+	})
+
+	t.Run("spring forward every minute doesn't repeat", func(t *testing.T) {
+		cron := MustParseCron("* * * * *", newYork)
+		next := cron.Next(time.Date(2024, 3, 10, 1, 59, 0, 0, newYork))
+		assert.Equal(t, time.Date(2024, 3, 10, 3, 0, 0, 0, newYork), next)
+		assert.Equal(t, time.Date(2024, 3, 10, 3, 1, 0, 0, newYork), cron.Next(next))
+	})
+
+	t.Run("fall back runs repeated time once", func(t *testing.T) {
+		cron := MustParseCron("30 1 * * *", newYork)
+		first := cron.Next(time.Date(2024, 11, 3, 0, 0, 0, 0, newYork))
+		assert.Equal(t, time.Date(2024, 11, 3, 5, 30, 0, 0, time.UTC), first.UTC()) //01:30 EDT
+		second := cron.Next(first)
+		assert.Equal(t, time.Date(2024, 11, 4, 1, 30, 0, 0, newYork), second)
+	})
+
+	t.Run("fall back hourly skips repeated hour", func(t *testing.T) {
+		cron := MustParseCron("0 * * * *", newYork)
+		first := cron.Next(time.Date(2024, 11, 3, 0, 30, 0, 0, newYork))
+		second := cron.Next(first)
+		assert.Equal(t, time.Date(2024, 11, 3, 5, 0, 0, 0, time.UTC), first.UTC())  //01:00 EDT
+		assert.Equal(t, time.Date(2024, 11, 3, 7, 0, 0, 0, time.UTC), second.UTC()) //02:00 EST
+	})
+}
+
+func TestEvery(t *testing.T) {
+	from := time.Date(2024, 5, 10, 8, 0, 0, 0, time.UTC)
+	assert.Equal(t, from.Add(24*time.Hour), Every(24*time.Hour).Next(from))
+	assert.True(t, Every(0).Next(from).IsZero())
+}
@@ -0,0 +1,106 @@
+package scheduler
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Clock is the time source of the scheduler, tests replace it with a fake one
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// SystemClock is the real clock
+type SystemClock struct{}
+
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (SystemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// Job has the signature of the services background jobs, it must call wg.Done when finished
+type Job func(ctx context.Context, wg *sync.WaitGroup)
+
+type entry struct {
+	name     string
+	schedule Schedule
+	job      Job
+}
+
+// Scheduler runs jobs at the times given by their schedules
+type Scheduler struct {
+	log     *slog.Logger
+	clock   Clock
+	entries []entry
+}
+
+func New(log *slog.Logger, clock Clock) *Scheduler {
+	if clock == nil {
+		clock = SystemClock{}
+	}
+	return &Scheduler{
+		log:   log,
+		clock: clock,
+	}
+}
+
+// Add registers a job, it must be called before Run
+func (s *Scheduler) Add(name string, schedule Schedule, job Job) {
+	s.entries = append(s.entries, entry{name: name, schedule: schedule, job: job})
+}
+
+// Run starts every job at its activation times until ctx is done, jobs are tracked by wg
+func (s *Scheduler) Run(ctx context.Context, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	var entriesWG sync.WaitGroup
+	for _, e := range s.entries {
+		entriesWG.Add(1)
+		go func(e entry) {
+			defer entriesWG.Done()
+			s.runEntry(ctx, wg, e)
+		}(e)
+	}
+	entriesWG.Wait()
+}
+
+func (s *Scheduler) runEntry(ctx context.Context, wg *sync.WaitGroup, e entry) {
+	op := "Scheduler.runEntry"
+	log := s.log.With(slog.String("op", op), slog.String("job", e.name))
+
+	next := e.schedule.Next(s.clock.Now())
+	for {
+		if next.IsZero() {
+			log.Warn("job has no next activation, stopped")
+			return
+		}
+		log.Debug("job scheduled", "next", next)
+
+		//the timer may fire early when the wall clock was changed, wait again until next
+		for now := s.clock.Now(); now.Before(next); now = s.clock.Now() {
+			select {
+			case <-ctx.Done():
+				return
+			case <-s.clock.After(next.Sub(now)):
+			}
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		wg.Add(1)
+		go e.job(ctx, wg)
+
+		//activations missed while the job was starting or the process was suspended are skipped
+		next = e.schedule.Next(next)
+		if now := s.clock.Now(); !next.After(now) {
+			next = e.schedule.Next(now)
+		}
+	}
+}
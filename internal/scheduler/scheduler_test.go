@@ -0,0 +1,157 @@
+package scheduler
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock moves only on Advance, timers fire when the clock passes their deadline
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []waiter
+	added   chan struct{}
+}
+
+type waiter struct {
+	deadline time.Time
+	ch       chan time.Time
+}
+
+func newFakeClock(now time.Time) *fakeClock {
+	return &fakeClock{now: now, added: make(chan struct{}, 100)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	c.waiters = append(c.waiters, waiter{deadline: c.now.Add(d), ch: ch})
+	c.added <- struct{}{}
+	return ch
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	waiters := c.waiters[:0]
+	for _, w := range c.waiters {
+		if w.deadline.After(c.now) {
+			waiters = append(waiters, w)
+			continue
+		}
+		w.ch <- c.now
+	}
+	c.waiters = waiters
+}
+
+// waitTimer blocks until the scheduler waits on the clock
+func (c *fakeClock) waitTimer(t *testing.T) {
+	select {
+	case <-c.added:
+	case <-time.After(2 * time.Second):
+		t.Fatal("scheduler didn't wait on the clock")
+	}
+}
+
+func TestScheduler_RunsAtActivationTimes(t *testing.T) {
+	clock := newFakeClock(time.Date(2024, 5, 10, 7, 30, 0, 0, time.UTC))
+	s := New(slog.New(slog.NewTextHandler(io.Discard, nil)), clock)
+
+	runs := make(chan time.Time, 10)
+	s.Add("test", MustParseCron("0 * * * *", time.UTC), func(ctx context.Context, wg *sync.WaitGroup) {
+		defer wg.Done()
+		runs <- clock.Now()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	go s.Run(ctx, wg)
+
+	clock.waitTimer(t)
+	clock.Advance(29 * time.Minute)
+	assert.Len(t, runs, 0)
+
+	clock.Advance(time.Minute)
+	assert.Equal(t, time.Date(2024, 5, 10, 8, 0, 0, 0, time.UTC), <-runs)
+
+	clock.waitTimer(t)
+	clock.Advance(time.Hour)
+	assert.Equal(t, time.Date(2024, 5, 10, 9, 0, 0, 0, time.UTC), <-runs)
+
+	clock.waitTimer(t)
+	cancel()
+	wg.Wait()
+	assert.Len(t, runs, 0)
+}
+
+func TestScheduler_SkipsMissedActivations(t *testing.T) {
+	clock := newFakeClock(time.Date(2024, 5, 10, 7, 30, 0, 0, time.UTC))
+	s := New(slog.New(slog.NewTextHandler(io.Discard, nil)), clock)
+
+	runs := make(chan time.Time, 10)
+	s.Add("test", MustParseCron("0 * * * *", time.UTC), func(ctx context.Context, wg *sync.WaitGroup) {
+		defer wg.Done()
+		runs <- clock.Now()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	go s.Run(ctx, wg)
+
+	//process was suspended for several hours
+	clock.waitTimer(t)
+	clock.Advance(5 * time.Hour)
+	<-runs
+
+	clock.waitTimer(t)
+	clock.Advance(30 * time.Minute)
+	assert.Equal(t, time.Date(2024, 5, 10, 13, 0, 0, 0, time.UTC), <-runs)
+
+	clock.waitTimer(t)
+	cancel()
+	wg.Wait()
+	assert.Len(t, runs, 0)
+}
+
+func TestScheduler_CancelWhileWaiting(t *testing.T) {
+	clock := newFakeClock(time.Date(2024, 5, 10, 7, 30, 0, 0, time.UTC))
+	s := New(slog.New(slog.NewTextHandler(io.Discard, nil)), clock)
+	s.Add("test", MustParseCron("0 8 * * *", time.UTC), func(ctx context.Context, wg *sync.WaitGroup) {
+		defer wg.Done()
+		t.Error("job must not run")
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	go s.Run(ctx, wg)
+
+	clock.waitTimer(t)
+	cancel()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		wg.Wait()
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("scheduler didn't stop on cancel")
+	}
+}